@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// actionKeywordPattern matches the action keywords that assign a review event to the
+// references following them, e.g. "approve #1, request changes on #2, comment on #3"
+var actionKeywordPattern = regexp.MustCompile(`(?i)(^|[\s,;:(])(approve|request\s+changes(?:\s+on)?|comment(?:\s+on)?)(?:[\s:]|$)`)
+
+// actionSegment is the part of a message governed by one action keyword
+type actionSegment struct {
+	// Action is the review event, or empty before the first keyword
+	Action string
+	Text   string
+}
+
+// keywordAction maps a matched action keyword to its review event
+func keywordAction(keyword string) string {
+	switch fields := strings.Fields(strings.ToLower(keyword)); fields[0] {
+	case "request":
+		return "REQUEST_CHANGES"
+	case "comment":
+		return "COMMENT"
+	default:
+		return "APPROVE"
+	}
+}
+
+// splitActionSegments splits a message at each action keyword. Text before the first
+// keyword forms a segment with no action, so its references use the policy's review event.
+func splitActionSegments(message string) []actionSegment {
+	var segments []actionSegment
+	action, start := "", 0
+	for _, loc := range actionKeywordPattern.FindAllStringSubmatchIndex(message, -1) {
+		segments = append(segments, actionSegment{Action: action, Text: message[start:loc[4]]})
+		action, start = keywordAction(message[loc[4]:loc[5]]), loc[5]
+	}
+	return append(segments, actionSegment{Action: action, Text: message[start:]})
+}
+
+// extractActionReferences extracts the references in each action segment, tagging them
+// with the segment's action. A PR given conflicting actions falls back to the policy's
+// review event rather than guessing.
+func (ip *IntentParser) extractActionReferences(message string) ([]PRReference, error) {
+	var references []PRReference
+	for _, segment := range splitActionSegments(message) {
+		refs, err := ip.ExtractPRReferences(segment.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refs {
+			ref.Action = segment.Action
+			duplicate := false
+			for i := range references {
+				if sameReference(references[i], ref) || ip.namesLinkedPR(references[i], ref) {
+					// A link says more than a bare number naming the same PR
+					if references[i].Owner == "" {
+						references[i].Owner, references[i].Repository, references[i].URL = ref.Owner, ref.Repository, ref.URL
+					}
+					if references[i].Action != ref.Action {
+						logDebug("PR #%d has conflicting actions %q and %q, using the default", ref.Number, references[i].Action, ref.Action)
+						references[i].Action = ""
+					}
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				references = append(references, ref)
+			}
+		}
+	}
+	return references, nil
+}
+
+// sameReference reports whether two references point at the same PR or commit.
+// A bare #123 matches any repository, as owner and repo are filled in later.
+func sameReference(a, b PRReference) bool {
+	if a.linked() || b.linked() {
+		return a.CommitSHA == b.CommitSHA && a.Branch == b.Branch && a.Owner == b.Owner && a.Repository == b.Repository
+	}
+	return a.Owner == b.Owner && a.Repository == b.Repository && a.Number == b.Number
+}
+
+// actionReviewMessage describes a review submitted for a per-reference action, naming
+// the triggering user's GitHub login when known. GitHub requires a body for anything
+// but an approval, so those always get one.
+func actionReviewMessage(event, githubLogin string, mapped bool) string {
+	verb := "Approved"
+	switch event {
+	case "REQUEST_CHANGES":
+		verb = "Changes requested"
+	case "COMMENT":
+		verb = "Commented"
+	}
+
+	switch {
+	case mapped:
+		return fmt.Sprintf("%s via Slack on behalf of @%s.", verb, githubLogin)
+	case event == "APPROVE":
+		return ""
+	default:
+		return verb + " via Slack."
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// slackUserAllowed reports whether a Slack user may request approvals. Everyone may
+// when AllowedSlackUsers is empty. IDs are compared without regard to case or
+// surrounding spaces. A message with no user, such as one relayed by an integration
+// or workflow, is never on the list.
+func (config *Configuration) slackUserAllowed(user string) bool {
+	if len(config.AllowedSlackUsers) == 0 {
+		return true
+	}
+	user = strings.TrimSpace(user)
+	if user == "" {
+		return false
+	}
+	for _, allowed := range config.AllowedSlackUsers {
+		if strings.EqualFold(strings.TrimSpace(allowed), user) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreDisallowedUser drops a message from a user who may not request approvals. A
+// message that would have triggered is recorded in the skip audit, and gets the
+// not_allowed reaction with ReactToDisallowedUsers; other chatter is left alone.
+func (sc *SlackClient) ignoreDisallowedUser(ctx context.Context, msg *SlackMessage) {
+	match, err := sc.matcherFor(msg.Channel).Match(sc.matchText(msg.Text))
+	if err != nil || match == nil {
+		logDebug("Ignoring message %s in channel %s from user %s: not an allowed user", msg.Timestamp, msg.Channel, sc.userLabel(ctx, msg.User))
+		return
+	}
+
+	logInfo("Ignoring matching message %s in channel %s from user %s: not an allowed user", msg.Timestamp, msg.Channel, sc.userLabel(ctx, msg.User))
+	outcome := outcomeNone
+	if sc.config.ReactToDisallowedUsers {
+		outcome = outcomeNotAllowed
+		sc.react(msg.Channel, msg.Timestamp, outcomeNotAllowed)
+	}
+	sc.skipAudit.record(msg, &approvalDecision{
+		User:       msg.User,
+		Channel:    msg.Channel,
+		Decision:   decisionSkipped,
+		Outcome:    outcome,
+		Reason:     "user is not in allowed_slack_users",
+		SkipReason: SkipNotAllowedUser,
+	})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults and limits of the approval API
+const (
+	approvalAPIPath         = "/v1/approvals"
+	defaultAPIRateLimit     = 60
+	maxAPIRequestBytes      = 64 * 1024
+	minAPISecretLength      = 16
+	apiShutdownTimeout      = 5 * time.Second
+	defaultAPIReviewMessage = "Approved via API"
+)
+
+// apiApprovalRequest is the JSON body of a POST to /v1/approvals
+type apiApprovalRequest struct {
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	PR      int    `json:"pr"`
+	Message string `json:"message,omitempty"`
+}
+
+// apiApprovalResponse is the ApprovalResult returned to API callers
+type apiApprovalResponse struct {
+	Owner           string    `json:"owner"`
+	Repo            string    `json:"repo"`
+	PR              int       `json:"pr"`
+	Success         bool      `json:"success"`
+	ReviewID        int64     `json:"review_id,omitempty"`
+	AlreadyApproved bool      `json:"already_approved,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	RetryAttempts   int       `json:"retry_attempts"`
+	ProcessedAt     time.Time `json:"processed_at"`
+	// DryRun is set when the PR passed validation but --dry-run kept it from being approved
+	DryRun bool `json:"dry_run,omitempty"`
+	// Event is the review event submitted; anything but APPROVE means the PR was not approved
+	Event string `json:"event,omitempty"`
+	// Approved is set when the submitted review is an approval
+	Approved bool `json:"approved"`
+}
+
+// apiError is the JSON body of every non-2xx API response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// approvalAPI serves approval requests over HTTP so tools other than Slack can use the
+// approval engine. Every request needs the shared secret as a bearer token.
+type approvalAPI struct {
+	config   *Configuration
+	github   *GitHubClient
+	throttle *approvalThrottle
+	receipts *receiptLog
+	drain    *drainSwitch
+	// slack is the Slack client whose pause, freeze, degraded and throttle holds API approvals share
+	slack *SlackClient
+}
+
+// newApprovalAPI creates the API handler for a Slack client's approval engine, rate
+// limited to APIRateLimit requests per minute
+func newApprovalAPI(config *Configuration, slackClient *SlackClient) *approvalAPI {
+	limit := config.APIRateLimit
+	if limit <= 0 {
+		limit = defaultAPIRateLimit
+	}
+	return &approvalAPI{
+		config:   config,
+		github:   slackClient.githubClient,
+		throttle: newThrottle(limit, ""),
+		receipts: slackClient.receipts,
+		drain:    slackClient.drain,
+		slack:    slackClient,
+	}
+}
+
+// serveApprovalAPI listens on APIListenAddr until ctx is cancelled, next to the
+// readiness and drain endpoints of the Slack client
+func serveApprovalAPI(ctx context.Context, config *Configuration, slackClient *SlackClient) error {
+	mux := http.NewServeMux()
+	mux.Handle(approvalAPIPath, newApprovalAPI(config, slackClient))
+	mux.Handle(drainPath, &drainAPI{config: config, drain: slackClient.drain, inFlight: slackClient.inFlight})
+	mux.HandleFunc(readinessPath, serveReadiness)
+
+	server := &http.Server{
+		Addr:              config.APIListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logInfo("Approval API listening on %s", config.APIListenAddr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("approval API: %v", err)
+	}
+	return nil
+}
+
+// ServeHTTP authenticates, rate limits and validates a request, then approves the PR
+func (api *approvalAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	if !bearerAuthorized(r, api.config.APISecret) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="lgtm"`)
+		writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+	if api.drain.active() {
+		writeAPIError(w, http.StatusServiceUnavailable, "instance is draining")
+		return
+	}
+	if ok, delay := api.throttle.take(); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(delay.Seconds())+1))
+		writeAPIError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	// API approvals take the same in-flight slots as Slack ones, so a drain waits for them
+	if !api.slack.inFlight.acquire() {
+		metrics.Inc(metricApprovalsRejected)
+		writeAPIError(w, http.StatusServiceUnavailable, fmt.Sprintf("%d approvals already in flight", api.config.MaxInFlightApprovals))
+		return
+	}
+	defer api.slack.inFlight.release()
+
+	body, err := decodeAPIApprovalRequest(w, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := &ApprovalRequest{
+		Owner:      canonicalRepoName(body.Owner, api.config.RepoCase),
+		Repository: canonicalRepoName(body.Repo, api.config.RepoCase),
+		PRNumber:   body.PR,
+		Message:    body.Message,
+		Timestamp:  time.Now(),
+		Policy:     api.config.GlobalPolicy(),
+	}
+	if req.Message == "" {
+		req.Message = defaultAPIReviewMessage
+	}
+
+	ctx := withRequestSource(r.Context(), "api")
+	logInfo("API approval requested for PR %s/%s#%d from %s", req.Owner, req.Repository, req.PRNumber, r.RemoteAddr)
+
+	// The holds on Slack approvals apply here too, but an API caller is answered at once
+	// rather than queued
+	if hold := api.slack.immediateHold(""); hold != nil {
+		metrics.Inc(metricApprovalsSkipped)
+		logInfo("Skipping API approval of PR %s/%s#%d: %s", req.Owner, req.Repository, req.PRNumber, hold.reason)
+		writeAPIHold(w, hold)
+		return
+	}
+
+	err = api.github.ValidatePRReference(ctx, req.Owner, req.Repository, req.PRNumber, req.Policy)
+	if err == nil {
+		err = api.github.checkExternalPolicy(ctx, req)
+	}
+	if err != nil {
+		var policyErr *PolicyError
+		if errors.As(err, &policyErr) {
+			writeAPIError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	if api.config.DryRun {
+		logInfo("[DRY_RUN] would approve %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+		writeAPIJSON(w, http.StatusOK, apiApprovalResponse{
+			Owner:       req.Owner,
+			Repo:        req.Repository,
+			PR:          req.PRNumber,
+			DryRun:      true,
+			ProcessedAt: time.Now(),
+		})
+		return
+	}
+
+	result, err := api.github.ApprovePRWithRetry(ctx, req)
+	decision := newApprovalDecision(req)
+	decision.Channel = "api"
+	if err != nil {
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision, decision.Outcome, decision.Reason = decisionFailed, outcomeFailed, err.Error()
+		api.receipts.record(decision)
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if result.Success {
+		reviewDecision(decision, result)
+	} else {
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision, decision.Outcome, decision.Reason = decisionFailed, outcomeFailed, result.Error
+	}
+	api.receipts.record(decision)
+
+	writeAPIJSON(w, http.StatusOK, apiApprovalResponse{
+		Owner:           req.Owner,
+		Repo:            req.Repository,
+		PR:              req.PRNumber,
+		Success:         result.Success,
+		ReviewID:        result.ReviewID,
+		AlreadyApproved: result.AlreadyApproved,
+		Error:           result.Error,
+		RetryAttempts:   result.RetryAttempts,
+		ProcessedAt:     result.ProcessedAt,
+		Event:           result.Event,
+		Approved:        result.approved(),
+	})
+}
+
+// bearerAuthorized checks the bearer token against the shared secret in constant time
+func bearerAuthorized(r *http.Request, secret string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(secret)) == 1
+}
+
+// decodeAPIApprovalRequest reads and validates an approval request body
+func decodeAPIApprovalRequest(w http.ResponseWriter, r *http.Request) (*apiApprovalRequest, error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes))
+	decoder.DisallowUnknownFields()
+
+	var body apiApprovalRequest
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+
+	switch {
+	case !repoNamePattern.MatchString(body.Owner):
+		return nil, fmt.Errorf("invalid owner %q", body.Owner)
+	case !repoNamePattern.MatchString(body.Repo):
+		return nil, fmt.Errorf("invalid repo %q", body.Repo)
+	case body.PR <= 0:
+		return nil, fmt.Errorf("pr must be a positive PR number")
+	}
+	return &body, nil
+}
+
+// writeAPIJSON writes value as a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logDebug("Failed to write API response: %v", err)
+	}
+}
+
+// writeAPIHold answers a request held by an approval hold: 429 for the throttle and 503
+// otherwise, with Retry-After when the hold's end is known
+func writeAPIHold(w http.ResponseWriter, hold *approvalHold) {
+	if hold.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(hold.retryAfter.Seconds())+1))
+	}
+	status := http.StatusServiceUnavailable
+	if hold.outcome == outcomeThrottled {
+		status = http.StatusTooManyRequests
+	}
+	writeAPIError(w, status, hold.reason)
+}
+
+// writeAPIError writes an error message as a JSON response
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, apiError{Error: message})
+}
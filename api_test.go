@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAPISecret is the bearer token of the test approval API
+const testAPISecret = "0123456789abcdef"
+
+// postApproval sends an approval request for o/r#1 to the API
+func postApproval(api *approvalAPI) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, approvalAPIPath, strings.NewReader(`{"owner": "o", "repo": "r", "pr": 1}`))
+	r.Header.Set("Authorization", "Bearer "+testAPISecret)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIApproval(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, _ := newTestSlackClient(t, &Configuration{APISecret: testAPISecret}, gh)
+
+	w := postApproval(newApprovalAPI(sc.config, sc))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"approved":true`) {
+		t.Fatalf("status %d, body %s, want an approval", w.Code, w.Body)
+	}
+	if reviews := gh.submitted(); len(reviews) != 1 || reviews[0] != "APPROVE" {
+		t.Errorf("submitted %v, want [APPROVE]", reviews)
+	}
+}
+
+func TestAPIApprovalHolds(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Configuration
+		setup      func(sc *SlackClient)
+		wantStatus int
+		retryAfter bool
+	}{
+		{
+			name:       "paused",
+			config:     &Configuration{QueueWhilePaused: true},
+			setup:      func(sc *SlackClient) { sc.pause.pause() },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "frozen",
+			config: &Configuration{
+				FreezeWindows: []FreezeWindow{{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			retryAfter: true,
+		},
+		{
+			name:   "degraded",
+			config: &Configuration{DegradedThreshold: 1, DegradedProbeInterval: time.Hour},
+			setup: func(sc *SlackClient) {
+				sc.githubClient.degraded.observe(http.StatusBadGateway)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "overloaded",
+			config:     &Configuration{MaxInFlightApprovals: 1},
+			setup:      func(sc *SlackClient) { sc.inFlight.acquire() },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "throttled",
+			config:     &Configuration{MaxApprovalsPerMinute: 1},
+			setup:      func(sc *SlackClient) { sc.throttle.take() },
+			wantStatus: http.StatusTooManyRequests,
+			retryAfter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			tt.config.APISecret = testAPISecret
+			sc, _ := newTestSlackClient(t, tt.config, gh)
+			if tt.setup != nil {
+				tt.setup(sc)
+			}
+
+			w := postApproval(newApprovalAPI(sc.config, sc))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, body %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := w.Header().Get("Retry-After") != ""; got != tt.retryAfter {
+				t.Errorf("Retry-After %q, want one: %v", w.Header().Get("Retry-After"), tt.retryAfter)
+			}
+			if reviews := gh.submitted(); len(reviews) != 0 {
+				t.Errorf("submitted %v while held", reviews)
+			}
+			if len(sc.pause.queue) != 0 {
+				t.Errorf("queued %d API request(s)", len(sc.pause.queue))
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// What to do when GitHub refuses an approval because of who the bot authenticates as
+const (
+	// AppFallbackNone fails the approval
+	AppFallbackNone = "none"
+	// AppFallbackComment submits the review as a comment instead
+	AppFallbackComment = "comment"
+	// AppFallbackStatus sets a successful commit status on the PR head instead
+	AppFallbackStatus = "status"
+)
+
+// approvalStatusContext is the commit status context set by AppFallbackStatus
+const approvalStatusContext = "lgtm/approval"
+
+// maxStatusDescription is the longest commit status description GitHub accepts
+const maxStatusDescription = 140
+
+// appRestrictionErrors are the 422 messages GitHub returns when the token's identity,
+// e.g. a GitHub App installation or the Actions token, may not approve pull requests
+var appRestrictionErrors = []string{
+	"not permitted to approve pull requests",
+	"review cannot be requested",
+	"integrations cannot approve",
+}
+
+// isAppApprovalRestricted reports whether a review error says the identity may not approve at all
+func isAppApprovalRestricted(details string) bool {
+	details = strings.ToLower(details)
+	for _, message := range appRestrictionErrors {
+		if strings.Contains(details, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// approvalFallback records an approval GitHub refused for the bot's identity in the way
+// AppApprovalFallback configures, updating result. body is the review body and summary
+// the same without hidden markers, for the status description.
+func (gc *GitHubClient) approvalFallback(ctx context.Context, req *ApprovalRequest, body, summary string, result *ApprovalResult) {
+	switch gc.config.AppApprovalFallback {
+	case AppFallbackComment:
+		logWarn("GitHub doesn't let this identity approve PR %s/%s#%d, submitting a comment review instead", req.Owner, req.Repository, req.PRNumber)
+		if body == "" {
+			body = "Approved via Slack."
+		}
+		review, _, err := gc.client.PullRequests.CreateReview(ctx, req.Owner, req.Repository, req.PRNumber, &github.PullRequestReviewRequest{
+			Event: github.String("COMMENT"),
+			Body:  github.String(body),
+		})
+		if err != nil {
+			result.Error = fmt.Sprintf("PR #%d review rejected by GitHub, and the comment fallback failed: %v", req.PRNumber, err)
+			return
+		}
+		result.Success, result.Error, result.ReviewID = true, "", review.GetID()
+		result.Event, result.Fallback = "COMMENT", AppFallbackComment
+	case AppFallbackStatus:
+		logWarn("GitHub doesn't let this identity approve PR %s/%s#%d, setting a %s commit status instead", req.Owner, req.Repository, req.PRNumber, approvalStatusContext)
+		if err := gc.setApprovalStatus(ctx, req, summary); err != nil {
+			result.Error = fmt.Sprintf("PR #%d review rejected by GitHub, and the status fallback failed: %v", req.PRNumber, err)
+			return
+		}
+		result.Success, result.Error = true, ""
+		result.Event, result.Fallback = "", AppFallbackStatus
+	default:
+		logWarn("GitHub doesn't let this identity approve PR %s/%s#%d; set --app-approval-fallback to comment or status to record approvals another way", req.Owner, req.Repository, req.PRNumber)
+	}
+}
+
+// setApprovalStatus sets a successful approval commit status on the PR's head commit
+func (gc *GitHubClient) setApprovalStatus(ctx context.Context, req *ApprovalRequest, summary string) error {
+	pr, err := gc.getPR(ctx, req.Owner, req.Repository, req.PRNumber)
+	if err != nil {
+		return err
+	}
+
+	statusContext := approvalStatusContext
+	if gc.config.InstanceName != "" {
+		statusContext += "/" + gc.config.InstanceName
+	}
+	description := strings.Join(strings.Fields(summary), " ")
+	if description == "" {
+		description = "Approved via Slack"
+	}
+	if len(description) > maxStatusDescription {
+		description = description[:maxStatusDescription-3] + "..."
+	}
+
+	_, _, err = gc.client.Repositories.CreateStatus(ctx, req.Owner, req.Repository, pr.GetHead().GetSHA(), &github.RepoStatus{
+		State:       github.String("success"),
+		Context:     github.String(statusContext),
+		Description: github.String(description),
+	})
+	return err
+}
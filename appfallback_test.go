@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestApprovalFallback(t *testing.T) {
+	tests := []struct {
+		fallback     string
+		wantReviews  []string
+		wantStatuses int
+		wantEvent    string
+	}{
+		{AppFallbackComment, []string{"APPROVE", "COMMENT"}, 0, "COMMENT"},
+		{AppFallbackStatus, []string{"APPROVE"}, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fallback, func(t *testing.T) {
+			gh := &fakeGitHub{restrictApprovals: true}
+			gc := newTestGitHubClient(t, &Configuration{AppApprovalFallback: tt.fallback}, gh.ServeHTTP)
+
+			result, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+			if err != nil {
+				t.Fatalf("ApprovePR: %v", err)
+			}
+			if !result.Success || result.Fallback != tt.fallback || result.Event != tt.wantEvent {
+				t.Fatalf("result = %+v, want a successful %s fallback with event %q", result, tt.fallback, tt.wantEvent)
+			}
+			if result.approved() {
+				t.Error("a fallback result is reported as approved")
+			}
+			if reviews := gh.submitted(); len(reviews) != len(tt.wantReviews) || reviews[len(reviews)-1] != tt.wantReviews[len(tt.wantReviews)-1] {
+				t.Errorf("reviews %v, want %v", reviews, tt.wantReviews)
+			}
+			if len(gh.statuses) != tt.wantStatuses {
+				t.Errorf("set %d status(es), want %d", len(gh.statuses), tt.wantStatuses)
+			}
+
+			decision := &approvalDecision{}
+			reviewDecision(decision, result)
+			if decision.Decision != decisionFallback || decision.Outcome != outcomeFallback {
+				t.Errorf("decision %s/%s, want %s/%s", decision.Decision, decision.Outcome, decisionFallback, outcomeFallback)
+			}
+		})
+	}
+}
+
+func TestApprovalFallbackNone(t *testing.T) {
+	gh := &fakeGitHub{restrictApprovals: true}
+	gc := newTestGitHubClient(t, &Configuration{AppApprovalFallback: AppFallbackNone}, gh.ServeHTTP)
+
+	result, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+	if err != nil {
+		t.Fatalf("ApprovePR: %v", err)
+	}
+	if result.Success || result.Fallback != "" {
+		t.Errorf("result = %+v, want a failure without a fallback", result)
+	}
+}
+
+func TestApprovalFallbackIsNotReportedAsApproved(t *testing.T) {
+	gh := &fakeGitHub{restrictApprovals: true}
+	sc, reactions := newTestSlackClient(t, &Configuration{AppApprovalFallback: AppFallbackStatus, OnApproveDispatch: true}, gh)
+
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if !reactions.has("ballot_box_with_check") {
+		t.Errorf("reactions %v, want ballot_box_with_check", reactions.added)
+	}
+	if reactions.has("white_check_mark") {
+		t.Error("a fallback got the approved reaction")
+	}
+	if gh.requested("POST /repos/o/r/dispatches") {
+		t.Error("a fallback sent the approval dispatch event")
+	}
+}
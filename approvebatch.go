@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// batchRefPattern matches an owner/repo#123 reference making up a whole line
+var batchRefPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)#([0-9]+)$`)
+
+// approveBatchCommand approves the PRs read from stdin, one reference per line, and
+// prints a result per line. It fails if any line could not be approved.
+func approveBatchCommand(c *cli.Context) error {
+	config, err := parseConfig(c)
+	if err != nil {
+		return err
+	}
+	logLevel = strings.ToLower(config.LogLevel)
+	if config.GitHubToken == "" {
+		return fmt.Errorf("GitHub token is required. Set GITHUB_TOKEN environment variable or use --github-token flag")
+	}
+
+	policy := config.GlobalPolicy()
+	if err := validatePolicy("Policy", policy); err != nil {
+		return err
+	}
+
+	matcher, err := NewPatternMatcher(".*")
+	if err != nil {
+		return fmt.Errorf("failed to create pattern matcher: %v", err)
+	}
+	matcher.SetRepoAliases(config.RepoAliases)
+	matcher.SetRepoCase(config.RepoCase)
+
+	ctx := context.Background()
+	githubClient, err := NewGitHubClient(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %v", err)
+	}
+
+	return approveBatch(ctx, os.Stdin, os.Stdout, matcher, githubClient, config, c.String("message"))
+}
+
+// approveBatch approves the reference on every non-blank line of input in order
+func approveBatch(ctx context.Context, input io.Reader, w io.Writer, matcher *PatternMatcher, githubClient *GitHubClient, config *Configuration, message string) error {
+	policy := config.GlobalPolicy()
+	lines, failed := 0, 0
+
+	scanner := bufio.NewScanner(input)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+
+		ref, err := parseBatchLine(matcher, config, line)
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "%s line %d: %v\n", failMark(), number, err)
+			continue
+		}
+		pr := fmt.Sprintf("%s/%s#%d", ref.Owner, ref.Repository, ref.Number)
+
+		if err := githubClient.ValidatePRReference(ctx, ref.Owner, ref.Repository, ref.Number, policy); err != nil {
+			failed++
+			fmt.Fprintf(w, "%s line %d: %s: %v\n", failMark(), number, pr, err)
+			continue
+		}
+
+		req := &ApprovalRequest{
+			Owner:      ref.Owner,
+			Repository: ref.Repository,
+			PRNumber:   ref.Number,
+			Message:    message,
+			Timestamp:  time.Now(),
+			Policy:     policy,
+		}
+		result, err := githubClient.ApprovePRWithRetry(ctx, req)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "%s line %d: %s: %v\n", failMark(), number, pr, err)
+		case !result.Success:
+			failed++
+			fmt.Fprintf(w, "%s line %d: %s: %s\n", failMark(), number, pr, result.Error)
+		case !result.approved():
+			fmt.Fprintf(w, "%s line %d: %s not approved: %s (Review ID: %d)\n", okMark(), number, pr, notApprovedReason(result), result.ReviewID)
+		case result.AlreadyApproved:
+			fmt.Fprintf(w, "%s line %d: %s was already approved\n", okMark(), number, pr)
+		default:
+			fmt.Fprintf(w, "%s line %d: %s approved (Review ID: %d)\n", okMark(), number, pr, result.ReviewID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stdin: %v", err)
+	}
+
+	if lines == 0 {
+		return fmt.Errorf("no PR references on stdin")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d line(s) failed", failed, lines)
+	}
+	return nil
+}
+
+// parseBatchLine reads the single PR reference on a line: a PR URL, owner/repo#123, or
+// #123 or an alias reference resolved against the configured defaults and aliases
+func parseBatchLine(matcher *PatternMatcher, config *Configuration, line string) (PRReference, error) {
+	var ref PRReference
+	if m := batchRefPattern.FindStringSubmatch(line); m != nil {
+		number, err := strconv.Atoi(m[3])
+		if err != nil {
+			return ref, fmt.Errorf("%q has an invalid PR number", line)
+		}
+		ref = PRReference{Owner: m[1], Repository: m[2], Number: number}
+	} else {
+		refs, err := matcher.ExtractPRReferences(line)
+		if err != nil {
+			return ref, fmt.Errorf("failed to extract a PR reference from %q: %v", line, err)
+		}
+		switch len(refs) {
+		case 0:
+			return ref, fmt.Errorf("no PR URL or owner/repo#number in %q", line)
+		case 1:
+			ref = refs[0]
+		default:
+			return ref, fmt.Errorf("%q has %d PR references; put one per line", line, len(refs))
+		}
+	}
+
+	if ref.Owner == "" {
+		ref.Owner = config.DefaultOwner
+	}
+	if ref.Repository == "" {
+		ref.Repository = config.DefaultRepo
+	}
+	if ref.Owner == "" || ref.Repository == "" {
+		return ref, fmt.Errorf("PR #%d in %q has no owner or repository (use a full reference or --github-owner and --github-repo)", ref.Number, line)
+	}
+	ref.Owner = canonicalRepoName(ref.Owner, config.RepoCase)
+	ref.Repository = canonicalRepoName(ref.Repository, config.RepoCase)
+	return ref, nil
+}
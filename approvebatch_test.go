@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// runApproveBatch approves the PRs on input against gh and returns the printed lines
+func runApproveBatch(t *testing.T, gh *fakeGitHub, input string) ([]string, error) {
+	t.Helper()
+	plain := plainOutput
+	t.Cleanup(func() { plainOutput = plain })
+	plainOutput = true
+
+	config := &Configuration{DefaultOwner: "o", DefaultRepo: "r"}
+	matcher, err := NewPatternMatcher(".*")
+	if err != nil {
+		t.Fatalf("NewPatternMatcher: %v", err)
+	}
+	var out bytes.Buffer
+	err = approveBatch(context.Background(), strings.NewReader(input), &out, matcher, newTestGitHubClient(t, config, gh.ServeHTTP), config, "Approved")
+	return strings.Split(strings.TrimSpace(out.String()), "\n"), err
+}
+
+func TestApproveBatch(t *testing.T) {
+	gh := &fakeGitHub{}
+	input := "https://github.com/o/r/pull/1\n\n  o/r#2  \n#3\n"
+	lines, err := runApproveBatch(t, gh, input)
+	if err != nil {
+		t.Fatalf("approveBatch: %v", err)
+	}
+	want := []string{
+		"[OK] line 1: o/r#1 approved (Review ID: 1)",
+		"[OK] line 3: o/r#2 approved (Review ID: 2)",
+		"[OK] line 4: o/r#3 approved (Review ID: 3)",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestApproveBatchFailures(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/pulls/2": http.StatusNotFound}}
+	input := "o/r#1\no/r#2\nnot a reference\no/r#1 and o/r#3\n"
+	lines, err := runApproveBatch(t, gh, input)
+	if err == nil || err.Error() != "3 of 4 line(s) failed" {
+		t.Fatalf("approveBatch = %v, want 3 of 4 line(s) failed", err)
+	}
+	want := []string{
+		"[OK] line 1: o/r#1 approved",
+		"[FAIL] line 2: o/r#2: ",
+		`[FAIL] line 3: no PR URL or owner/repo#number in "not a reference"`,
+		`[FAIL] line 4: "o/r#1 and o/r#3" has 2 PR references; put one per line`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("output %q, want %d lines", lines, len(want))
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i]) {
+			t.Errorf("line %d = %q, want prefix %q", i+1, line, want[i])
+		}
+	}
+}
+
+func TestApproveBatchReviewFailure(t *testing.T) {
+	gh := &fakeGitHub{reviewStatus: http.StatusUnprocessableEntity, reviewError: "Can not approve your own pull request"}
+	lines, err := runApproveBatch(t, gh, "o/r#1\n")
+	if err == nil || err.Error() != "1 of 1 line(s) failed" {
+		t.Fatalf("approveBatch = %v, want 1 of 1 line(s) failed", err)
+	}
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "[FAIL] line 1: o/r#1: ") {
+		t.Errorf("output %q, want a failure for o/r#1", lines)
+	}
+}
+
+func TestApproveBatchEmptyInput(t *testing.T) {
+	gh := &fakeGitHub{}
+	if _, err := runApproveBatch(t, gh, "\n  \n"); err == nil || err.Error() != "no PR references on stdin" {
+		t.Errorf("approveBatch = %v, want no PR references on stdin", err)
+	}
+	if len(gh.requests) != 0 {
+		t.Errorf("requests %q, want none", gh.requests)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// auditCommand prints a read-only report of PRs approved by the bot's GitHub user
+func auditCommand(c *cli.Context) error {
+	owner := c.String("owner")
+	repo := c.String("repo")
+	if owner == "" || repo == "" {
+		return fmt.Errorf("both --owner and --repo are required")
+	}
+
+	window, err := parseSince(c.String("since"))
+	if err != nil {
+		return fmt.Errorf("invalid --since value: %v", err)
+	}
+	since := time.Now().Add(-window)
+
+	config := &Configuration{
+		GitHubToken:  c.String("github-token"),
+		InstanceName: c.String("instance-name"),
+		LogLevel:     c.String("log-level"),
+	}
+	logLevel = strings.ToLower(config.LogLevel)
+
+	ctx := context.Background()
+	githubClient, err := NewGitHubClient(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %v", err)
+	}
+
+	approvals, err := githubClient.ListBotApprovals(ctx, owner, repo, since)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Approvals in %s/%s since %s: %d\n\n", owner, repo, since.Format(time.RFC3339), len(approvals))
+	if len(approvals) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PR\tAPPROVED AT\tAUTHOR\tSTATE\tTITLE")
+	for _, approval := range approvals {
+		state := approval.PR.GetState()
+		if approval.PR.GetMerged() || approval.PR.MergedAt != nil {
+			state = "merged"
+		}
+		fmt.Fprintf(w, "#%d\t%s\t%s\t%s\t%s\n",
+			approval.PR.GetNumber(),
+			approval.ApprovedAt.Format(time.RFC3339),
+			approval.PR.GetUser().GetLogin(),
+			state,
+			approval.PR.GetTitle())
+	}
+	return w.Flush()
+}
+
+// parseSince parses a look-back window such as 7d, 12h or 90m; it must be positive
+func parseSince(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%q is not a positive number of days", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("%q must be a positive duration, such as 7d or 12h", value)
+	}
+	return window, nil
+}
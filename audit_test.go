@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr string
+	}{
+		{value: "7d", want: 7 * 24 * time.Hour},
+		{value: "12h", want: 12 * time.Hour},
+		{value: "90m", want: 90 * time.Minute},
+		{value: "0d", wantErr: `"0d" is not a positive number of days`},
+		{value: "-3d", wantErr: `"-3d" is not a positive number of days`},
+		{value: "xd", wantErr: `"xd" is not a positive number of days`},
+		{value: "-1h", wantErr: `"-1h" must be a positive duration`},
+		{value: "0s", wantErr: `"0s" must be a positive duration`},
+		{value: "a week", wantErr: "invalid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSince(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseSince = %v, %v, want %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseSince = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditRejectsNegativeSince(t *testing.T) {
+	err := newApp().Run([]string{"lgtm", "audit", "--github-token", "ghp_test", "--owner", "o", "--repo", "r", "--since", "-1h"})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid --since value: ") {
+		t.Errorf("lgtm audit --since -1h = %v, want an invalid --since error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// approvalBatch collects the decisions for every PR approved from one message, so the
+// message gets one composite reaction instead of one reaction per PR
+type approvalBatch struct {
+	mu        sync.Mutex
+	pending   int
+	decisions []*approvalDecision
+	// interaction is the approve button click the batch answers by replacing the
+	// button's message with the outcomes instead of reacting; nil for messages
+	interaction *slack.InteractionCallback
+	// notes are the outcome lines of clicked references that never became approvals
+	notes []string
+}
+
+// newApprovalBatch creates a batch expecting size decisions
+func newApprovalBatch(size int) *approvalBatch {
+	return &approvalBatch{pending: size}
+}
+
+// record adds a decision and returns all decisions once the last one is in
+func (ab *approvalBatch) record(decision *approvalDecision) ([]*approvalDecision, bool) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.decisions = append(ab.decisions, decision)
+	ab.pending--
+	return ab.decisions, ab.pending == 0
+}
+
+// compositeOutcome is approved when every PR was approved, partial when some were,
+// and failed when none were. PRs that only got a comment or other review, or an
+// approval fallback, count as not approved, unless every PR did.
+func compositeOutcome(decisions []*approvalDecision) string {
+	approved, dryRuns, reviewed, fallbacks := 0, 0, 0, 0
+	for _, decision := range decisions {
+		switch decision.Decision {
+		case decisionApproved:
+			approved++
+		case decisionDryRun:
+			dryRuns++
+		case decisionReviewed:
+			reviewed++
+		case decisionFallback:
+			fallbacks++
+		}
+	}
+
+	// In a dry run, PRs that would be approved count as approved
+	if dryRuns > 0 && dryRuns == len(decisions) {
+		return outcomeDryRun
+	}
+	if reviewed > 0 && reviewed == len(decisions) {
+		return outcomeReviewed
+	}
+	if fallbacks > 0 && fallbacks == len(decisions) {
+		return outcomeFallback
+	}
+	switch approved + dryRuns {
+	case len(decisions):
+		return outcomeApproved
+	case 0:
+		return outcomeFailed
+	default:
+		return outcomePartial
+	}
+}
+
+// batchBreakdown lists each PR's outcome for the thread reply
+func batchBreakdown(decisions []*approvalDecision) string {
+	lines := []string{"Approval results:"}
+	for _, decision := range decisions {
+		pr := fmt.Sprintf("%s/%s#%d", decision.Owner, decision.Repository, decision.PRNumber)
+		switch {
+		case decision.Decision == decisionApproved:
+			lines = append(lines, fmt.Sprintf(":white_check_mark: %s approved", pr))
+		case decision.Decision == decisionDryRun:
+			lines = append(lines, fmt.Sprintf(":test_tube: %s would be approved (dry run)", pr))
+		case decision.Decision == decisionReviewed:
+			lines = append(lines, fmt.Sprintf(":speech_balloon: %s %s", pr, decision.Reason))
+		case decision.Decision == decisionFallback:
+			lines = append(lines, fmt.Sprintf(":ballot_box_with_check: %s %s", pr, decision.Reason))
+		case decision.Reason != "":
+			lines = append(lines, fmt.Sprintf(":x: %s %s: %s", pr, decision.Decision, decision.Reason))
+		default:
+			lines = append(lines, fmt.Sprintf(":x: %s %s", pr, decision.Decision))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// interactionSummary lists each PR's outcome in place of a clicked approve button
+func interactionSummary(notes []string, decisions []*approvalDecision, user string) string {
+	lines := append([]string(nil), notes...)
+	for _, decision := range decisions {
+		pr := fmt.Sprintf("%s/%s#%d", decision.Owner, decision.Repository, decision.PRNumber)
+		switch {
+		case decision.Decision == decisionApproved:
+			lines = append(lines, fmt.Sprintf(":white_check_mark: %s approved by <@%s>", pr, user))
+		case decision.Decision == decisionDryRun:
+			lines = append(lines, fmt.Sprintf(":test_tube: %s would be approved (dry run)", pr))
+		case decision.Decision == decisionReviewed:
+			lines = append(lines, fmt.Sprintf(":speech_balloon: %s: %s", pr, decision.Reason))
+		case decision.Decision == decisionFallback:
+			lines = append(lines, fmt.Sprintf(":ballot_box_with_check: %s: %s", pr, decision.Reason))
+		case decision.Reason != "":
+			lines = append(lines, fmt.Sprintf(":x: %s: %s", pr, decision.Reason))
+		default:
+			lines = append(lines, fmt.Sprintf(":x: %s %s", pr, decision.Decision))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reactOutcome reacts with a PR's outcome, unless the PR is part of a batch that
+// reacts once for the whole message
+func (sc *SlackClient) reactOutcome(req *ApprovalRequest, outcome string) {
+	if req.batch != nil {
+		return
+	}
+	sc.react(req.SourceChannel, req.SourceMessage.Timestamp, outcome)
+}
+
+// reactQueued reacts with the outcome holding a queued PR, such as paused, as an interim
+// reaction that the PR's final outcome replaces with ReplaceInterimReactions
+func (sc *SlackClient) reactQueued(req *ApprovalRequest, outcome string) {
+	if req.batch != nil {
+		return
+	}
+	emoji := sc.config.outcomeReaction(outcome)
+	sc.reactions.interimReaction(req.SourceChannel, req.SourceMessage.Timestamp, emoji)
+	sc.queuedReactions.record(req, emoji)
+}
+
+// reactDelayed reacts with the queued hourglass on a PR that waits for GitHub or the
+// throttle before it runs. The PR's outcome, or its batch's composite reaction, always
+// replaces it; a clicked button's message shows the outcome instead.
+func (sc *SlackClient) reactDelayed(req *ApprovalRequest) {
+	if req.batch != nil && req.batch.interaction != nil {
+		return
+	}
+	emoji := sc.config.outcomeReaction(outcomeQueued)
+	sc.reactions.queuedReaction(req.SourceChannel, req.SourceMessage.Timestamp, emoji)
+	sc.queuedReactions.record(req, emoji)
+}
+
+// finishBatchDecision records a PR's decision in its batch and, after the last PR,
+// adds the composite reaction and the optional breakdown reply, or replaces a clicked
+// button's message with the outcomes
+func (sc *SlackClient) finishBatchDecision(req *ApprovalRequest, decision *approvalDecision) {
+	if req.batch == nil {
+		return
+	}
+
+	decisions, done := req.batch.record(decision)
+	if !done {
+		return
+	}
+
+	outcome := compositeOutcome(decisions)
+	if req.batch.interaction != nil {
+		logInfo("Processed %d PR(s) from an approve button in channel %s: %s", len(decisions), req.SourceChannel, outcome)
+		sc.updateInteractiveMessage(*req.batch.interaction, interactionSummary(req.batch.notes, decisions, req.SourceUser))
+		return
+	}
+	logInfo("Processed %d PR(s) from message %s in channel %s: %s", len(decisions), req.SourceMessage.Timestamp, req.SourceChannel, outcome)
+	sc.react(req.SourceChannel, req.SourceMessage.Timestamp, outcome)
+
+	if sc.config.ReplyWithBreakdown && outcome != outcomeApproved {
+		if err := sc.replyInThread(req.SourceChannel, req.SourceMessage.Timestamp, req.SourceMessage.ThreadTS, batchBreakdown(decisions)); err != nil {
+			logWarn("Failed to post approval breakdown in channel %s: %v", req.SourceChannel, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// maxCorpusLineBytes bounds one corpus message, well above Slack's message size
+const maxCorpusLineBytes = 1 << 20
+
+// slowMessage is a corpus message whose match took longer than the slow threshold
+type slowMessage struct {
+	Line     int
+	Length   int
+	Duration time.Duration
+}
+
+// matcherBenchmark summarizes running a matcher over a corpus
+type matcherBenchmark struct {
+	Messages   int
+	Matches    int
+	References int
+	Total      time.Duration
+	Durations  []time.Duration
+	Slow       []slowMessage
+}
+
+// benchMatcherCommand runs the configured patterns over a corpus of messages, one per
+// line, and reports the match rate, throughput and per-message timing
+func benchMatcherCommand(c *cli.Context) error {
+	matcher, err := NewMultiPatternMatcher(textFlag(c, "pattern"), c.String("match-mode"))
+	if err != nil {
+		return fmt.Errorf("failed to create pattern matcher: %v", err)
+	}
+
+	messages, err := readCorpus(c.String("corpus"))
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("corpus %s has no messages", c.String("corpus"))
+	}
+
+	iterations := c.Int("iterations")
+	if iterations < 1 {
+		iterations = 1
+	}
+
+	bench, err := runMatcherBenchmark(matcher, messages, iterations, c.Duration("slow-threshold"))
+	if err != nil {
+		return err
+	}
+	writeMatcherBenchmark(os.Stdout, bench, iterations, c.Duration("slow-threshold"))
+
+	if len(bench.Slow) > 0 {
+		return fmt.Errorf("%d message(s) took longer than %v to match", len(bench.Slow), c.Duration("slow-threshold"))
+	}
+	return nil
+}
+
+// readCorpus reads one message per non-empty line from a file, or stdin for "-"
+func readCorpus(path string) ([]string, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open corpus: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var messages []string
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), maxCorpusLineBytes)
+	for scanner.Scan() {
+		messages = append(messages, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %v", err)
+	}
+	return messages, nil
+}
+
+// runMatcherBenchmark matches every message iterations times. Blank lines count toward
+// line numbers but are not matched. Go's regexp runs in linear time, so there is no
+// catastrophic backtracking to cut short; messages slower than slowThreshold are reported instead.
+func runMatcherBenchmark(matcher *PatternMatcher, messages []string, iterations int, slowThreshold time.Duration) (*matcherBenchmark, error) {
+	bench := &matcherBenchmark{}
+
+	for i, message := range messages {
+		if message == "" {
+			continue
+		}
+		bench.Messages++
+
+		var slowest time.Duration
+		for iteration := 0; iteration < iterations; iteration++ {
+			start := time.Now()
+			match, err := matcher.Match(message)
+			elapsed := time.Since(start)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+
+			bench.Total += elapsed
+			bench.Durations = append(bench.Durations, elapsed)
+			if elapsed > slowest {
+				slowest = elapsed
+			}
+
+			if iteration == 0 && match != nil {
+				bench.Matches++
+				bench.References += len(match.PRReferences)
+			}
+		}
+
+		if slowThreshold > 0 && slowest > slowThreshold {
+			bench.Slow = append(bench.Slow, slowMessage{Line: i + 1, Length: len(message), Duration: slowest})
+		}
+	}
+
+	sort.Slice(bench.Durations, func(i, j int) bool { return bench.Durations[i] < bench.Durations[j] })
+	sort.Slice(bench.Slow, func(i, j int) bool { return bench.Slow[i].Duration > bench.Slow[j].Duration })
+	return bench, nil
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// writeMatcherBenchmark prints the benchmark report, listing at most ten slow messages
+func writeMatcherBenchmark(w io.Writer, bench *matcherBenchmark, iterations int, slowThreshold time.Duration) {
+	fmt.Fprintf(w, "Messages:    %d (x%d)\n", bench.Messages, iterations)
+	fmt.Fprintf(w, "Matched:     %d (%.1f%%)\n", bench.Matches, 100*float64(bench.Matches)/float64(max(bench.Messages, 1)))
+	fmt.Fprintf(w, "PR refs:     %d\n", bench.References)
+	if bench.Total > 0 {
+		fmt.Fprintf(w, "Throughput:  %.0f messages/s\n", float64(len(bench.Durations))/bench.Total.Seconds())
+	}
+	fmt.Fprintf(w, "Latency:     p50=%v p90=%v p99=%v max=%v\n",
+		percentile(bench.Durations, 0.50),
+		percentile(bench.Durations, 0.90),
+		percentile(bench.Durations, 0.99),
+		percentile(bench.Durations, 1))
+
+	if slowThreshold <= 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	if len(bench.Slow) == 0 {
+		fmt.Fprintf(w, "%s No message took longer than %v\n", okMark(), slowThreshold)
+		return
+	}
+	fmt.Fprintf(w, "%s %d message(s) took longer than %v:\n", failMark(), len(bench.Slow), slowThreshold)
+	for i, slow := range bench.Slow {
+		if i == 10 {
+			fmt.Fprintf(w, "  ... and %d more\n", len(bench.Slow)-i)
+			break
+		}
+		fmt.Fprintf(w, "  line %d (%d bytes): %v\n", slow.Line, slow.Length, slow.Duration)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// resolveChannelName looks up the ID of the channel named by SlackChannelName and
+// monitors it as if it were given as SlackChannelID. A resolution saved in the store
+// is reused while the channel still has that name.
+func (sc *SlackClient) resolveChannelName(ctx context.Context) error {
+	name := strings.TrimPrefix(strings.TrimSpace(sc.config.SlackChannelName), "#")
+	if name == "" {
+		return nil
+	}
+
+	if id, ok := sc.cachedChannelID(ctx, name); ok {
+		logInfo("Monitoring channel #%s (%s)", name, id)
+		sc.config.SlackChannelID = id
+		return nil
+	}
+
+	ids, err := sc.channelIDsByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to resolve channel #%s: %v", name, err)
+	}
+	switch len(ids) {
+	case 0:
+		return &ConfigError{Field: "SlackChannelName", Message: fmt.Sprintf("No channel named #%s is visible to the bot; check the name, or invite the bot if it is private", name)}
+	case 1:
+	default:
+		return &ConfigError{Field: "SlackChannelName", Message: fmt.Sprintf("Several channels are named #%s (%s); set --slack-channel-id instead", name, strings.Join(ids, ", "))}
+	}
+
+	if err := sc.store.Put(ctx, storeNamespaceChannelNames, name, ids[0], 0); err != nil {
+		logWarn("Failed to save channel #%s resolution: %v", name, err)
+	}
+
+	logInfo("Resolved channel #%s to %s", name, ids[0])
+	sc.config.SlackChannelID = ids[0]
+	return nil
+}
+
+// cachedChannelID returns the saved ID for a channel name if that channel still has the name
+func (sc *SlackClient) cachedChannelID(ctx context.Context, name string) (string, bool) {
+	id, ok, err := sc.store.Get(ctx, storeNamespaceChannelNames, name)
+	if err != nil || !ok {
+		return "", false
+	}
+
+	channel, err := sc.slackAPI().GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: id})
+	if err != nil || channel.Name != name || channel.IsArchived {
+		logDebug("Saved resolution of channel #%s to %s is stale", name, id)
+		return "", false
+	}
+	return id, true
+}
+
+// channelIDsByName lists the IDs of every unarchived channel with the given name. Names
+// are unique within a workspace, but an org-wide install can see one per workspace.
+func (sc *SlackClient) channelIDsByName(ctx context.Context, name string) ([]string, error) {
+	params := &slack.GetConversationsParameters{
+		Types:           []string{"public_channel", "private_channel"},
+		ExcludeArchived: true,
+		Limit:           200,
+	}
+
+	var ids []string
+	for {
+		channels, cursor, err := sc.slackAPI().GetConversationsContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			if channel.Name == name {
+				ids = append(ids, channel.ID)
+			}
+		}
+		if cursor == "" {
+			return ids, nil
+		}
+		params.Cursor = cursor
+	}
+}
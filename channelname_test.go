@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeConversations serves conversations.list a page at a time and conversations.info
+type fakeConversations struct {
+	mu sync.Mutex
+	// pages lists the channels of each page as "ID name"
+	pages [][]string
+	// renamed maps a channel ID to the name conversations.info reports for it
+	renamed map[string]string
+	// listError is the Slack error of every conversations.list call
+	listError string
+	lists     int
+}
+
+// serve answers sc's Slack API calls
+func (f *fakeConversations) serve(t *testing.T, sc *SlackClient) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case "conversations.list":
+			f.lists++
+			if f.listError != "" {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": f.listError})
+				return
+			}
+			page := len(r.FormValue("cursor"))
+			channels := []map[string]string{}
+			if page < len(f.pages) {
+				for _, channel := range f.pages[page] {
+					id, name, _ := strings.Cut(channel, " ")
+					channels = append(channels, map[string]string{"id": id, "name": name})
+				}
+			}
+			cursor := ""
+			if page+1 < len(f.pages) {
+				cursor = strings.Repeat("c", page+1)
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channels": channels, "response_metadata": map[string]string{"next_cursor": cursor}})
+		case "conversations.info":
+			id := r.FormValue("channel")
+			name, ok := f.renamed[id]
+			if !ok {
+				name = "general"
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channel": map[string]string{"id": id, "name": name}})
+		default:
+			writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+		}
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+}
+
+func TestResolveChannelName(t *testing.T) {
+	tests := []struct {
+		name           string
+		channelName    string
+		conversations  *fakeConversations
+		wantID         string
+		wantConfigErr  bool
+		wantOtherError bool
+	}{
+		{name: "found on a later page", channelName: "#general", conversations: &fakeConversations{pages: [][]string{{"C1 random"}, {"C2 general"}}}, wantID: "C2"},
+		{name: "not found", channelName: "general", conversations: &fakeConversations{pages: [][]string{{"C1 random"}}}, wantConfigErr: true},
+		{name: "several channels", channelName: "general", conversations: &fakeConversations{pages: [][]string{{"C1 general"}, {"C2 general"}}}, wantConfigErr: true},
+		{name: "list error", channelName: "general", conversations: &fakeConversations{listError: "missing_scope"}, wantOtherError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newTestSlackClient(t, &Configuration{SlackChannelName: tt.channelName}, &fakeGitHub{})
+			tt.conversations.serve(t, sc)
+
+			err := sc.resolveChannelName(context.Background())
+			var configErr *ConfigError
+			if got := errors.As(err, &configErr) && configErr.Field == "SlackChannelName"; got != tt.wantConfigErr {
+				t.Errorf("resolveChannelName = %v, want a SlackChannelName error %v", err, tt.wantConfigErr)
+			}
+			if tt.wantOtherError && (err == nil || configErr != nil) {
+				t.Errorf("resolveChannelName = %v, want a lookup error", err)
+			}
+			if sc.config.SlackChannelID != tt.wantID {
+				t.Errorf("SlackChannelID = %q, want %q", sc.config.SlackChannelID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestResolveChannelNameIsCached(t *testing.T) {
+	conversations := &fakeConversations{pages: [][]string{{"C2 general"}}}
+	config := &Configuration{SlackChannelName: "general"}
+	sc, _ := newTestSlackClient(t, config, &fakeGitHub{})
+	conversations.serve(t, sc)
+
+	for i := 0; i < 2; i++ {
+		config.SlackChannelID = ""
+		if err := sc.resolveChannelName(context.Background()); err != nil {
+			t.Fatalf("resolveChannelName: %v", err)
+		}
+		if config.SlackChannelID != "C2" {
+			t.Fatalf("SlackChannelID = %q, want C2", config.SlackChannelID)
+		}
+	}
+	if conversations.lists != 1 {
+		t.Errorf("listed channels %d times, want the saved resolution reused", conversations.lists)
+	}
+
+	// Once the channel is renamed, the name is resolved again
+	conversations.pages = [][]string{{"C2 general-old"}, {"C3 general"}}
+	conversations.renamed = map[string]string{"C2": "general-old"}
+	config.SlackChannelID = ""
+	if err := sc.resolveChannelName(context.Background()); err != nil {
+		t.Fatalf("resolveChannelName: %v", err)
+	}
+	if config.SlackChannelID != "C3" || conversations.lists == 1 {
+		t.Errorf("SlackChannelID = %q after %d channel list call(s), want C3 resolved again", config.SlackChannelID, conversations.lists)
+	}
+}
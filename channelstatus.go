@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+)
+
+// What to do when the bot leaves a monitored channel or the channel is archived
+const (
+	ChannelRemovedIgnore  = "ignore"
+	ChannelRemovedWarn    = "warn"
+	ChannelRemovedUnready = "unready"
+)
+
+// readinessPath is served next to the approval API for load balancers and orchestrators
+const readinessPath = "/readyz"
+
+// handleChannelRemoved warns when the bot can no longer see a monitored channel and,
+// when it was the only monitored one, marks the bot not ready
+func (sc *SlackClient) handleChannelRemoved(channel, reason string) {
+	action := sc.config.ChannelRemovedAction
+	if action == "" {
+		action = ChannelRemovedUnready
+	}
+	if action == ChannelRemovedIgnore {
+		return
+	}
+	if sc.config.SlackChannelID != "" && channel != sc.config.SlackChannelID {
+		logDebug("Channel %s %s, but it isn't monitored", channel, reason)
+		return
+	}
+
+	logWarn("Channel %s %s; approvals requested there won't be seen", channel, reason)
+	if action == ChannelRemovedUnready && sc.config.SlackChannelID != "" {
+		logWarn("Channel %s was the only monitored channel, marking the bot not ready", channel)
+		metrics.SetGauge(metricSlackReady, 0)
+	}
+}
+
+// handleChannelRestored marks the bot ready again once its monitored channel is usable
+func (sc *SlackClient) handleChannelRestored(channel string) {
+	if sc.config.SlackChannelID == "" || channel != sc.config.SlackChannelID || metrics.Gauge(metricSlackReady) == 1 {
+		return
+	}
+	logInfo("Monitored channel %s is available again, marking the bot ready", channel)
+	metrics.SetGauge(metricSlackReady, 1)
+}
+
+// serveReadiness reports 200 while the bot is ready and 503 otherwise, including while it drains
+func serveReadiness(w http.ResponseWriter, r *http.Request) {
+	if metrics.Gauge(metricSlackReady) != 1 || metrics.Gauge(metricDraining) == 1 {
+		writeAPIJSON(w, http.StatusServiceUnavailable, map[string]bool{"ready": false, "draining": metrics.Gauge(metricDraining) == 1})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]bool{"ready": true})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// markReady marks the bot ready and not draining until the test ends
+func markReady(t *testing.T) {
+	t.Helper()
+	ready, draining := metrics.Gauge(metricSlackReady), metrics.Gauge(metricDraining)
+	t.Cleanup(func() {
+		metrics.SetGauge(metricSlackReady, ready)
+		metrics.SetGauge(metricDraining, draining)
+	})
+	metrics.SetGauge(metricSlackReady, 1)
+	metrics.SetGauge(metricDraining, 0)
+}
+
+// callbackEvent wraps a Slack event the way the Events API delivers it
+func callbackEvent(data interface{}) slackevents.EventsAPIEvent {
+	return slackevents.EventsAPIEvent{Type: slackevents.CallbackEvent, InnerEvent: slackevents.EventsAPIInnerEvent{Data: data}}
+}
+
+func TestChannelRemoved(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		monitored string
+		event     interface{}
+		wantReady bool
+	}{
+		{name: "left the monitored channel", monitored: "C1", event: &slackevents.ChannelLeftEvent{Channel: "C1"}},
+		{name: "monitored private channel archived", monitored: "C1", event: &slackevents.GroupArchiveEvent{Channel: "C1"}},
+		{name: "other channel archived", monitored: "C1", event: &slackevents.ChannelArchiveEvent{Channel: "C2"}, wantReady: true},
+		{name: "every channel monitored", event: &slackevents.ChannelLeftEvent{Channel: "C1"}, wantReady: true},
+		{name: "warn only", action: ChannelRemovedWarn, monitored: "C1", event: &slackevents.ChannelLeftEvent{Channel: "C1"}, wantReady: true},
+		{name: "ignored", action: ChannelRemovedIgnore, monitored: "C1", event: &slackevents.ChannelLeftEvent{Channel: "C1"}, wantReady: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markReady(t)
+			sc, _ := newTestSlackClient(t, &Configuration{ChannelRemovedAction: tt.action, SlackChannelID: tt.monitored}, &fakeGitHub{})
+
+			sc.handleEventsAPIEvent(context.Background(), callbackEvent(tt.event))
+
+			if got := metrics.Gauge(metricSlackReady) == 1; got != tt.wantReady {
+				t.Errorf("ready = %v, want %v", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestChannelRestored(t *testing.T) {
+	tests := []struct {
+		name  string
+		event interface{}
+	}{
+		{name: "unarchived", event: &slackevents.ChannelUnarchiveEvent{Channel: "C1"}},
+		{name: "private channel unarchived", event: &slackevents.GroupUnarchiveEvent{Channel: "C1"}},
+		{name: "bot invited back", event: &slackevents.MemberJoinedChannelEvent{Channel: "C1", User: "UBOT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markReady(t)
+			sc, _ := newTestSlackClient(t, &Configuration{SlackChannelID: "C1"}, &fakeGitHub{})
+			sc.botUserID = "UBOT"
+
+			sc.handleEventsAPIEvent(context.Background(), callbackEvent(&slackevents.ChannelLeftEvent{Channel: "C1"}))
+			sc.handleEventsAPIEvent(context.Background(), callbackEvent(tt.event))
+
+			if metrics.Gauge(metricSlackReady) != 1 {
+				t.Error("the bot is still not ready after its channel came back")
+			}
+		})
+	}
+}
+
+func TestServeReadiness(t *testing.T) {
+	tests := []struct {
+		name     string
+		ready    float64
+		draining float64
+		want     int
+	}{
+		{name: "ready", ready: 1, want: http.StatusOK},
+		{name: "not ready", ready: 0, want: http.StatusServiceUnavailable},
+		{name: "draining", ready: 1, draining: 1, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markReady(t)
+			metrics.SetGauge(metricSlackReady, tt.ready)
+			metrics.SetGauge(metricDraining, tt.draining)
+
+			recorder := httptest.NewRecorder()
+			serveReadiness(recorder, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelRemovedActionValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--channel-removed-action", "leave"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "ChannelRemovedAction" {
+		t.Errorf("validateConfiguration = %v, want a ChannelRemovedAction error", err)
+	}
+}
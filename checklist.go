@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// htmlCommentPattern matches the HTML comments PR templates use as placeholder hints
+var htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// markdownHeadingPattern matches a Markdown heading line, capturing its text
+var markdownHeadingPattern = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// renderChecklist renders the review checklist as checked task list items
+func renderChecklist(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Checklist:")
+	for _, item := range items {
+		b.WriteString("\n- [x] " + item)
+	}
+	return b.String()
+}
+
+// missingPRFields returns the required fields a PR description leaves out or empty. A
+// field is filled in as a heading followed by text before the next heading, e.g.
+// "## Testing", or as a "Testing: ..." line. Fields match case-insensitively, and text
+// inside HTML comments doesn't count.
+func missingPRFields(body string, fields []string) []string {
+	lines := strings.Split(htmlCommentPattern.ReplaceAllString(body, ""), "\n")
+
+	filled := make(map[string]bool)
+	heading := ""
+	for _, line := range lines {
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil {
+			heading = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(match[1]), ":"))
+			continue
+		}
+
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		if heading != "" {
+			filled[heading] = true
+		}
+
+		// Inline fields, optionally as a list item or in bold: "- **Risk:** low"
+		text = strings.TrimSpace(strings.TrimLeft(text, "-* "))
+		name, value, ok := strings.Cut(strings.ReplaceAll(text, "**", ""), ":")
+		if ok && strings.TrimSpace(value) != "" {
+			filled[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	var missing []string
+	for _, field := range fields {
+		if !filled[strings.ToLower(strings.TrimSpace(field))] {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// checkPRFields fails for PRs whose description doesn't fill in every required template field
+func (gc *GitHubClient) checkPRFields(ctx context.Context, pr *github.PullRequest) error {
+	missing := missingPRFields(pr.GetBody(), gc.config.RequiredPRFields)
+	if len(missing) == 0 {
+		return nil
+	}
+	return &PolicyError{Policy: "pr-fields", Message: fmt.Sprintf("PR #%d description is missing %s", pr.GetNumber(), quoteList(missing))}
+}
+
+// quoteList joins values as "a", "b" and "c"
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
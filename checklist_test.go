@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderChecklist(t *testing.T) {
+	if got := renderChecklist(nil); got != "" {
+		t.Errorf("renderChecklist(nil) = %q, want empty", got)
+	}
+	want := "Checklist:\n- [x] Tests pass\n- [x] Docs updated"
+	if got := renderChecklist([]string{"Tests pass", "Docs updated"}); got != want {
+		t.Errorf("renderChecklist = %q, want %q", got, want)
+	}
+}
+
+func TestReviewIncludesChecklist(t *testing.T) {
+	gh := &fakeGitHub{}
+	gc := newTestGitHubClient(t, &Configuration{ReviewChecklist: []string{"Tests pass"}, ReviewFooter: "— automated via lgtm"}, gh.ServeHTTP)
+
+	if _, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, Message: "Approved"}); err != nil {
+		t.Fatalf("ApprovePR: %v", err)
+	}
+	want := "Approved\n\nChecklist:\n- [x] Tests pass\n\n— automated via lgtm"
+	if got := gh.bodies(); len(got) != 1 || got[0] != want {
+		t.Errorf("review bodies %q, want %q", got, want)
+	}
+}
+
+func TestMissingPRFields(t *testing.T) {
+	fields := []string{"Testing", "Risk"}
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "headings", body: "## Testing\nUnit tests\n\n## Risk\nLow"},
+		{name: "inline fields", body: "- **Testing:** unit tests\n- Risk: low"},
+		{name: "case and colon", body: "### testing:\ncovered\nRISK: none"},
+		{name: "empty heading", body: "## Testing\n\n## Risk\nLow", want: []string{"Testing"}},
+		{name: "template hint only", body: "## Testing\n<!-- How was this tested? -->\n## Risk\nLow", want: []string{"Testing"}},
+		{name: "empty inline field", body: "Testing:\nRisk: low", want: []string{"Testing"}},
+		{name: "empty description", want: []string{"Testing", "Risk"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingPRFields(tt.body, fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingPRFields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPRFieldsGate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "filled in", body: "## Testing\nUnit tests\nRisk: low"},
+		{name: "missing fields", body: "Adds a button", wantErr: `PR #1 description is missing "Testing" and "Risk"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePR(t, &Configuration{RequiredPRFields: []string{"Testing", "Risk"}}, &fakeGitHub{body: tt.body})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+				}
+				return
+			}
+			if failedPolicy(err) != "pr-fields" || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePRReference = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuoteList(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{values: []string{"a"}, want: `"a"`},
+		{values: []string{"a", "b"}, want: `"a" and "b"`},
+		{values: []string{"a", "b", "c"}, want: `"a", "b" and "c"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteList(tt.values); got != tt.want {
+			t.Errorf("quoteList(%q) = %s, want %s", tt.values, got, tt.want)
+		}
+	}
+}
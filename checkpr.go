@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// checkPRCommand runs the bot's validation gates against a PR and prints a checklist, without approving
+func checkPRCommand(c *cli.Context) error {
+	owner := c.String("owner")
+	repo := c.String("repo")
+	prNumber := c.Int("pr")
+	if owner == "" || repo == "" {
+		return fmt.Errorf("both --owner and --repo are required")
+	}
+
+	config, err := parseConfig(c)
+	if err != nil {
+		return err
+	}
+	logLevel = strings.ToLower(config.LogLevel)
+	if config.GitHubToken == "" {
+		return fmt.Errorf("GitHub token is required. Set GITHUB_TOKEN environment variable, use --github-token flag or set github_token in the --config file")
+	}
+
+	channel := c.String("channel")
+	policy := config.PolicyFor(channel)
+	if err := validatePolicy("Policy", policy); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	githubClient, err := NewGitHubClient(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %v", err)
+	}
+
+	results, err := githubClient.CheckPR(ctx, owner, repo, prNumber, policy)
+	if err != nil {
+		return err
+	}
+
+	// The external policy sees the same context as a Slack approval from this channel
+	external := GateResult{Name: externalPolicyName, Enabled: config.PolicyURL != ""}
+	if external.Enabled {
+		external.Err = githubClient.checkExternalPolicy(ctx, &ApprovalRequest{
+			Owner:         owner,
+			Repository:    repo,
+			PRNumber:      prNumber,
+			SourceChannel: channel,
+			Policy:        policy,
+		})
+	}
+	results = append(results, external)
+
+	policyName := "global"
+	if _, ok := config.ChannelPolicies[channel]; ok {
+		policyName = "channel " + channel
+	}
+	fmt.Printf("Checking %s/%s#%d (policy: %s)\n\n", owner, repo, prNumber, policyName)
+
+	failed := 0
+	for _, result := range results {
+		switch {
+		case !result.Enabled:
+			fmt.Printf("%s %s (not configured)\n", skipMark(), result.Name)
+		case result.Err != nil:
+			failed++
+			fmt.Printf("%s %s: %v\n", failMark(), result.Name, result.Err)
+		default:
+			fmt.Printf("%s %s\n", okMark(), result.Name)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("PR would be skipped: %d gate(s) failed", failed)
+	}
+	fmt.Println("PR would be approved")
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// defaultApprovalCommentTemplate is the PR comment posted after an approval when no template is configured
+const defaultApprovalCommentTemplate = "Approved via Slack by {{.SlackUser}} in {{.Channel}} at {{.Time}}."
+
+// approvalCommentMarkerPrefix starts the hidden marker that makes approval comments idempotent
+const approvalCommentMarkerPrefix = "<!-- lgtm:approval-comment"
+
+// approvalCommentData is the data available to the approval comment template
+type approvalCommentData struct {
+	Owner      string
+	Repository string
+	PRNumber   int
+	SlackUser  string
+	Channel    string
+	MessageTS  string
+	Time       string
+}
+
+// parseApprovalCommentTemplate compiles the approval comment template
+func parseApprovalCommentTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultApprovalCommentTemplate
+	}
+	return template.New("approval-comment").Option("missingkey=error").Parse(text)
+}
+
+// approvalCommentMarker identifies the comment for one triggering Slack message on one PR,
+// scoped to the instance name when one is set
+func approvalCommentMarker(req *ApprovalRequest, instance string) string {
+	source := "cli"
+	if req.SourceMessage != nil {
+		source = req.SourceMessage.Channel + "/" + req.SourceMessage.Timestamp
+	}
+	if instance != "" {
+		return fmt.Sprintf("%s instance=%s source=%s -->", approvalCommentMarkerPrefix, instance, source)
+	}
+	return fmt.Sprintf("%s source=%s -->", approvalCommentMarkerPrefix, source)
+}
+
+// CommentOnApproval posts a summary comment on an approved PR naming who triggered it in Slack.
+// A comment already carrying the same marker is left alone, so re-processing a message doesn't duplicate it.
+func (gc *GitHubClient) CommentOnApproval(ctx context.Context, req *ApprovalRequest) error {
+	marker := approvalCommentMarker(req, gc.config.InstanceName)
+
+	exists, err := gc.hasCommentWithMarker(ctx, req.Owner, req.Repository, req.PRNumber, marker)
+	if err != nil {
+		return err
+	}
+	if exists {
+		logDebug("Approval comment already present on %s/%s#%d, skipping", req.Owner, req.Repository, req.PRNumber)
+		return nil
+	}
+
+	data := approvalCommentData{
+		Owner:      req.Owner,
+		Repository: req.Repository,
+		PRNumber:   req.PRNumber,
+		SlackUser:  req.SourceUser,
+		Channel:    req.SourceChannel,
+		Time:       req.Timestamp.UTC().Format(time.RFC3339),
+	}
+	if req.SourceMessage != nil {
+		data.MessageTS = req.SourceMessage.Timestamp
+	}
+
+	var body bytes.Buffer
+	if err := gc.commentTemplate.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to render approval comment: %v", err)
+	}
+	body.WriteString("\n\n" + marker)
+
+	_, _, err = gc.client.Issues.CreateComment(ctx, req.Owner, req.Repository, req.PRNumber, &github.IssueComment{
+		Body: github.String(body.String()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to comment on PR #%d: %v", req.PRNumber, err)
+	}
+
+	logDebug("Posted approval comment on %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+	return nil
+}
+
+// hasCommentWithMarker reports whether any comment on the PR contains the marker
+func (gc *GitHubClient) hasCommentWithMarker(ctx context.Context, owner, repo string, prNumber int, marker string) (bool, error) {
+	listOptions := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: gc.perPage()}}
+
+	for {
+		comments, response, err := gc.client.Issues.ListComments(ctx, owner, repo, prNumber, listOptions)
+		if err != nil {
+			return false, fmt.Errorf("failed to list comments on PR #%d: %v", prNumber, err)
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.GetBody(), marker) {
+				return true, nil
+			}
+		}
+
+		if response.NextPage == 0 {
+			return false, nil
+		}
+		listOptions.Page = response.NextPage
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseRepoAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]RepoTarget
+		wantErr bool
+	}{
+		{
+			name:   "aliases",
+			values: []string{"api=my-org/api-server", " web = my-org/web.app"},
+			want:   map[string]RepoTarget{"api": {Owner: "my-org", Repo: "api-server"}, "web": {Owner: "my-org", Repo: "web.app"}},
+		},
+		{name: "none", want: map[string]RepoTarget{}},
+		{name: "missing =", values: []string{"api"}, wantErr: true},
+		{name: "missing alias", values: []string{"=my-org/api"}, wantErr: true},
+		{name: "missing owner", values: []string{"api=api-server"}, wantErr: true},
+		{name: "too many segments", values: []string{"api=my-org/api/server"}, wantErr: true},
+		{name: "invalid owner", values: []string{"api=my org/api"}, wantErr: true},
+		{name: "empty target", values: []string{"api="}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRepoAliases(tt.values)
+			if tt.wantErr {
+				var configErr *ConfigError
+				if !errors.As(err, &configErr) || configErr.Field != "RepoAliases" {
+					t.Errorf("parseRepoAliases(%q) = %v, %v, want a RepoAliases error", tt.values, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRepoAliases(%q): %v", tt.values, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRepoAliases(%q) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoAliasIsResolved(t *testing.T) {
+	config := parseRunFlags(t, "--repo-alias", "api=o/api-server,web=o/web")
+	if len(config.RepoAliases) != 2 {
+		t.Fatalf("RepoAliases = %v, want api and web", config.RepoAliases)
+	}
+
+	gh := &fakeGitHub{}
+	sc, _ := newTestSlackClient(t, config, gh)
+	sc.matcher.SetRepoAliases(config.RepoAliases)
+	sc.processMessage(context.Background(), testMessage("lgtm api#7 and unknown#8"))
+	waitForApprovals(t, sc)
+
+	if !gh.requested("POST /repos/o/api-server/pulls/7/reviews") {
+		t.Errorf("requests %v, want a review of o/api-server#7", gh.requests)
+	}
+	if gh.requested("POST /repos/o/web/pulls/8/reviews") {
+		t.Error("an unknown alias was resolved to another alias's repository")
+	}
+}
+
+// validConfig returns the `lgtm run` configuration for args, with tokens that pass
+// validateConfiguration
+func validConfig(t *testing.T, args ...string) *Configuration {
+	t.Helper()
+	return parseRunFlags(t, append([]string{"--github-token", "ghp_test", "--slack-bot-token", "xoxb-test", "--slack-app-token", "xapp-test"}, args...)...)
+}
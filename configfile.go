@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfigTemplate writes a commented YAML template covering every
+// Configuration field, using the struct tags as the single source of truth
+func writeConfigTemplate(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# lgtm configuration file\n")
+	b.WriteString("# Generated by `lgtm config init`. Values shown are the defaults.\n")
+
+	configType := reflect.TypeOf(Configuration{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+
+		b.WriteString("\n")
+		if desc := field.Tag.Get("desc"); desc != "" {
+			b.WriteString("# " + desc + "\n")
+		}
+		b.WriteString(key + ": " + templateValue(field) + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlKey returns the YAML key for a Configuration field, or empty if the field is not exposed
+func yamlKey(field reflect.StructField) string {
+	tag := field.Tag.Get("yaml")
+	if tag == "" || tag == "-" {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// configKeyIssue is a key in a configuration file that no Configuration field reads
+type configKeyIssue struct {
+	Key        string
+	Line       int
+	Suggestion string
+}
+
+// String describes the issue, e.g. `line 3: unknown key "slack_chanel_id" (did you mean "slack_channel_id"?)`
+func (issue configKeyIssue) String() string {
+	text := fmt.Sprintf("line %d: unknown key %q", issue.Line, issue.Key)
+	if issue.Suggestion != "" {
+		text += fmt.Sprintf(" (did you mean %q?)", issue.Suggestion)
+	}
+	return text
+}
+
+// unknownConfigKeys lists the keys of a YAML configuration file that don't match a
+// Configuration field, including keys nested in structs such as channel policies
+func unknownConfigKeys(data []byte) ([]configKeyIssue, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	var issues []configKeyIssue
+	collectUnknownKeys(document.Content[0], reflect.TypeOf(Configuration{}), "", &issues)
+	return issues, nil
+}
+
+// collectUnknownKeys checks a YAML node against the fields of t, descending into
+// struct-valued fields, maps and slices of structs
+func collectUnknownKeys(node *yaml.Node, t reflect.Type, prefix string, issues *[]configKeyIssue) {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice:
+		if node.Kind == yaml.MappingNode && t.Kind() == reflect.Map {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				collectUnknownKeys(node.Content[i+1], t.Elem(), prefix+node.Content[i].Value+".", issues)
+			}
+		}
+		if node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice {
+			for _, item := range node.Content {
+				collectUnknownKeys(item, t.Elem(), prefix, issues)
+			}
+		}
+		return
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Duration(0)) || node.Kind != yaml.MappingNode {
+			return
+		}
+	default:
+		return
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		if key := yamlKey(t.Field(i)); key != "" {
+			fields[key] = t.Field(i).Type
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		fieldType, ok := fields[keyNode.Value]
+		if !ok {
+			*issues = append(*issues, configKeyIssue{
+				Key:        prefix + keyNode.Value,
+				Line:       keyNode.Line,
+				Suggestion: closestKey(keyNode.Value, fields),
+			})
+			continue
+		}
+		collectUnknownKeys(node.Content[i+1], fieldType, prefix+keyNode.Value+".", issues)
+	}
+}
+
+// closestKey returns the known key within two edits of key, if any, to suggest for a typo
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if distance := editDistance(key, candidate); distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// checkConfigKeys reports unknown keys in a configuration file: as an error in strict
+// mode, otherwise as warnings
+func checkConfigKeys(path string, data []byte, strict bool) error {
+	issues, err := unknownConfigKeys(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if strict {
+		descriptions := make([]string, len(issues))
+		for i, issue := range issues {
+			descriptions[i] = issue.String()
+		}
+		return &ConfigError{Field: "config file", Message: fmt.Sprintf("%s has unknown keys:\n  %s", path, strings.Join(descriptions, "\n  "))}
+	}
+	for _, issue := range issues {
+		logWarn("%s: %s; the key is ignored", path, issue)
+	}
+	return nil
+}
+
+// templateValue renders the default value of a field as YAML
+func templateValue(field reflect.StructField) string {
+	def, hasDefault := field.Tag.Lookup("default")
+
+	if field.Type == reflect.TypeOf(time.Duration(0)) {
+		if !hasDefault {
+			def = "0s"
+		}
+		return fmt.Sprintf("%q", def)
+	}
+
+	switch field.Type.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", def)
+	case reflect.Bool:
+		if !hasDefault {
+			return "false"
+		}
+		return def
+	case reflect.Int, reflect.Int64:
+		if !hasDefault {
+			return "0"
+		}
+		return def
+	case reflect.Slice:
+		if !hasDefault {
+			return "[]"
+		}
+		return def
+	case reflect.Map:
+		if !hasDefault {
+			return "{}"
+		}
+		return def
+	default:
+		return fmt.Sprintf("%q", def)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a configuration value came from, lowest precedence first
+const (
+	configSourceDefault = "default"
+	configSourceFile    = "file"
+	configSourceEnv     = "env"
+	configSourceFlag    = "flag"
+)
+
+// descEnvPattern finds the environment variable named in a Configuration field's desc tag
+var descEnvPattern = regexp.MustCompile(`env: ([A-Z0-9_]+)`)
+
+// applyConfigFile merges the YAML file given by --config into a configuration read from
+// flags and the environment. A flag or environment variable that is set wins over the
+// file, and the file wins over flag defaults. Unknown keys are logged and ignored, or
+// fail with --strict-config. The source of every value is recorded for `lgtm validate`.
+func applyConfigFile(c *cli.Context, config *Configuration) error {
+	path := c.String("config")
+
+	file := &Configuration{}
+	fileKeys := make(map[string]bool)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+		// Commands set the log level once the configuration is parsed, so the unknown
+		// key warnings use the flag's level
+		if logLevel == "" {
+			logLevel = strings.ToLower(config.LogLevel)
+		}
+		if err := checkConfigKeys(path, data, c.Bool("strict-config")); err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, file); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+		var top map[string]yaml.Node
+		if err := yaml.Unmarshal(data, &top); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %v", path, err)
+		}
+		for key := range top {
+			fileKeys[key] = true
+		}
+	}
+
+	flags := flagsByEnvVar(c)
+	config.configFile = path
+	config.sources = make(map[string]string)
+
+	target, values := reflect.ValueOf(config).Elem(), reflect.ValueOf(file).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+
+		source := configSourceDefault
+		flag := flags[fieldEnvVar(field)]
+		switch {
+		case flag != nil && c.IsSet(flag.Names()[0]):
+			source = configSourceEnv
+			if flagOnCommandLine(os.Args[1:], flag.Names()) {
+				source = configSourceFlag
+			}
+		case fileKeys[key]:
+			target.Field(i).Set(values.Field(i))
+			source = configSourceFile
+		}
+		config.sources[key] = source
+	}
+	return nil
+}
+
+// fieldEnvVar returns the environment variable documented for a Configuration field
+func fieldEnvVar(field reflect.StructField) string {
+	if match := descEnvPattern.FindStringSubmatch(field.Tag.Get("desc")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// flagsByEnvVar indexes the flags of the running command and its parents by their
+// environment variables, which is how Configuration fields name their flag
+func flagsByEnvVar(c *cli.Context) map[string]cli.Flag {
+	var all []cli.Flag
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil {
+			all = append(all, ctx.Command.Flags...)
+		}
+	}
+	if c.App != nil {
+		all = append(all, c.App.Flags...)
+	}
+
+	flags := make(map[string]cli.Flag)
+	for _, flag := range all {
+		if docFlag, ok := flag.(cli.DocGenerationFlag); ok {
+			for _, env := range docFlag.GetEnvVars() {
+				if _, seen := flags[env]; !seen {
+					flags[env] = flag
+				}
+			}
+		}
+	}
+	return flags
+}
+
+// flagOnCommandLine reports whether one of a flag's names is among the arguments. c.IsSet
+// can't tell a flag given on the command line from one set by its environment variable.
+func flagOnCommandLine(args, names []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		for _, candidate := range names {
+			if name == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeConfigSources prints the source of every value not left at its default
+func writeConfigSources(w io.Writer, config *Configuration) {
+	if config.configFile != "" {
+		fmt.Fprintf(w, "Configuration sources (file: %s):\n", config.configFile)
+	} else {
+		fmt.Fprintln(w, "Configuration sources:")
+	}
+
+	keys := make([]string, 0, len(config.sources))
+	defaults := 0
+	for key, source := range config.sources {
+		if source == configSourceDefault {
+			defaults++
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", key, config.sources[key])
+	}
+	fmt.Fprintf(w, "  %d other setting(s) use their defaults\n", defaults)
+}
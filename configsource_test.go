@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// applyTestConfigFile applies the config file at path the way a command given args does
+func applyTestConfigFile(t *testing.T, path string, args ...string) (*Configuration, error) {
+	t.Helper()
+	config := &Configuration{}
+	var applyErr error
+	app := &cli.App{
+		Flags: []cli.Flag{configFileFlag(), strictConfigFlag()},
+		Action: func(c *cli.Context) error {
+			applyErr = applyConfigFile(c, config)
+			return nil
+		},
+	}
+	if err := app.Run(append([]string{"lgtm", "--config", path}, args...)); err != nil {
+		t.Fatalf("running app: %v", err)
+	}
+	return config, applyErr
+}
+
+func TestApplyConfigFileStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("github_owner: my-org\nslack_chanel_id: C1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := applyTestConfigFile(t, path)
+	if err != nil {
+		t.Fatalf("unknown key without --strict-config: %v, want a warning only", err)
+	}
+	if config.DefaultOwner != "my-org" {
+		t.Errorf("github_owner = %q, want my-org", config.DefaultOwner)
+	}
+
+	_, err = applyTestConfigFile(t, path, "--strict-config")
+	if err == nil || !strings.Contains(err.Error(), "slack_chanel_id") {
+		t.Errorf("unknown key with --strict-config: %v, want an error naming the key", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// defaultConfirmationWindow is how long armed approvals wait when no window is configured
+const defaultConfirmationWindow = 5 * time.Minute
+
+// armedApproval is a PR waiting for a confirmation message
+type armedApproval struct {
+	match   *PatternMatch
+	user    string
+	armedAt time.Time
+}
+
+// confirmationTracker holds PRs armed by a matching message until the same user
+// confirms them with the keyword in the same channel, or the window expires
+type confirmationTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	keyword *regexp.Regexp
+	armed   map[string]*armedApproval
+}
+
+// newConfirmationTracker creates a tracker; it returns nil when no keyword is configured
+func newConfirmationTracker(keyword string, window time.Duration) *confirmationTracker {
+	if keyword == "" {
+		return nil
+	}
+	if window <= 0 {
+		window = defaultConfirmationWindow
+	}
+	return &confirmationTracker{
+		window:  window,
+		keyword: confirmationKeywordPattern(keyword),
+		armed:   make(map[string]*armedApproval),
+	}
+}
+
+// confirmationKeywordPattern matches the keyword as a whole word, ignoring case
+func confirmationKeywordPattern(keyword string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^[:alnum:]_])` + regexp.QuoteMeta(keyword) + `([^[:alnum:]_]|$)`)
+}
+
+// isConfirmation reports whether a message contains the confirmation keyword
+func (ct *confirmationTracker) isConfirmation(text string) bool {
+	return ct.keyword.MatchString(text)
+}
+
+// arm records a PR from a matching message; arming it again restarts its window
+func (ct *confirmationTracker) arm(owner, repo string, prRef PRReference, match *PatternMatch) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.expire(time.Now())
+
+	single := *match
+	single.PRReferences = []PRReference{prRef}
+	ct.armed[armedKey(match.SourceMessage.Channel, owner, repo, prRef.Number)] = &armedApproval{
+		match:   &single,
+		user:    match.SourceMessage.User,
+		armedAt: time.Now(),
+	}
+}
+
+// confirm removes and returns the PRs the user armed in the channel within the window
+func (ct *confirmationTracker) confirm(channel, user string) []*PatternMatch {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.expire(time.Now())
+
+	var matches []*PatternMatch
+	for key, armed := range ct.armed {
+		if armed.match.SourceMessage.Channel != channel || armed.user != user {
+			continue
+		}
+		matches = append(matches, armed.match)
+		delete(ct.armed, key)
+	}
+	return matches
+}
+
+// expire drops armed PRs older than the window; callers hold the lock
+func (ct *confirmationTracker) expire(now time.Time) {
+	for key, armed := range ct.armed {
+		if now.Sub(armed.armedAt) > ct.window {
+			logDebug("Confirmation window expired for %s", key)
+			delete(ct.armed, key)
+		}
+	}
+}
+
+// armedKey identifies one armed PR in one channel
+func armedKey(channel, owner, repo string, number int) string {
+	return fmt.Sprintf("%s:%s/%s#%d", channel, owner, repo, number)
+}
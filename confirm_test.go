@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConfirmationKeyword(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      time.Duration
+		user        string
+		text        string
+		wantReviews int
+	}{
+		{name: "confirmed by the sender", window: time.Minute, user: "U1", text: "CONFIRM", wantReviews: 1},
+		{name: "confirmed by someone else", window: time.Minute, user: "U2", text: "confirm"},
+		{name: "keyword inside a word", window: time.Minute, user: "U1", text: "unconfirmed"},
+		{name: "window expired", window: time.Millisecond, user: "U1", text: "confirm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, &Configuration{RequireConfirmationKeyword: "confirm", ConfirmationWindow: tt.window}, gh)
+			ctx := context.Background()
+
+			sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+			if reviews := gh.submitted(); len(reviews) != 0 {
+				t.Fatalf("submitted %v before the confirmation", reviews)
+			}
+			if !reactions.has("raised_hand") {
+				t.Errorf("reactions %v, want the armed reaction", reactions.added)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			confirmation := testMessage(tt.text)
+			confirmation.User = tt.user
+			confirmation.Timestamp = "1700000001.000100"
+			sc.processMessage(ctx, confirmation)
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != tt.wantReviews {
+				t.Errorf("submitted %v after %q from %s, want %d review(s)", reviews, tt.text, tt.user, tt.wantReviews)
+			}
+		})
+	}
+}
+
+func TestConfirmationIsUsedOnce(t *testing.T) {
+	ct := newConfirmationTracker("ship it", 0)
+	if ct.window != defaultConfirmationWindow {
+		t.Errorf("window = %v, want the default %v", ct.window, defaultConfirmationWindow)
+	}
+	if !ct.isConfirmation("ok, Ship It!") || ct.isConfirmation("shipit") {
+		t.Error("the keyword isn't matched as whole words ignoring case")
+	}
+
+	ct.arm("o", "r", PRReference{Number: 1}, &PatternMatch{SourceMessage: testMessage("lgtm #1")})
+	if armed := ct.confirm("C1", "U1"); len(armed) != 1 || armed[0].PRReferences[0].Number != 1 {
+		t.Fatalf("confirm = %+v, want PR 1", armed)
+	}
+	if armed := ct.confirm("C1", "U1"); len(armed) != 0 {
+		t.Errorf("confirmed %d PR(s) a second time", len(armed))
+	}
+	if newConfirmationTracker("", time.Minute) != nil {
+		t.Error("a tracker without a keyword was created")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "0 * * * *"},
+		{expr: "*/15 9-17 * * 1-5"},
+		{expr: "0,30 8 1,15 */2 7"},
+		{expr: "5/10 * * * *"},
+		{expr: "0 * * *", wantErr: "must have 5 fields"},
+		{expr: "60 * * * *", wantErr: `"60" is out of range 0-59 for the minute field`},
+		{expr: "0 17-9 * * *", wantErr: `"17-9" is out of range 0-23 for the hour field`},
+		{expr: "0 * 0 * *", wantErr: "out of range 1-31 for the day of month field"},
+		{expr: "*/0 * * * *", wantErr: `invalid step "0" in minute field`},
+		{expr: "0 * * jan *", wantErr: `invalid value "jan" in month field`},
+		{expr: "0 * * * 1-x", wantErr: `invalid value "1-x" in day of week field`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseCronSchedule(tt.expr)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseCronSchedule: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseCronSchedule = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// 2024-01-10 is a Wednesday
+	from := time.Date(2024, 1, 10, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "0 * * * *", want: time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)},
+		{expr: "7 10 * * *", want: time.Date(2024, 1, 11, 10, 7, 0, 0, time.UTC)},
+		{expr: "0 9 * * 1-5", want: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", want: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted, either one matching is enough
+		{expr: "0 0 20 * 5", want: time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCronSchedule: %v", err)
+			}
+			if got := schedule.next(from); !got.Equal(tt.want) {
+				t.Errorf("next(%s) = %s, want %s", from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+
+// Approval decisions recorded once per processed PR reference
+const (
+	decisionApproved = "approved"
+	decisionSkipped  = "skipped"
+	decisionFailed   = "failed"
+	// decisionDryRun is a PR that passed validation and would have been approved
+	decisionDryRun = "dry_run"
+	// decisionReviewed is a PR that got a review other than an approval, such as a comment
+	decisionReviewed = "reviewed"
+	// decisionFallback is a PR GitHub wouldn't let the bot approve, recorded by AppApprovalFallback instead
+	decisionFallback = "fallback"
+)
+
+// outcomeNone is the decision outcome for skips that get no reaction
+const outcomeNone = "none"
+
+// approvalDecision is the outcome of processing one PR reference
+type approvalDecision struct {
+	Owner      string
+	Repository string
+	PRNumber   int
+	User       string
+	Channel    string
+	Decision   string
+	Outcome    string
+	Reason     string
+	Retries    int
+	// SkipReason classifies a skipped decision for the skip audit
+	SkipReason SkipReason
+}
+
+// newApprovalDecision starts the decision record for an approval request
+func newApprovalDecision(req *ApprovalRequest) *approvalDecision {
+	return &approvalDecision{
+		Owner:      req.Owner,
+		Repository: req.Repository,
+		PRNumber:   req.PRNumber,
+		User:       req.SourceUser,
+		Channel:    req.SourceChannel,
+	}
+}
+
+// reviewDecision fills in the decision for a review GitHub accepted: approved for an
+// approval, fallback for an approval recorded by AppApprovalFallback, and reviewed for
+// any other event
+func reviewDecision(decision *approvalDecision, result *ApprovalResult) {
+	if result.Fallback != "" {
+		metrics.Inc(metricFallbacks)
+		decision.Decision, decision.Outcome = decisionFallback, outcomeFallback
+		decision.Reason = notApprovedReason(result)
+		return
+	}
+	if result.approved() {
+		metrics.Inc(metricApprovals)
+		decision.Decision, decision.Outcome = decisionApproved, outcomeApproved
+		if result.AlreadyApproved {
+			decision.Reason = "already approved"
+		}
+		return
+	}
+	metrics.Inc(metricReviews)
+	decision.Decision, decision.Outcome = decisionReviewed, outcomeReviewed
+	decision.Reason = notApprovedReason(result)
+}
+
+// notApprovedReason explains why a review GitHub accepted is not an approval
+func notApprovedReason(result *ApprovalResult) string {
+	if result.Fallback != "" {
+		return fmt.Sprintf("GitHub refused the approval, recorded with the %s fallback", result.Fallback)
+	}
+	return fmt.Sprintf("submitted a %s review instead of an approval", result.Event)
+}
+
+// logDecision emits the single structured decision line for a PR reference.
+// The key=value format is stable so log pipelines can count outcomes.
+func logDecision(d *approvalDecision) {
+	logInfo("decision owner=%s repo=%s number=%d user=%s channel=%s decision=%s outcome=%s retries=%d reason=%q",
+		d.Owner, d.Repository, d.PRNumber, d.User, d.Channel, d.Decision, d.Outcome, d.Retries, d.Reason)
+}
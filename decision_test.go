@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// captureLog collects log lines at info level until the test ends
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buffer bytes.Buffer
+	level, flags, output := logLevel, log.Flags(), log.Writer()
+	logLevel = "info"
+	log.SetFlags(0)
+	log.SetOutput(&buffer)
+	t.Cleanup(func() {
+		logLevel = level
+		log.SetFlags(flags)
+		log.SetOutput(output)
+	})
+	return &buffer
+}
+
+// decisionLines returns the decision lines logged
+func decisionLines(buffer *bytes.Buffer) []string {
+	var lines []string
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		if strings.HasPrefix(line, "[INFO] decision ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestLogDecision(t *testing.T) {
+	buffer := captureLog(t)
+	logDecision(&approvalDecision{
+		Owner:      "o",
+		Repository: "r",
+		PRNumber:   1,
+		User:       "U1",
+		Channel:    "C1",
+		Decision:   decisionSkipped,
+		Outcome:    outcomeDenied,
+		Reason:     `policy "draft" failed`,
+		Retries:    2,
+	})
+
+	want := `[INFO] decision owner=o repo=r number=1 user=U1 channel=C1 decision=skipped outcome=denied retries=2 reason="policy \"draft\" failed"`
+	if got := strings.TrimSpace(buffer.String()); got != want {
+		t.Errorf("logDecision wrote %q, want %q", got, want)
+	}
+}
+
+func TestDecisionIsLoggedOncePerPR(t *testing.T) {
+	tests := []struct {
+		name         string
+		gh           *fakeGitHub
+		wantDecision string
+	}{
+		{name: "approved", gh: &fakeGitHub{}, wantDecision: "decision=approved"},
+		{name: "review refused", gh: &fakeGitHub{reviewStatus: http.StatusUnprocessableEntity, reviewError: "Validation Failed"}, wantDecision: "decision=failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newTestSlackClient(t, &Configuration{}, tt.gh)
+			buffer := captureLog(t)
+
+			sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			lines := decisionLines(buffer)
+			if len(lines) != 1 || !strings.Contains(lines[0], tt.wantDecision) {
+				t.Errorf("decision lines = %q, want one with %s", lines, tt.wantDecision)
+			}
+		})
+	}
+}
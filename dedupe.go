@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dedupeCache remembers approvals per triggering message so each PR is approved at most once
+// per root message within the window, even when the message is edited or redelivered
+type dedupeCache struct {
+	window time.Duration
+	store  Store
+}
+
+// newDedupeCache creates a dedupe cache; a zero window disables deduplication
+func newDedupeCache(window time.Duration, store Store) *dedupeCache {
+	return &dedupeCache{
+		window: window,
+		store:  store,
+	}
+}
+
+// approvalKey identifies one PR triggered from one root Slack message
+func approvalKey(req *ApprovalRequest) string {
+	channel, ts := req.SourceChannel, ""
+	if req.SourceMessage != nil {
+		channel, ts = req.SourceMessage.Channel, req.SourceMessage.Timestamp
+	}
+	return fmt.Sprintf("%s/%s#%d@%s/%s", req.Owner, req.Repository, req.PRNumber, channel, ts)
+}
+
+// claim records key and reports whether it was new within the window
+func (dc *dedupeCache) claim(key string) bool {
+	if dc.window <= 0 {
+		return true
+	}
+
+	claimed, err := dc.store.PutIfAbsent(context.Background(), storeNamespaceDedupe, key, time.Now().UTC().Format(time.RFC3339), dc.window)
+	if err != nil {
+		// Approving twice is safer than never approving; GitHub reports the duplicate
+		logWarn("Dedupe store unavailable, processing %s anyway: %v", key, err)
+		return true
+	}
+	return claimed
+}
+
+// release forgets key so a failed approval can be retried by a later edit
+func (dc *dedupeCache) release(key string) {
+	if dc.window <= 0 {
+		return
+	}
+	if err := dc.store.Delete(context.Background(), storeNamespaceDedupe, key); err != nil {
+		logWarn("Failed to release dedupe entry %s: %v", key, err)
+	}
+}
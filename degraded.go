@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultDegradedProbeInterval is how often GitHub is probed while degraded when no interval is configured
+const defaultDegradedProbeInterval = 30 * time.Second
+
+// degradedMode stops approvals while GitHub keeps answering with 5xx errors. It is
+// entered after threshold consecutive 5xx responses and left once a probe succeeds.
+type degradedMode struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	threshold int
+	interval  time.Duration
+	probe     func(ctx context.Context) error
+	failures  int
+	active    bool
+	since     time.Time
+	recovered chan struct{}
+}
+
+// newDegradedMode creates the degraded-mode tracker; it returns nil when threshold is zero.
+// Probing stops when ctx is cancelled.
+func newDegradedMode(ctx context.Context, threshold int, interval time.Duration, probe func(ctx context.Context) error) *degradedMode {
+	if threshold <= 0 {
+		return nil
+	}
+	if interval <= 0 {
+		interval = defaultDegradedProbeInterval
+	}
+	return &degradedMode{
+		ctx:       ctx,
+		threshold: threshold,
+		interval:  interval,
+		probe:     probe,
+	}
+}
+
+// observe counts a GitHub response status, entering degraded mode on a run of 5xx responses
+func (dm *degradedMode) observe(status int) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if status < http.StatusInternalServerError {
+		// Only the probe ends degraded mode, so a stray success doesn't flap it
+		if !dm.active {
+			dm.failures = 0
+		}
+		return
+	}
+
+	dm.failures++
+	if dm.active || dm.failures < dm.threshold {
+		return
+	}
+
+	dm.active = true
+	dm.since = time.Now()
+	dm.recovered = make(chan struct{})
+	metrics.SetGauge(metricGitHubDegraded, 1)
+	logWarn("GITHUB DEGRADED: %d consecutive 5xx responses; approvals are on hold until GitHub answers a health probe (every %v)", dm.failures, dm.interval)
+	go dm.probeUntilRecovered()
+}
+
+// probeUntilRecovered probes GitHub every interval and leaves degraded mode on the first success
+func (dm *degradedMode) probeUntilRecovered() {
+	ticker := time.NewTicker(dm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-dm.ctx.Done():
+			return
+		}
+
+		if err := dm.probe(dm.ctx); err != nil {
+			logDebug("GitHub health probe failed: %v", err)
+			continue
+		}
+
+		dm.mu.Lock()
+		logInfo("GitHub recovered after %v; resuming approvals", time.Since(dm.since).Round(time.Second))
+		dm.active = false
+		dm.failures = 0
+		close(dm.recovered)
+		metrics.SetGauge(metricGitHubDegraded, 0)
+		dm.mu.Unlock()
+		return
+	}
+}
+
+// isActive reports whether approvals are on hold; a nil tracker is never degraded
+func (dm *degradedMode) isActive() bool {
+	if dm == nil {
+		return false
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.active
+}
+
+// wait blocks until GitHub recovers or ctx is cancelled
+func (dm *degradedMode) wait(ctx context.Context) error {
+	if dm == nil {
+		return nil
+	}
+
+	dm.mu.Lock()
+	active, recovered := dm.active, dm.recovered
+	dm.mu.Unlock()
+	if !active {
+		return nil
+	}
+
+	select {
+	case <-recovered:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// degradedTransport feeds every GitHub response status to the degraded-mode tracker
+type degradedTransport struct {
+	base http.RoundTripper
+	mode *degradedMode
+}
+
+func (t *degradedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.mode.observe(resp.StatusCode)
+	}
+	return resp, err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDegradedModeRecoversAfterHealthyProbe(t *testing.T) {
+	var probes atomic.Int32
+	dm := newDegradedMode(t.Context(), 2, time.Millisecond, func(ctx context.Context) error {
+		if probes.Add(1) < 3 {
+			return errors.New("502 Bad Gateway")
+		}
+		return nil
+	})
+
+	dm.observe(http.StatusBadGateway)
+	if dm.isActive() {
+		t.Fatal("degraded after one 5xx response, want the threshold of two")
+	}
+	dm.observe(http.StatusBadGateway)
+	if !dm.isActive() {
+		t.Fatal("not degraded after two 5xx responses")
+	}
+
+	// Only a probe ends degraded mode
+	dm.observe(http.StatusOK)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	if err := dm.wait(ctx); err != nil {
+		t.Fatalf("wait: %v, want recovery", err)
+	}
+	if dm.isActive() {
+		t.Error("still degraded after a healthy probe")
+	}
+	if got := probes.Load(); got != 3 {
+		t.Errorf("probed %d time(s), want 3", got)
+	}
+	if got := metrics.Gauge(metricGitHubDegraded); got != 0 {
+		t.Errorf("%s = %v after recovery, want 0", metricGitHubDegraded, got)
+	}
+
+	// A fresh run of 5xx responses is counted from zero after recovering
+	dm.observe(http.StatusBadGateway)
+	if dm.isActive() {
+		t.Error("degraded again after one 5xx response")
+	}
+}
+
+func TestDegradedModeWaitsWhileProbesFail(t *testing.T) {
+	dm := newDegradedMode(t.Context(), 1, time.Millisecond, func(ctx context.Context) error {
+		return errors.New("503 Service Unavailable")
+	})
+	dm.observe(http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := dm.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want the deadline while GitHub stays down", err)
+	}
+	if !dm.isActive() {
+		t.Error("recovered although every probe failed")
+	}
+}
+
+func TestDegradedModeDisabled(t *testing.T) {
+	dm := newDegradedMode(t.Context(), 0, 0, nil)
+	if dm != nil {
+		t.Fatal("degraded mode created with a zero threshold")
+	}
+	if dm.isActive() || dm.wait(t.Context()) != nil {
+		t.Error("a disabled tracker held approvals")
+	}
+}
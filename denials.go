@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+// defaultDenialTemplatePolicy is the template key used for policies without their own template
+const defaultDenialTemplatePolicy = "default"
+
+// defaultDenialTemplates explain each policy gate failure in the Slack thread
+var defaultDenialTemplates = map[string]string{
+	"draft":                     "Not approving {{.PR}} yet: it's still a draft.",
+	"approval-checkbox":         "Not approving {{.PR}}: the \"safe to auto-approve\" checkbox in the PR description isn't checked.",
+	"required-label":            "Not approving {{.PR}}: it needs a required label. {{.Reason}}.",
+	"allowed-author":            "Not approving {{.PR}}: its author isn't allowed to be auto-approved. {{.Reason}}.",
+	"safe-paths":                "Not approving {{.PR}}: it changes files outside the safe paths. {{.Reason}}.",
+	"min-approvals":             "Not approving {{.PR}}: it needs more human approvals first. {{.Reason}}.",
+	"requested-changes":         "Not approving {{.PR}}: a reviewer has requested changes. {{.Reason}}.",
+	"up-to-date":                "Not approving {{.PR}} yet: it needs to be updated from its base branch. {{.Reason}}.",
+	"verified-commits":          "Not approving {{.PR}}: its head commit isn't signed and verified. {{.Reason}}.",
+	"archived":                  "Not approving {{.PR}}: its repository is archived. {{.Reason}}.",
+	"repository":                "Not approving {{.PR}}: the bot isn't set up to approve in its repository. {{.Reason}}.",
+	"completed-check":           "Not approving {{.PR}} yet: no CI check has finished. {{.Reason}}.",
+	"linked-issue":              "Not approving {{.PR}}: it doesn't close a tracked issue. {{.Reason}}.",
+	"pr-fields":                 "Not approving {{.PR}}: its description doesn't fill in the PR template. {{.Reason}}.",
+	"team-reviews":              "Not approving {{.PR}} yet: a team still has to review it. {{.Reason}}.",
+	externalPolicyName:          "Not approving {{.PR}}: the external policy denied it. {{.Reason}}.",
+	defaultDenialTemplatePolicy: "Not approving {{.PR}}: policy {{.Policy}} not satisfied. {{.Reason}}.",
+}
+
+// denialData is the data available to denial templates
+type denialData struct {
+	Owner      string
+	Repository string
+	PRNumber   int
+	PR         string
+	Policy     string
+	Reason     string
+}
+
+// parseDenialTemplates compiles the denial templates, with overrides replacing the defaults per policy
+func parseDenialTemplates(overrides map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for policy, text := range defaultDenialTemplates {
+		if override, ok := overrides[policy]; ok {
+			text = override
+		}
+		tmpl, err := template.New("denial-" + policy).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("denial template for %s: %v", policy, err)
+		}
+		templates[policy] = tmpl
+	}
+	return templates, nil
+}
+
+// parseDenialTemplateFlags parses policy=template pairs
+func parseDenialTemplateFlags(values []string) (map[string]string, error) {
+	pairs, err := parseKeyValues(values, "DenialTemplates", "denial template", "policy=template")
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]string)
+	for _, pair := range pairs {
+		templates[pair.key] = pair.value
+	}
+	return templates, nil
+}
+
+// explainDenial replies in the message thread with why a policy gate blocked an approval
+func (sc *SlackClient) explainDenial(req *ApprovalRequest, err error) {
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return
+	}
+
+	tmpl, ok := sc.denialTemplates[policyErr.Policy]
+	if !ok {
+		tmpl = sc.denialTemplates[defaultDenialTemplatePolicy]
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, denialData{
+		Owner:      req.Owner,
+		Repository: req.Repository,
+		PRNumber:   req.PRNumber,
+		PR:         fmt.Sprintf("%s/%s#%d", req.Owner, req.Repository, req.PRNumber),
+		Policy:     policyErr.Policy,
+		Reason:     policyErr.Message,
+	}); err != nil {
+		logWarn("Failed to render denial explanation for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		return
+	}
+
+	if err := sc.replyInThread(req.SourceChannel, req.SourceMessage.Timestamp, req.SourceMessage.ThreadTS, text.String()); err != nil {
+		logWarn("Failed to post denial explanation for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+	}
+}
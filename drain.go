@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// drainPath is the admin endpoint that drains the instance before a rolling restart
+const drainPath = "/v1/admin/drain"
+
+// drainSwitch stops new approvals from starting so an orchestrator can roll the
+// instance once the ones in flight finish. Unlike SIGTERM it keeps the process,
+// its Slack connection and its API running, and can be undone.
+type drainSwitch struct {
+	draining atomic.Bool
+}
+
+// newDrainSwitch creates a switch that is not draining
+func newDrainSwitch() *drainSwitch {
+	metrics.SetGauge(metricDraining, 0)
+	return &drainSwitch{}
+}
+
+// start begins draining, reporting false when already draining
+func (ds *drainSwitch) start() bool {
+	if !ds.draining.CompareAndSwap(false, true) {
+		return false
+	}
+	metrics.SetGauge(metricDraining, 1)
+	return true
+}
+
+// stop accepts new approvals again, reporting false when not draining
+func (ds *drainSwitch) stop() bool {
+	if !ds.draining.CompareAndSwap(true, false) {
+		return false
+	}
+	metrics.SetGauge(metricDraining, 0)
+	return true
+}
+
+// active reports whether the instance is draining
+func (ds *drainSwitch) active() bool {
+	return ds.draining.Load()
+}
+
+// drainStatus is the JSON body of every drain endpoint response
+type drainStatus struct {
+	Draining bool `json:"draining"`
+	InFlight int  `json:"in_flight"`
+	// Drained is set once draining and no approvals are left in flight, when it is safe to stop
+	Drained bool `json:"drained"`
+}
+
+// drainAPI serves the drain endpoint: GET reports the drain state, POST starts draining
+// and DELETE stops it. It needs the approval API's shared secret as a bearer token.
+type drainAPI struct {
+	config   *Configuration
+	drain    *drainSwitch
+	inFlight *approvalSlots
+}
+
+// ServeHTTP authenticates the request and applies it to the drain switch
+func (api *drainAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !bearerAuthorized(r, api.config.APISecret) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="lgtm"`)
+		writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if api.drain.start() {
+			logInfo("Draining: no new approvals will start, %d in flight", api.inFlight.count())
+		}
+	case http.MethodDelete:
+		if api.drain.stop() {
+			logInfo("Drain cancelled: accepting approvals again")
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET, POST or DELETE")
+		return
+	}
+
+	inFlight := api.inFlight.count()
+	writeAPIJSON(w, http.StatusOK, drainStatus{
+		Draining: api.drain.active(),
+		InFlight: inFlight,
+		Drained:  api.drain.active() && inFlight == 0,
+	})
+}
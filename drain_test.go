@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// drainRequest sends a request to the drain endpoint and returns the drain state
+func drainRequest(t *testing.T, api *drainAPI, method string) drainStatus {
+	t.Helper()
+	r := httptest.NewRequest(method, drainPath, nil)
+	r.Header.Set("Authorization", "Bearer "+testAPISecret)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+
+	var status drainStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("decoding drain status: %v", err)
+	}
+	return status
+}
+
+func TestDrainWaitsForAPIApprovals(t *testing.T) {
+	gh := &fakeGitHub{reviewGate: make(chan struct{})}
+	sc, _ := newTestSlackClient(t, &Configuration{APISecret: testAPISecret}, gh)
+	api := newApprovalAPI(sc.config, sc)
+	drain := &drainAPI{config: sc.config, drain: sc.drain, inFlight: sc.inFlight}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postApproval(api) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.inFlight.count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the API approval never counted as in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if status := drainRequest(t, drain, http.MethodPost); !status.Draining || status.InFlight != 1 || status.Drained {
+		t.Errorf("drain status %+v with an API approval running, want 1 in flight and not drained", status)
+	}
+	if w := postApproval(api); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("API approval while draining got status %d, want 503", w.Code)
+	}
+
+	close(gh.reviewGate)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("in-flight API approval got status %d, want 200", w.Code)
+	}
+	if status := drainRequest(t, drain, http.MethodGet); status.InFlight != 0 || !status.Drained {
+		t.Errorf("drain status %+v once the approval finished, want drained", status)
+	}
+	if reviews := gh.submitted(); len(reviews) != 1 {
+		t.Errorf("submitted %v, want only the approval started before draining", reviews)
+	}
+}
+
+func TestDrainRejectsSlackApprovals(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{}, gh)
+	sc.drain.start()
+
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 {
+		t.Errorf("submitted %v while draining", reviews)
+	}
+	if !reactions.has("door") {
+		t.Errorf("reactions %v, want door", reactions.added)
+	}
+}
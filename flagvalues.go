@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// listFlag returns the items of a list option, such as user IDs or labels. Each flag
+// or environment variable value may hold several items separated by commas.
+func listFlag(c *cli.Context, name string) []string {
+	var items []string
+	for _, value := range c.StringSlice(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// textFlag returns the values of a free-text option, such as a pattern or a template,
+// which may contain commas. An environment variable holds one value per line.
+func textFlag(c *cli.Context, name string) []string {
+	var values []string
+	for _, value := range c.StringSlice(name) {
+		for _, line := range strings.Split(value, "\n") {
+			if strings.TrimSpace(line) != "" {
+				values = append(values, line)
+			}
+		}
+	}
+	return values
+}
+
+// keyValue is one key=value option value
+type keyValue struct {
+	key   string
+	value string
+}
+
+// parseKeyValues splits key=value option values at their first "=", trimming the key
+// and keeping the value as given. A value without "=" or a key fails with a ConfigError
+// for field saying what the value must look like, e.g. "denial template" and
+// "policy=template".
+func parseKeyValues(values []string, field, what, form string) ([]keyValue, error) {
+	pairs := make([]keyValue, 0, len(values))
+	for _, value := range values {
+		key, rest, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, &ConfigError{Field: field, Message: fmt.Sprintf("%s %q must be in %s form", what, value, form)}
+		}
+		pairs = append(pairs, keyValue{key: key, value: rest})
+	}
+	return pairs, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// parseRunFlags parses the configuration of `lgtm run` with args
+func parseRunFlags(t *testing.T, args ...string) *Configuration {
+	t.Helper()
+	app := newApp()
+	var config *Configuration
+	for _, command := range app.Commands {
+		if command.Name == "run" {
+			command.Action = func(c *cli.Context) error {
+				var err error
+				config, err = parseConfig(c)
+				return err
+			}
+		}
+	}
+	if err := app.Run(append([]string{"lgtm", "run"}, args...)); err != nil {
+		t.Fatalf("lgtm run %v: %v", args, err)
+	}
+	return config
+}
+
+func TestListFlagsSplitOnCommas(t *testing.T) {
+	t.Setenv("REQUIRED_LABELS", "safe, docs")
+	config := parseRunFlags(t, "--allowed-users", "U1,U2", "--allowed-users", "U3")
+
+	if want := []string{"U1", "U2", "U3"}; !reflect.DeepEqual(config.AllowedSlackUsers, want) {
+		t.Errorf("AllowedSlackUsers = %v, want %v", config.AllowedSlackUsers, want)
+	}
+	if want := []string{"safe", "docs"}; !reflect.DeepEqual(config.RequiredLabels, want) {
+		t.Errorf("RequiredLabels = %v, want %v", config.RequiredLabels, want)
+	}
+}
+
+func TestDenialTemplateKeepsCommas(t *testing.T) {
+	config := parseRunFlags(t, "--denial-template", "draft=Not approving {{.PR}}, it is a draft")
+	if got := config.DenialTemplates["draft"]; got != "Not approving {{.PR}}, it is a draft" {
+		t.Errorf("draft template = %q, want the whole value", got)
+	}
+
+	t.Setenv("DENIAL_TEMPLATES", "draft=Not yet, it is a draft\nrequired-label=Needs a label, sorry")
+	config = parseRunFlags(t)
+	want := map[string]string{"draft": "Not yet, it is a draft", "required-label": "Needs a label, sorry"}
+	if !reflect.DeepEqual(config.DenialTemplates, want) {
+		t.Errorf("DenialTemplates = %v, want one template per line %v", config.DenialTemplates, want)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	pairs, err := parseKeyValues([]string{" draft = a=b, c"}, "DenialTemplates", "denial template", "policy=template")
+	if err != nil {
+		t.Fatalf("parseKeyValues: %v", err)
+	}
+	if want := []keyValue{{key: "draft", value: " a=b, c"}}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("pairs = %+v, want %+v", pairs, want)
+	}
+
+	for _, value := range []string{"no separator", "=missing key"} {
+		_, err := parseKeyValues([]string{value}, "DenialTemplates", "denial template", "policy=template")
+		want := `denial template "` + value + `" must be in policy=template form`
+		if configErr, ok := err.(*ConfigError); !ok || configErr.Message != want {
+			t.Errorf("parseKeyValues(%q) = %v, want %q", value, err, want)
+		}
+	}
+}
+
+func TestChannelPatternKeepsCommas(t *testing.T) {
+	config := parseRunFlags(t, "--channel-pattern", "C1=lgtm{1,3}", "--channel-pattern", "C1=ship (it|this), please")
+	want := map[string][]string{"C1": {"lgtm{1,3}", "ship (it|this), please"}}
+	if !reflect.DeepEqual(config.ChannelPatterns, want) {
+		t.Errorf("ChannelPatterns = %v, want %v", config.ChannelPatterns, want)
+	}
+
+	t.Setenv("CHANNEL_PATTERNS", "C1=lgtm{1,3}\nC2=ship it")
+	config = parseRunFlags(t)
+	want = map[string][]string{"C1": {"lgtm{1,3}"}, "C2": {"ship it"}}
+	if !reflect.DeepEqual(config.ChannelPatterns, want) {
+		t.Errorf("ChannelPatterns = %v, want one pattern per line %v", config.ChannelPatterns, want)
+	}
+
+	matcher, err := NewMultiPatternMatcher(config.ChannelPatterns["C1"], "")
+	if err != nil {
+		t.Fatalf("NewMultiPatternMatcher: %v", err)
+	}
+	if match, err := matcher.Match("lgtmm"); err != nil || match == nil {
+		t.Errorf("Match(lgtmm) = %v, %v, want a match for lgtm{1,3}", match, err)
+	}
+}
+
+func TestExtraPatternKeepsCommas(t *testing.T) {
+	config := parseRunFlags(t, "--extra-pattern", "lgtm{1,3}", "--extra-pattern", "ship (it|this)")
+	if want := []string{"lgtm{1,3}", "ship (it|this)"}; !reflect.DeepEqual(config.ExtraPatterns, want) {
+		t.Errorf("ExtraPatterns = %v, want %v", config.ExtraPatterns, want)
+	}
+
+	t.Setenv("EXTRA_MESSAGE_PATTERNS", "lgtm{1,3}\nship it")
+	config = parseRunFlags(t)
+	if want := []string{"lgtm{1,3}", "ship it"}; !reflect.DeepEqual(config.ExtraPatterns, want) {
+		t.Errorf("ExtraPatterns = %v, want one pattern per line %v", config.ExtraPatterns, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FreezeWindow is a date range, e.g. a holiday deploy freeze, during which approvals are held
+type FreezeWindow struct {
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+}
+
+// String formats the window the way --freeze-window takes it
+func (w FreezeWindow) String() string {
+	return w.Start.Format(time.RFC3339) + "/" + w.End.Format(time.RFC3339)
+}
+
+// parseFreezeWindows parses start/end pairs of RFC 3339 timestamps,
+// e.g. 2026-12-20T00:00:00Z/2027-01-04T00:00:00Z
+func parseFreezeWindows(values []string) ([]FreezeWindow, error) {
+	var windows []FreezeWindow
+	for _, value := range values {
+		start, end, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, &ConfigError{Field: "FreezeWindows", Message: fmt.Sprintf("freeze window %q must be in start/end form", value)}
+		}
+
+		var window FreezeWindow
+		var err error
+		if window.Start, err = time.Parse(time.RFC3339, strings.TrimSpace(start)); err != nil {
+			return nil, &ConfigError{Field: "FreezeWindows", Message: fmt.Sprintf("invalid start of freeze window %q: %v", value, err)}
+		}
+		if window.End, err = time.Parse(time.RFC3339, strings.TrimSpace(end)); err != nil {
+			return nil, &ConfigError{Field: "FreezeWindows", Message: fmt.Sprintf("invalid end of freeze window %q: %v", value, err)}
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// activeFreeze returns the freeze window covering now, if any. Windows include their
+// start and exclude their end; of overlapping windows the one ending last is returned.
+func activeFreeze(windows []FreezeWindow, now time.Time) (FreezeWindow, bool) {
+	var active FreezeWindow
+	found := false
+	for _, window := range windows {
+		if now.Before(window.Start) || !now.Before(window.End) {
+			continue
+		}
+		if !found || window.End.After(active.End) {
+			active, found = window, true
+		}
+	}
+	return active, found
+}
+
+// frozenFor reports the freeze window holding a Slack user's approvals; users on the
+// override allowlist are never frozen
+func (config *Configuration) frozenFor(user string, now time.Time) (FreezeWindow, bool) {
+	for _, override := range config.FreezeOverrideUsers {
+		if override == user {
+			return FreezeWindow{}, false
+		}
+	}
+	return activeFreeze(config.FreezeWindows, now)
+}
+
+// waitForFreeze blocks until no freeze window covers the current time, following
+// windows that overlap or follow each other directly
+func waitForFreeze(ctx context.Context, windows []FreezeWindow) error {
+	for {
+		window, frozen := activeFreeze(windows, time.Now())
+		if !frozen {
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(window.End))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// prGate is a single check a PR must pass before it is approved
+type prGate struct {
+	Name    string
+	Enabled bool
+	Check   func(ctx context.Context, pr *github.PullRequest) error
+}
+
+// GateResult is the outcome of evaluating one gate
+type GateResult struct {
+	Name    string
+	Enabled bool
+	Err     error
+}
+
+// prGates returns the validation gates for a policy, in evaluation order
+func (gc *GitHubClient) prGates(policy Policy) []prGate {
+	return []prGate{
+		{
+			Name:    "state",
+			Enabled: true,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				if pr.GetState() != "open" {
+					return fmt.Errorf("PR #%d is %s and cannot be approved", pr.GetNumber(), pr.GetState())
+				}
+				if pr.GetMerged() {
+					return fmt.Errorf("PR #%d is already merged", pr.GetNumber())
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "draft",
+			Enabled: gc.config.SkipDrafts,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				if pr.GetDraft() {
+					return &PolicyError{Policy: "draft", Message: fmt.Sprintf("PR #%d is a draft", pr.GetNumber())}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "archived",
+			Enabled: true,
+			Check:   gc.checkNotArchived,
+		},
+		{
+			Name:    "repository",
+			Enabled: len(gc.config.Repositories) > 0,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				if repo := pr.GetBase().GetRepo().GetFullName(); !containsFold(gc.config.Repositories, repo) {
+					return &PolicyError{Policy: "repository", Message: fmt.Sprintf("PR #%d is in %s, which is not a configured repository", pr.GetNumber(), repo)}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "approval-checkbox",
+			Enabled: gc.checkboxPattern != nil,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				if !gc.checkboxPattern.MatchString(pr.GetBody()) {
+					return &PolicyError{Policy: "approval-checkbox", Message: fmt.Sprintf("PR #%d does not have the auto-approve checkbox checked", pr.GetNumber())}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "required-label",
+			Enabled: len(policy.RequiredLabels) > 0,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				for _, required := range policy.RequiredLabels {
+					hasLabel := false
+					for _, label := range pr.Labels {
+						if strings.EqualFold(label.GetName(), required) {
+							hasLabel = true
+							break
+						}
+					}
+					if !hasLabel {
+						return &PolicyError{Policy: "required-label", Message: fmt.Sprintf("PR #%d is missing required label %q", pr.GetNumber(), required)}
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "allowed-author",
+			Enabled: len(policy.AllowedAuthors) > 0,
+			Check: func(ctx context.Context, pr *github.PullRequest) error {
+				if !containsFold(policy.AllowedAuthors, pr.GetUser().GetLogin()) {
+					return &PolicyError{Policy: "allowed-author", Message: fmt.Sprintf("PR #%d author %s is not an allowed author", pr.GetNumber(), pr.GetUser().GetLogin())}
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "linked-issue",
+			Enabled: gc.config.RequireLinkedIssue,
+			Check:   gc.checkLinkedIssue,
+		},
+		{
+			Name:    "pr-fields",
+			Enabled: len(gc.config.RequiredPRFields) > 0,
+			Check:   gc.checkPRFields,
+		},
+		{
+			Name:    "team-reviews",
+			Enabled: gc.config.PendingTeamReviews == PendingTeamsSkip,
+			Check:   gc.checkPendingTeamReviews,
+		},
+		{
+			Name:    "min-approvals",
+			Enabled: gc.config.MinExistingApprovals > 0,
+			Check:   gc.checkMinApprovals,
+		},
+		{
+			Name:    "requested-changes",
+			Enabled: gc.config.RespectRequestedChanges,
+			Check:   gc.checkNoRequestedChanges,
+		},
+		{
+			Name:    "up-to-date",
+			Enabled: gc.config.RequireUpToDate,
+			Check:   gc.checkUpToDate,
+		},
+		{
+			Name:    "verified-commits",
+			Enabled: gc.config.RequireVerifiedCommits,
+			Check:   gc.checkVerifiedHead,
+		},
+		{
+			Name:    "completed-check",
+			Enabled: gc.config.RequireAnyCompletedCheck,
+			Check:   gc.checkAnyCompletedCheck,
+		},
+		{
+			Name:    "safe-paths",
+			Enabled: len(gc.safePaths) > 0,
+			Check:   gc.checkSafePaths,
+		},
+	}
+}
+
+// checkNotArchived fails for PRs in archived repositories, which GitHub makes read-only.
+// The PR's base repository normally says whether it is archived; the repository is only
+// looked up, through the cache, when it doesn't.
+func (gc *GitHubClient) checkNotArchived(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	archived := pr.GetBase().GetRepo().Archived
+	if archived == nil {
+		metadata, err := gc.repository(ctx, owner, repo)
+		if err != nil {
+			return err
+		}
+		archived = &metadata.Archived
+	}
+	if *archived {
+		return &PolicyError{Policy: "archived", Message: fmt.Sprintf("PR #%d is in archived repository %s/%s", pr.GetNumber(), owner, repo)}
+	}
+	return nil
+}
+
+// compileGlob converts a path glob to a regex: ** matches across directories, so
+// **/ matches zero or more of them, and * and ? match within a single path segment
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	runes := []rune(glob)
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+2 < len(runes) && runes[i+1] == '*' && runes[i+2] == '/' {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if i+1 < len(runes) && runes[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// compileGlobs compiles a list of path globs
+func compileGlobs(globs []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, glob := range globs {
+		pattern, err := compileGlob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %v", glob, err)
+		}
+		compiled = append(compiled, pattern)
+	}
+	return compiled, nil
+}
+
+// checkSafePaths fails if any file changed by the PR falls outside the safe path patterns
+func (gc *GitHubClient) checkSafePaths(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+	listOptions := &github.ListOptions{PerPage: gc.perPage()}
+
+	for {
+		files, response, err := gc.client.PullRequests.ListFiles(ctx, owner, repo, pr.GetNumber(), listOptions)
+		if err != nil {
+			return fmt.Errorf("failed to list files for PR #%d: %v", pr.GetNumber(), err)
+		}
+
+		for _, file := range files {
+			// Renames must be safe on both sides
+			for _, name := range []string{file.GetFilename(), file.GetPreviousFilename()} {
+				if name != "" && !matchesAny(gc.safePaths, name) {
+					return &PolicyError{Policy: "safe-paths", Message: fmt.Sprintf("PR #%d changes %s, which is outside the safe paths", pr.GetNumber(), name)}
+				}
+			}
+		}
+
+		if response.NextPage == 0 {
+			return nil
+		}
+		listOptions.Page = response.NextPage
+	}
+}
+
+// matchesAny reports whether value matches any of the patterns
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewerStates returns each human reviewer's latest effective review state, excluding the bot.
+// Comment-only reviews don't change a reviewer's state.
+func (gc *GitHubClient) reviewerStates(ctx context.Context, pr *github.PullRequest) (map[string]string, error) {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	botLogin, err := gc.botLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := gc.listReviews(ctx, owner, repo, pr.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string)
+	for _, review := range reviews {
+		login := review.GetUser().GetLogin()
+		if strings.EqualFold(login, botLogin) {
+			continue
+		}
+		switch review.GetState() {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			states[strings.ToLower(login)] = review.GetState()
+		}
+	}
+	return states, nil
+}
+
+// checkMinApprovals fails unless enough humans have already approved the PR
+func (gc *GitHubClient) checkMinApprovals(ctx context.Context, pr *github.PullRequest) error {
+	states, err := gc.reviewerStates(ctx, pr)
+	if err != nil {
+		return err
+	}
+
+	approvals := 0
+	for _, state := range states {
+		if state == "APPROVED" {
+			approvals++
+		}
+	}
+
+	if approvals < gc.config.MinExistingApprovals {
+		return &PolicyError{Policy: "min-approvals", Message: fmt.Sprintf("PR #%d has %d of %d required human approvals", pr.GetNumber(), approvals, gc.config.MinExistingApprovals)}
+	}
+	return nil
+}
+
+// checkNoRequestedChanges fails while any human reviewer has outstanding requested changes
+func (gc *GitHubClient) checkNoRequestedChanges(ctx context.Context, pr *github.PullRequest) error {
+	states, err := gc.reviewerStates(ctx, pr)
+	if err != nil {
+		return err
+	}
+
+	var reviewers []string
+	for login, state := range states {
+		if state == "CHANGES_REQUESTED" {
+			reviewers = append(reviewers, login)
+		}
+	}
+
+	if len(reviewers) > 0 {
+		sort.Strings(reviewers)
+		return &PolicyError{Policy: "requested-changes", Message: fmt.Sprintf("PR #%d has changes requested by %s", pr.GetNumber(), strings.Join(reviewers, ", "))}
+	}
+	return nil
+}
+
+// checkUpToDate fails when the PR branch is behind its base branch. ValidatePRReference
+// waits for GitHub to compute mergeable_state first; if it is still unknown, the head
+// is compared with the base branch instead.
+func (gc *GitHubClient) checkUpToDate(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	switch pr.GetMergeableState() {
+	case "behind":
+		return gc.behindError(pr)
+	case "", "unknown":
+		comparison, _, err := gc.client.Repositories.CompareCommits(ctx, owner, repo, pr.GetBase().GetRef(), pr.GetHead().GetSHA(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to compare PR #%d with %s: %v", pr.GetNumber(), pr.GetBase().GetRef(), err)
+		}
+		if comparison.GetBehindBy() > 0 {
+			return gc.behindError(pr)
+		}
+	}
+	return nil
+}
+
+// behindError reports a PR that needs updating from its base branch
+func (gc *GitHubClient) behindError(pr *github.PullRequest) error {
+	return &PolicyError{Policy: "up-to-date", Message: fmt.Sprintf("PR #%d is behind %s", pr.GetNumber(), pr.GetBase().GetRef())}
+}
+
+// checkVerifiedHead fails unless GitHub reports the PR head commit's signature as verified
+func (gc *GitHubClient) checkVerifiedHead(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+	sha := pr.GetHead().GetSHA()
+
+	commit, _, err := gc.client.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get head commit of PR #%d: %v", pr.GetNumber(), err)
+	}
+
+	verification := commit.GetCommit().GetVerification()
+	if verification == nil {
+		return &PolicyError{Policy: "verified-commits", Message: fmt.Sprintf("PR #%d head commit %.7s has no verification information", pr.GetNumber(), sha)}
+	}
+	if !verification.GetVerified() {
+		return &PolicyError{Policy: "verified-commits", Message: fmt.Sprintf("PR #%d head commit %.7s is not verified (%s)", pr.GetNumber(), sha, verification.GetReason())}
+	}
+	return nil
+}
+
+// checkAnyCompletedCheck fails until at least one check run on the PR head commit has
+// completed, whatever its conclusion, so approvals don't race CI that hasn't run yet
+func (gc *GitHubClient) checkAnyCompletedCheck(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+	sha := pr.GetHead().GetSHA()
+	listOptions := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: gc.perPage()}}
+
+	total := 0
+	for {
+		results, response, err := gc.client.Checks.ListCheckRunsForRef(ctx, owner, repo, sha, listOptions)
+		if err != nil {
+			return fmt.Errorf("failed to list check runs for PR #%d: %v", pr.GetNumber(), err)
+		}
+
+		total = results.GetTotal()
+		for _, run := range results.CheckRuns {
+			if run.GetStatus() == "completed" {
+				return nil
+			}
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+		listOptions.Page = response.NextPage
+	}
+
+	if total == 0 {
+		return &PolicyError{Policy: "completed-check", Message: fmt.Sprintf("PR #%d head commit %.7s has no check runs yet", pr.GetNumber(), sha)}
+	}
+	return &PolicyError{Policy: "completed-check", Message: fmt.Sprintf("PR #%d has %d check run(s), none completed yet", pr.GetNumber(), total)}
+}
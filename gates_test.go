@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestArchivedRepositoryGate(t *testing.T) {
+	tests := []struct {
+		name          string
+		gh            *fakeGitHub
+		wantArchived  bool
+		wantRepoFetch bool
+	}{
+		{name: "active, from the PR", gh: &fakeGitHub{}},
+		{name: "archived, from the PR", gh: &fakeGitHub{archived: true}, wantArchived: true},
+		{name: "active, from the repository", gh: &fakeGitHub{omitArchived: true}, wantRepoFetch: true},
+		{name: "archived, from the repository", gh: &fakeGitHub{archived: true, omitArchived: true}, wantArchived: true, wantRepoFetch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{}
+			gc := newTestGitHubClient(t, config, tt.gh.ServeHTTP)
+
+			err := gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy())
+			var policyErr *PolicyError
+			if tt.wantArchived != (errors.As(err, &policyErr) && policyErr.Policy == "archived") {
+				t.Errorf("ValidatePRReference = %v, want archived %v", err, tt.wantArchived)
+			}
+			if !tt.wantArchived && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+			if got := tt.gh.requested("GET /repos/o/r"); got != tt.wantRepoFetch {
+				t.Errorf("fetched the repository = %v, want %v", got, tt.wantRepoFetch)
+			}
+		})
+	}
+}
+
+func TestArchivedRepositoryIsCached(t *testing.T) {
+	gh := &fakeGitHub{omitArchived: true}
+	config := &Configuration{}
+	gc := newTestGitHubClient(t, config, gh.ServeHTTP)
+
+	for i := 0; i < 3; i++ {
+		if err := gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy()); err != nil {
+			t.Fatalf("ValidatePRReference: %v", err)
+		}
+	}
+	fetches := 0
+	for _, request := range gh.requests {
+		if request == "GET /repos/o/r" {
+			fetches++
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetched the repository %d times, want once", fetches)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{glob: "docs/**", path: "docs/guide/setup.md", want: true},
+		{glob: "docs/**", path: "src/main.go", want: false},
+		// **/ matches zero or more directories
+		{glob: "docs/**/*.md", path: "docs/README.md", want: true},
+		{glob: "docs/**/*.md", path: "docs/guide/setup.md", want: true},
+		{glob: "docs/**/*.md", path: "docs/guide/setup.txt", want: false},
+		{glob: "**/*.md", path: "README.md", want: true},
+		{glob: "**/*.md", path: "docs/README.md", want: true},
+		{glob: "*.md", path: "README.md", want: true},
+		{glob: "*.md", path: "docs/README.md", want: false},
+		{glob: "docs/?.md", path: "docs/a.md", want: true},
+		{glob: "docs/?.md", path: "docs/ab.md", want: false},
+		{glob: "docs/?.md", path: "docs//.md", want: false},
+		// Multi-byte characters are matched whole, not byte by byte
+		{glob: "docs/?.md", path: "docs/é.md", want: true},
+		{glob: "dokumente/übersicht/*.md", path: "dokumente/übersicht/a.md", want: true},
+		{glob: "docs/*.md", path: "docs/日本語.md", want: true},
+		{glob: "a.b", path: "axb", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			pattern, err := compileGlob(tt.glob)
+			if err != nil {
+				t.Fatalf("compileGlob(%q): %v", tt.glob, err)
+			}
+			if got := pattern.MatchString(tt.path); got != tt.want {
+				t.Errorf("%q matches %q = %v, want %v (regex %s)", tt.glob, tt.path, got, tt.want, pattern)
+			}
+		})
+	}
+}
+
+// review is a review of the PR by login in state, as GitHub lists it
+func review(login, state string) map[string]interface{} {
+	return map[string]interface{}{"state": state, "user": map[string]string{"login": login}}
+}
+
+// validatePR validates o/r#1 served by gh with config's global policy
+func validatePR(t *testing.T, config *Configuration, gh *fakeGitHub) error {
+	t.Helper()
+	gc := newTestGitHubClient(t, config, gh.ServeHTTP)
+	return gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy())
+}
+
+// failedPolicy returns the policy an error blames, empty when it isn't a policy error
+func failedPolicy(err error) string {
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Policy
+	}
+	return ""
+}
+
+func TestMinExistingApprovals(t *testing.T) {
+	tests := []struct {
+		name    string
+		reviews []interface{}
+		wantErr bool
+	}{
+		{name: "enough approvals", reviews: []interface{}{review("alice", "APPROVED"), review("bob", "APPROVED")}},
+		{name: "too few approvals", reviews: []interface{}{review("alice", "APPROVED")}, wantErr: true},
+		{name: "the bot's approval doesn't count", reviews: []interface{}{review("alice", "APPROVED"), review(fakeBotLogin, "APPROVED")}, wantErr: true},
+		{name: "dismissed approval", reviews: []interface{}{review("alice", "APPROVED"), review("bob", "APPROVED"), review("bob", "DISMISSED")}, wantErr: true},
+		{name: "comment keeps an approval", reviews: []interface{}{review("alice", "APPROVED"), review("bob", "APPROVED"), review("bob", "COMMENTED")}},
+		{name: "the same reviewer twice", reviews: []interface{}{review("alice", "APPROVED"), review("Alice", "APPROVED")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1/reviews": tt.reviews}}
+			err := validatePR(t, &Configuration{MinExistingApprovals: 2}, gh)
+			if tt.wantErr != (failedPolicy(err) == "min-approvals") || (!tt.wantErr && err != nil) {
+				t.Errorf("ValidatePRReference = %v, want a min-approvals error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMinExistingApprovalsLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/pulls/1/reviews": http.StatusNotFound}}
+	err := validatePR(t, &Configuration{MinExistingApprovals: 1}, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want the review lookup error", err)
+	}
+}
+
+func TestRespectRequestedChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		reviews []interface{}
+		wantErr bool
+	}{
+		{name: "no reviews"},
+		{name: "changes requested", reviews: []interface{}{review("alice", "APPROVED"), review("bob", "CHANGES_REQUESTED")}, wantErr: true},
+		{name: "changes requested, then approved", reviews: []interface{}{review("bob", "CHANGES_REQUESTED"), review("bob", "APPROVED")}},
+		{name: "changes requested, then dismissed", reviews: []interface{}{review("bob", "CHANGES_REQUESTED"), review("bob", "DISMISSED")}},
+		{name: "a comment doesn't lift requested changes", reviews: []interface{}{review("bob", "CHANGES_REQUESTED"), review("bob", "COMMENTED")}, wantErr: true},
+		{name: "the bot's requested changes", reviews: []interface{}{review(fakeBotLogin, "CHANGES_REQUESTED")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1/reviews": tt.reviews}}
+			err := validatePR(t, &Configuration{RespectRequestedChanges: true}, gh)
+			if tt.wantErr != (failedPolicy(err) == "requested-changes") || (!tt.wantErr && err != nil) {
+				t.Errorf("ValidatePRReference = %v, want a requested-changes error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestedChangesAreDenied(t *testing.T) {
+	gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1/reviews": []interface{}{review("bob", "CHANGES_REQUESTED")}}}
+	sc, reactions := newTestSlackClient(t, &Configuration{RespectRequestedChanges: true}, gh)
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 {
+		t.Errorf("submitted %v with changes requested", reviews)
+	}
+	if !reactions.has("no_entry") {
+		t.Errorf("reactions %v, want the denied reaction", reactions.added)
+	}
+}
+
+func TestRequestedChangesLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /user": http.StatusUnauthorized}}
+	err := validatePR(t, &Configuration{RespectRequestedChanges: true}, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want the bot login error", err)
+	}
+}
+
+func TestUpToDateGate(t *testing.T) {
+	tests := []struct {
+		name           string
+		mergeableState string
+		behindBy       int
+		wantBehind     bool
+		wantCompare    bool
+	}{
+		{name: "clean", mergeableState: "clean"},
+		{name: "behind", mergeableState: "behind", wantBehind: true},
+		{name: "unknown state, up to date", behindBy: 0, wantCompare: true},
+		{name: "unknown state, behind", behindBy: 2, wantBehind: true, wantCompare: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{
+				mergeableState: tt.mergeableState,
+				routes: map[string]interface{}{
+					"GET /repos/o/r/compare/main...abc123": map[string]int{"behind_by": tt.behindBy},
+				},
+			}
+			config := &Configuration{RequireUpToDate: true, MergeableStateTimeout: time.Millisecond}
+
+			err := validatePR(t, config, gh)
+			if got := failedPolicy(err) == "up-to-date"; got != tt.wantBehind {
+				t.Errorf("ValidatePRReference = %v, want behind %v", err, tt.wantBehind)
+			}
+			if !tt.wantBehind && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+			if got := gh.requested("GET /repos/o/r/compare/main...abc123"); got != tt.wantCompare {
+				t.Errorf("compared with the base branch = %v, want %v", got, tt.wantCompare)
+			}
+		})
+	}
+}
+
+func TestUpToDateCompareFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/compare/main...abc123": http.StatusNotFound}}
+	config := &Configuration{RequireUpToDate: true, MergeableStateTimeout: time.Millisecond}
+
+	err := validatePR(t, config, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want a comparison error", err)
+	}
+}
+
+func TestVerifiedCommitsGate(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification interface{}
+		wantErr      bool
+	}{
+		{name: "verified", verification: map[string]interface{}{"verified": true, "reason": "valid"}},
+		{name: "unsigned", verification: map[string]interface{}{"verified": false, "reason": "unsigned"}, wantErr: true},
+		{name: "no verification information", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit := map[string]interface{}{}
+			if tt.verification != nil {
+				commit["verification"] = tt.verification
+			}
+			gh := &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/commits/abc123": map[string]interface{}{"sha": "abc123", "commit": commit},
+			}}
+
+			err := validatePR(t, &Configuration{RequireVerifiedCommits: true}, gh)
+			if got := failedPolicy(err) == "verified-commits"; got != tt.wantErr {
+				t.Errorf("ValidatePRReference = %v, want unverified %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+		})
+	}
+}
+
+func TestVerifiedCommitsLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/commits/abc123": http.StatusNotFound}}
+
+	err := validatePR(t, &Configuration{RequireVerifiedCommits: true}, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want a lookup error", err)
+	}
+}
+
+func TestAnyCompletedCheckGate(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  bool
+	}{
+		{name: "one completed", statuses: []string{"in_progress", "completed"}},
+		{name: "completed with a failure still counts", statuses: []string{"completed"}},
+		{name: "all in progress", statuses: []string{"queued", "in_progress"}, wantErr: true},
+		{name: "no check runs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := make([]map[string]interface{}, 0, len(tt.statuses))
+			for i, status := range tt.statuses {
+				run := map[string]interface{}{"id": i + 1, "status": status}
+				if status == "completed" {
+					run["conclusion"] = "failure"
+				}
+				runs = append(runs, run)
+			}
+			gh := &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/commits/abc123/check-runs": map[string]interface{}{"total_count": len(runs), "check_runs": runs},
+			}}
+
+			err := validatePR(t, &Configuration{RequireAnyCompletedCheck: true}, gh)
+			if got := failedPolicy(err) == "completed-check"; got != tt.wantErr {
+				t.Errorf("ValidatePRReference = %v, want no completed check %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+		})
+	}
+}
+
+func TestAnyCompletedCheckLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/commits/abc123/check-runs": http.StatusForbidden}}
+
+	err := validatePR(t, &Configuration{RequireAnyCompletedCheck: true}, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want a lookup error", err)
+	}
+}
@@ -2,8 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/gofri/go-github-ratelimit/v2/github_ratelimit"
@@ -15,6 +21,26 @@ import (
 type GitHubClient struct {
 	client *github.Client
 	config *Configuration
+	
+	checkboxPattern *regexp.Regexp
+	commentTemplate *template.Template
+	safePaths       []*regexp.Regexp
+	
+	// login of the authenticated user, resolved on first use
+	loginMu sync.Mutex
+	login   string
+	
+	// teams tracks whether reviews can count for ReviewTeam
+	teams teamReviewer
+	
+	// repos caches repository metadata
+	repos repoCache
+	
+	// degraded holds approvals during sustained GitHub 5xx errors, nil when disabled
+	degraded *degradedMode
+	
+	// policyClient calls the external policy endpoint, nil when PolicyURL is unset
+	policyClient *http.Client
 }
 
 // ApprovalRequest represents a request to approve a GitHub pull request
@@ -27,6 +53,12 @@ type ApprovalRequest struct {
 	SourceUser    string
 	SourceMessage *SlackMessage
 	Timestamp     time.Time
+	Policy        Policy
+	// RequestedEvent is the review event the message asked for explicitly, overriding every default
+	RequestedEvent string
+	
+	// batch groups the PRs approved from one message for a composite reaction
+	batch *approvalBatch
 }
 
 // ApprovalResult represents the result of a GitHub PR approval operation
@@ -37,27 +69,127 @@ type ApprovalResult struct {
 	Error          string
 	ProcessedAt    time.Time
 	RetryAttempts  int
+	// AlreadyApproved is set when GitHub reported an existing review instead of creating a new one
+	AlreadyApproved bool
+	// DryRun is set when the PR passed validation but no review was submitted
+	DryRun bool
+	// Event is the review event submitted, e.g. COMMENT when a repository or pending
+	// team reviews turned the approval into a comment
+	Event string
+	// Fallback is the AppApprovalFallback that recorded the approval GitHub refused,
+	// empty for an approving review
+	Fallback string
 }
 
-// NewGitHubClient creates a new GitHub client with rate limiting
-func NewGitHubClient(config *Configuration) (*GitHubClient, error) {
+// approved reports whether the PR got an approving review. A successful COMMENT or
+// REQUEST_CHANGES review, or an approval fallback, is not an approval, and gets none
+// of its follow-ups.
+func (result *ApprovalResult) approved() bool {
+	return result.Success && result.Event == "APPROVE" && result.Fallback == ""
+}
+
+// NewGitHubClient creates a new GitHub client with rate limiting. An *http.Client
+// stored in ctx under oauth2.HTTPClient is used as the base transport, e.g. in tests.
+func NewGitHubClient(ctx context.Context, config *Configuration) (*GitHubClient, error) {
+	// Route requests through the configured proxies unless the caller supplied a client
+	if _, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); !ok && config.proxyConfigured() {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, newHTTPClient(config))
+	}
+	
 	// Create OAuth2 token source
-	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.GitHubToken})
 	
 	// Create OAuth2 HTTP client
 	oauthClient := oauth2.NewClient(ctx, ts)
 	
+	gc := &GitHubClient{config: config}
+	
+	// Tag requests with their Slack source and record rate limits from every response
+	var transport http.RoundTripper = &rateLimitTransport{base: &sourceHeaderTransport{base: oauthClient.Transport}}
+	
+	// Hold approvals while GitHub keeps failing, probing /rate_limit until it recovers
+	gc.degraded = newDegradedMode(ctx, config.DegradedThreshold, config.DegradedProbeInterval, func(ctx context.Context) error {
+		_, err := gc.RateLimits(ctx)
+		return err
+	})
+	if gc.degraded != nil {
+		transport = &degradedTransport{base: transport, mode: gc.degraded}
+	}
+	
 	// Create rate-limited HTTP client
-	rateLimitedClient := github_ratelimit.NewClient(oauthClient.Transport)
+	rateLimitedClient := github_ratelimit.NewClient(transport)
 	
 	// Create GitHub client
-	client := github.NewClient(rateLimitedClient)
+	gc.client = github.NewClient(rateLimitedClient)
+	gc.client.UserAgent = githubUserAgent(config)
+	
+	if config.RequireApprovalCheckbox {
+		pattern, err := regexp.Compile(config.ApprovalCheckboxPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approval checkbox pattern: %v", err)
+		}
+		gc.checkboxPattern = pattern
+	}
+	
+	if config.CommentOnApprove {
+		commentTemplate, err := parseApprovalCommentTemplate(config.ApprovalCommentTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approval comment template: %v", err)
+		}
+		gc.commentTemplate = commentTemplate
+	}
+	
+	safePaths, err := compileGlobs(config.SafePathPatterns)
+	if err != nil {
+		return nil, err
+	}
+	gc.safePaths = safePaths
 	
-	return &GitHubClient{
-		client: client,
-		config: config,
-	}, nil
+	if config.PolicyURL != "" {
+		gc.policyClient = newHTTPClient(config)
+	}
+	
+	return gc, nil
+}
+
+// githubUserAgent builds the User-Agent sent to GitHub: lgtm/<version> by default, plus the deployment name
+func githubUserAgent(config *Configuration) string {
+	userAgent := config.GitHubUserAgent
+	if userAgent == "" {
+		userAgent = "lgtm/" + version
+	}
+	if config.DeploymentName != "" {
+		userAgent += " (" + config.DeploymentName + ")"
+	}
+	return userAgent
+}
+
+// sourceContextKey carries the Slack channel that triggered a GitHub request
+type sourceContextKey struct{}
+
+// withRequestSource tags GitHub requests made with ctx with the originating Slack channel
+func withRequestSource(ctx context.Context, channel string) context.Context {
+	if channel == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sourceContextKey{}, channel)
+}
+
+// sourceHeaderTransport adds the X-Lgtm-Source header for traceability in GitHub audit logs
+type sourceHeaderTransport struct {
+	base http.RoundTripper
+}
+
+func (t *sourceHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	channel, ok := req.Context().Value(sourceContextKey{}).(string)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Lgtm-Source", "slack:"+channel)
+	return t.base.RoundTrip(req)
 }
 
 // ValidatePermissions checks if the GitHub token has required permissions
@@ -72,7 +204,7 @@ func (gc *GitHubClient) ValidatePermissions(ctx context.Context) error {
 	
 	// Test if we can access the repository (if default repo is configured)
 	if gc.config.DefaultOwner != "" && gc.config.DefaultRepo != "" {
-		_, _, err := gc.client.Repositories.Get(ctx, gc.config.DefaultOwner, gc.config.DefaultRepo)
+		_, err := gc.repository(ctx, gc.config.DefaultOwner, gc.config.DefaultRepo)
 		if err != nil {
 			return &AuthenticationError{
 				Service: "GitHub", 
@@ -96,36 +228,193 @@ func (gc *GitHubClient) GetAuthenticatedUser(ctx context.Context) (*github.User,
 	return user, nil
 }
 
-// ValidatePRReference checks if a PR exists and is in a valid state for approval
-func (gc *GitHubClient) ValidatePRReference(ctx context.Context, owner, repo string, prNumber int) error {
+// botLogin returns the authenticated user's login, caching it after the first lookup
+func (gc *GitHubClient) botLogin(ctx context.Context) (string, error) {
+	gc.loginMu.Lock()
+	defer gc.loginMu.Unlock()
+	
+	if gc.login != "" {
+		return gc.login, nil
+	}
+	
+	user, err := gc.GetAuthenticatedUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	gc.login = user.GetLogin()
+	return gc.login, nil
+}
+
+// ValidatePRReference checks if a PR exists, is in a valid state for approval and satisfies the policy
+func (gc *GitHubClient) ValidatePRReference(ctx context.Context, owner, repo string, prNumber int, policy Policy) error {
 	logDebug("Validating PR: %s/%s#%d", owner, repo, prNumber)
 	
-	// Get the pull request
-	pr, response, err := gc.client.PullRequests.Get(ctx, owner, repo, prNumber)
+	pr, err := gc.getPR(ctx, owner, repo, prNumber)
 	if err != nil {
+		return err
+	}
+	
+	// Gates reading mergeability need GitHub to have computed it first
+	gates := gc.prGates(policy)
+	if needsMergeableState(gates) {
+		if pr, err = gc.awaitMergeableState(ctx, owner, repo, pr); err != nil {
+			return err
+		}
+	}
+	
+	// Run every enabled gate; the first failure blocks approval
+	for _, gate := range gates {
+		if !gate.Enabled {
+			continue
+		}
+		if err := gate.Check(ctx, pr); err != nil {
+			return err
+		}
+	}
+	
+	logDebug("PR validation successful: %s/%s#%d state=%s mergeable=%v", owner, repo, prNumber, pr.GetState(), pr.GetMergeable())
+	
+	return nil
+}
+
+// CheckPR evaluates every validation gate for a PR without approving it, reporting each outcome.
+// It uses the same gates as ValidatePRReference but doesn't stop at the first failure.
+func (gc *GitHubClient) CheckPR(ctx context.Context, owner, repo string, prNumber int, policy Policy) ([]GateResult, error) {
+	pr, err := gc.getPR(ctx, owner, repo, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	
+	gates := gc.prGates(policy)
+	if needsMergeableState(gates) {
+		if pr, err = gc.awaitMergeableState(ctx, owner, repo, pr); err != nil {
+			return nil, err
+		}
+	}
+	
+	var results []GateResult
+	for _, gate := range gates {
+		result := GateResult{Name: gate.Name, Enabled: gate.Enabled}
+		if gate.Enabled {
+			result.Err = gate.Check(ctx, pr)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// PRAuthor returns the GitHub login of a PR's author
+func (gc *GitHubClient) PRAuthor(ctx context.Context, owner, repo string, prNumber int) (string, error) {
+	pr, err := gc.getPR(ctx, owner, repo, prNumber)
+	if err != nil {
+		return "", err
+	}
+	return pr.GetUser().GetLogin(), nil
+}
+
+// getPR fetches a pull request, translating common API failures into readable errors.
+// Transient failures (network errors, 5xx) are retried with the same backoff as approvals.
+func (gc *GitHubClient) getPR(ctx context.Context, owner, repo string, prNumber int) (*github.PullRequest, error) {
+	maxRetries, baseDelay := gc.retrySettings()
+	
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := time.Duration(1<<uint(attempt)) * baseDelay
+			logDebug("Retrying PR validation: attempt=%d/%d delay=%v pr_number=%d", attempt+1, maxRetries, delay, prNumber)
+			
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		
+		pr, response, err := gc.client.PullRequests.Get(ctx, owner, repo, prNumber)
+		if err == nil {
+			return pr, nil
+		}
+		
 		if response != nil {
 			switch response.StatusCode {
 			case 404:
-				return fmt.Errorf("PR #%d not found in %s/%s", prNumber, owner, repo)
+				return nil, fmt.Errorf("PR #%d not found in %s/%s", prNumber, owner, repo)
 			case 403:
-				return fmt.Errorf("insufficient permissions to access PR #%d in %s/%s", prNumber, owner, repo)
+				if message, ok := ssoRequiredMessage(response, owner); ok {
+					return nil, &AuthenticationError{Service: "GitHub", Message: message}
+				}
+				return nil, fmt.Errorf("insufficient permissions to access PR #%d in %s/%s", prNumber, owner, repo)
 			}
 		}
-		return fmt.Errorf("failed to get PR #%d: %v", prNumber, err)
+		
+		lastErr = fmt.Errorf("failed to get PR #%d: %v", prNumber, err)
+		if !isTransientResponse(ctx, response) {
+			return nil, lastErr
+		}
+		logDebug("PR validation attempt failed: attempt=%d error=%v", attempt+1, err)
 	}
 	
-	// Check if PR is in a valid state for approval
-	if pr.GetState() != "open" {
-		return fmt.Errorf("PR #%d is %s and cannot be approved", prNumber, pr.GetState())
+	return nil, lastErr
+}
+
+// retrySettings returns the attempt count and base backoff delay shared by validation and approval
+func (gc *GitHubClient) retrySettings() (int, time.Duration) {
+	maxRetries := gc.config.GitHubMaxRetries
+	if maxRetries < 1 {
+		maxRetries = 3
 	}
-	
-	if pr.GetMerged() {
-		return fmt.Errorf("PR #%d is already merged", prNumber)
+	baseDelay := gc.config.GitHubRetryDelay
+	if baseDelay <= 0 {
+		baseDelay = time.Second
+	}
+	return maxRetries, baseDelay
+}
+
+// isTransientResponse reports whether a failed request is worth retrying:
+// network errors without a response, and server-side 5xx errors
+func isTransientResponse(ctx context.Context, response *github.Response) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if response == nil {
+		return true
+	}
+	return response.StatusCode >= 500
+}
+
+// ssoRequiredMessage explains a 403 caused by a token that hasn't been authorized for
+// the organization's SAML SSO. GitHub flags these with an X-GitHub-SSO header naming
+// the URL where the token can be authorized.
+func ssoRequiredMessage(response *github.Response, org string) (string, bool) {
+	if response == nil {
+		return "", false
 	}
 	
-	logDebug("PR validation successful: %s/%s#%d state=%s mergeable=%v", owner, repo, prNumber, pr.GetState(), pr.GetMergeable())
+	header := response.Header.Get("X-GitHub-SSO")
+	if !strings.HasPrefix(header, "required") {
+		return "", false
+	}
 	
-	return nil
+	message := fmt.Sprintf("token is not SSO-authorized for organization %s", org)
+	for _, part := range strings.Split(header, ";") {
+		if authorizeURL, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+			return message + "; authorize it at " + authorizeURL, true
+		}
+	}
+	return message + "; authorize it under your token's Configure SSO settings", true
+}
+
+// reviewBody joins a review message and the configured footer, separated by a blank line
+func reviewBody(message, footer string) string {
+	message, footer = strings.TrimSpace(message), strings.TrimSpace(footer)
+	switch {
+	case footer == "":
+		return message
+	case message == "":
+		return footer
+	default:
+		return message + "\n\n" + footer
+	}
 }
 
 // ApprovePR approves a GitHub pull request
@@ -138,8 +427,27 @@ func (gc *GitHubClient) ApprovePR(ctx context.Context, req *ApprovalRequest) (*A
 	logDebug("Approving PR: %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
 	
 	// Create review request with approval
+	event := gc.config.reviewEventFor(req)
+	message := req.Message
+	
+	// Teams still asked to review get to, so the bot only comments until they have
+	if event == "APPROVE" {
+		if note := gc.pendingTeamsNote(ctx, req); note != "" {
+			logInfo("PR %s/%s#%d has pending team reviews, commenting instead of approving", req.Owner, req.Repository, req.PRNumber)
+			event = "COMMENT"
+			message = reviewBody(note, message)
+		}
+	}
+	
+	result.Event = event
 	reviewRequest := &github.PullRequestReviewRequest{
-		Event: github.String("APPROVE"),
+		Event: github.String(event),
+	}
+	message = reviewBody(message, gc.reviewTeamNote(ctx))
+	message = reviewBody(message, renderChecklist(gc.config.ReviewChecklist))
+	message = reviewBody(message, gc.config.ReviewFooter)
+	if body := reviewBody(message, instanceMarker(gc.config.InstanceName)); body != "" {
+		reviewRequest.Body = github.String(body)
 	}
 	
 	// Submit the review
@@ -162,8 +470,26 @@ func (gc *GitHubClient) ApprovePR(ctx context.Context, req *ApprovalRequest) (*A
 				result.Error = fmt.Sprintf("PR #%d not found in %s/%s", req.PRNumber, req.Owner, req.Repository)
 			case 403:
 				result.Error = fmt.Sprintf("insufficient permissions to approve PR #%d", req.PRNumber)
+				if message, ok := ssoRequiredMessage(response, req.Owner); ok {
+					result.Error = message
+				}
 			case 422:
-				result.Error = fmt.Sprintf("PR #%d cannot be approved (already merged or closed)", req.PRNumber)
+				details := strings.ToLower(errorResponseText(err))
+				switch {
+				case strings.Contains(details, "already") && (strings.Contains(details, "review") || strings.Contains(details, "approved")):
+					// Re-triggered message: the review is already there, so this is a skip rather than a failure
+					logInfo("PR %s/%s#%d already has a review from this user, skipping", req.Owner, req.Repository, req.PRNumber)
+					result.Success = true
+					result.AlreadyApproved = true
+					result.Error = ""
+				case strings.Contains(details, "closed") || strings.Contains(details, "merged"):
+					result.Error = fmt.Sprintf("PR #%d cannot be approved (already merged or closed)", req.PRNumber)
+				case event == "APPROVE" && isAppApprovalRestricted(details):
+					result.Error = fmt.Sprintf("PR #%d review rejected by GitHub: %s", req.PRNumber, errorResponseText(err))
+					gc.approvalFallback(ctx, req, reviewRequest.GetBody(), message, result)
+				default:
+					result.Error = fmt.Sprintf("PR #%d review rejected by GitHub: %s", req.PRNumber, errorResponseText(err))
+				}
 			}
 		}
 		
@@ -181,8 +507,7 @@ func (gc *GitHubClient) ApprovePR(ctx context.Context, req *ApprovalRequest) (*A
 
 // ApprovePRWithRetry approves a GitHub PR with retry logic
 func (gc *GitHubClient) ApprovePRWithRetry(ctx context.Context, req *ApprovalRequest) (*ApprovalResult, error) {
-	const maxRetries = 3
-	const baseDelay = time.Second
+	maxRetries, baseDelay := gc.retrySettings()
 	
 	var lastResult *ApprovalResult
 	var lastErr error
@@ -231,6 +556,255 @@ func (gc *GitHubClient) ApprovePRWithRetry(ctx context.Context, req *ApprovalReq
 	return nil, fmt.Errorf("approval failed after %d attempts: %v", maxRetries, lastErr)
 }
 
+// BotApproval is an approving review submitted by the authenticated user
+type BotApproval struct {
+	PR         *github.PullRequest
+	ReviewID   int64
+	ApprovedAt time.Time
+}
+
+// ListBotApprovals returns PRs in a repository approved by the authenticated user since the given time.
+// Pagination stops once PRs were last updated before the cutoff, since they can't carry newer reviews.
+func (gc *GitHubClient) ListBotApprovals(ctx context.Context, owner, repo string, since time.Time) ([]BotApproval, error) {
+	botLogin, err := gc.botLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	var approvals []BotApproval
+	listOptions := &github.PullRequestListOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: gc.perPage()},
+	}
+	
+	for {
+		prs, response, err := gc.client.PullRequests.List(ctx, owner, repo, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PRs in %s/%s: %v", owner, repo, err)
+		}
+		
+		reachedCutoff := false
+		for _, pr := range prs {
+			if pr.GetUpdatedAt().Before(since) {
+				reachedCutoff = true
+				break
+			}
+			
+			reviews, err := gc.listReviews(ctx, owner, repo, pr.GetNumber())
+			if err != nil {
+				return nil, err
+			}
+			
+			for _, review := range reviews {
+				if review.GetState() != "APPROVED" || !strings.EqualFold(review.GetUser().GetLogin(), botLogin) {
+					continue
+				}
+				if !ownedByInstance(review.GetBody(), gc.config.InstanceName) {
+					continue
+				}
+				if review.GetSubmittedAt().Before(since) {
+					continue
+				}
+				approvals = append(approvals, BotApproval{
+					PR:         pr,
+					ReviewID:   review.GetID(),
+					ApprovedAt: review.GetSubmittedAt().Time,
+				})
+			}
+		}
+		
+		if reachedCutoff || response.NextPage == 0 {
+			break
+		}
+		listOptions.Page = response.NextPage
+	}
+	
+	return approvals, nil
+}
+
+// DismissBotApproval dismisses the bot's standing approval of a PR.
+// It reports false without error when the bot has no approval to dismiss.
+func (gc *GitHubClient) DismissBotApproval(ctx context.Context, owner, repo string, prNumber int, message string) (bool, error) {
+	botLogin, err := gc.botLogin(ctx)
+	if err != nil {
+		return false, err
+	}
+	
+	reviews, err := gc.listReviews(ctx, owner, repo, prNumber)
+	if err != nil {
+		return false, err
+	}
+	
+	// Only this instance's latest approving or dismissed review decides whether an approval stands
+	var approval *github.PullRequestReview
+	for _, review := range reviews {
+		if !strings.EqualFold(review.GetUser().GetLogin(), botLogin) || !ownedByInstance(review.GetBody(), gc.config.InstanceName) {
+			continue
+		}
+		switch review.GetState() {
+		case "APPROVED":
+			approval = review
+		case "DISMISSED", "CHANGES_REQUESTED":
+			approval = nil
+		}
+	}
+	
+	if approval == nil {
+		return false, nil
+	}
+	
+	_, _, err = gc.client.PullRequests.DismissReview(ctx, owner, repo, prNumber, approval.GetID(), &github.PullRequestReviewDismissalRequest{
+		Message: github.Ptr(message),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to dismiss review %d on PR #%d: %v", approval.GetID(), prNumber, err)
+	}
+	
+	return true, nil
+}
+
+// errCommitHasNoPR reports a commit that isn't part of any open pull request
+var errCommitHasNoPR = errors.New("no open pull request contains this commit")
+
+// ResolveCommitPR finds the open pull request containing a commit.
+// A commit in several open PRs is ambiguous and returns an error naming them.
+func (gc *GitHubClient) ResolveCommitPR(ctx context.Context, owner, repo, sha string) (int, error) {
+	prs, _, err := gc.client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, &github.ListOptions{PerPage: maxGitHubPerPage})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PRs for commit %s: %v", sha, err)
+	}
+	
+	var open []string
+	number := 0
+	for _, pr := range prs {
+		if pr.GetState() != "open" {
+			continue
+		}
+		number = pr.GetNumber()
+		open = append(open, fmt.Sprintf("#%d", number))
+	}
+	
+	switch len(open) {
+	case 0:
+		return 0, errCommitHasNoPR
+	case 1:
+		return number, nil
+	default:
+		return 0, fmt.Errorf("commit %s is in %d open pull requests (%s)", sha, len(open), strings.Join(open, ", "))
+	}
+}
+
+// errBranchHasNoPR reports a file link whose branch isn't the head of any open pull request
+var errBranchHasNoPR = errors.New("no open pull request has this branch as its head")
+
+// maxBranchLinkSegments bounds how many leading path segments of a file link are tried as the branch
+const maxBranchLinkSegments = 4
+
+// permalinkSHAPattern matches the full commit SHA of a file permalink
+var permalinkSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// ResolveBranchPR finds the open PR whose head is the branch of a blob or tree link, given the
+// link's ref and path. Branch names may contain slashes, so the shortest leading segments that
+// name a PR's head win. A permalink to a commit resolves like a commit link. Only branches in
+// the repository itself are found, not branches of forks.
+func (gc *GitHubClient) ResolveBranchPR(ctx context.Context, owner, repo, refPath string) (int, error) {
+	segments := strings.Split(refPath, "/")
+	if permalinkSHAPattern.MatchString(segments[0]) {
+		return gc.ResolveCommitPR(ctx, owner, repo, strings.ToLower(segments[0]))
+	}
+	
+	for i := 1; i <= len(segments) && i <= maxBranchLinkSegments; i++ {
+		branch := strings.Join(segments[:i], "/")
+		prs, _, err := gc.client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+			State:       "open",
+			Head:        owner + ":" + branch,
+			ListOptions: github.ListOptions{PerPage: maxGitHubPerPage},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list PRs for branch %s: %v", branch, err)
+		}
+		
+		switch len(prs) {
+		case 0:
+			continue
+		case 1:
+			return prs[0].GetNumber(), nil
+		default:
+			open := make([]string, len(prs))
+			for j, pr := range prs {
+				open[j] = fmt.Sprintf("#%d", pr.GetNumber())
+			}
+			return 0, fmt.Errorf("branch %s is the head of %d open pull requests (%s)", branch, len(prs), strings.Join(open, ", "))
+		}
+	}
+	return 0, errBranchHasNoPR
+}
+
+// listReviews returns all reviews on a PR, following pagination
+func (gc *GitHubClient) listReviews(ctx context.Context, owner, repo string, prNumber int) ([]*github.PullRequestReview, error) {
+	var reviews []*github.PullRequestReview
+	listOptions := &github.ListOptions{PerPage: gc.perPage()}
+	
+	for {
+		page, response, err := gc.client.PullRequests.ListReviews(ctx, owner, repo, prNumber, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list reviews for PR #%d: %v", prNumber, err)
+		}
+		reviews = append(reviews, page...)
+		
+		if response.NextPage == 0 {
+			return reviews, nil
+		}
+		listOptions.Page = response.NextPage
+	}
+}
+
+// DispatchApprovalEvent fires a repository_dispatch event on the approved PR's repository
+// so downstream workflows can react to the approval
+func (gc *GitHubClient) DispatchApprovalEvent(ctx context.Context, req *ApprovalRequest, result *ApprovalResult) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"owner":      req.Owner,
+		"repository": req.Repository,
+		"pr_number":  req.PRNumber,
+		"review_id":  result.ReviewID,
+		"slack_user": req.SourceUser,
+		"channel":    req.SourceChannel,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch payload: %v", err)
+	}
+	
+	rawPayload := json.RawMessage(payload)
+	_, _, err = gc.client.Repositories.Dispatch(ctx, req.Owner, req.Repository, github.DispatchRequestOptions{
+		EventType:     gc.config.DispatchEventType,
+		ClientPayload: &rawPayload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dispatch %s event to %s/%s: %v", gc.config.DispatchEventType, req.Owner, req.Repository, err)
+	}
+	
+	logDebug("Dispatched %s event: %s/%s#%d", gc.config.DispatchEventType, req.Owner, req.Repository, req.PRNumber)
+	return nil
+}
+
+// errorResponseText returns the message and validation errors from a GitHub API error body
+func errorResponseText(err error) string {
+	var errorResponse *github.ErrorResponse
+	if !errors.As(err, &errorResponse) {
+		return err.Error()
+	}
+	
+	parts := []string{errorResponse.Message}
+	for _, e := range errorResponse.Errors {
+		if e.Message != "" {
+			parts = append(parts, e.Message)
+		}
+	}
+	return strings.Join(parts, ": ")
+}
+
 // isPermanentError determines if an error should not be retried
 func isPermanentError(errorMsg string) bool {
 	permanentErrors := []string{
@@ -240,6 +814,8 @@ func isPermanentError(errorMsg string) bool {
 		"already closed",
 		"invalid_auth",
 		"Bad credentials",
+		"review rejected",
+		"not SSO-authorized",
 	}
 	
 	for _, permanent := range permanentErrors {
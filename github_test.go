@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+	"golang.org/x/oauth2"
+)
+
+// handlerTransport answers GitHub API requests with an http.Handler instead of the network
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// RoundTrip serves the request with the handler
+func (t *handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, r)
+	response := recorder.Result()
+	response.Request = r
+	return response, nil
+}
+
+// newTestGitHubClient creates a client whose requests are served by handler
+func newTestGitHubClient(t *testing.T, config *Configuration, handler http.HandlerFunc) *GitHubClient {
+	t.Helper()
+	if config.GitHubRetryDelay == 0 {
+		config.GitHubRetryDelay = time.Millisecond
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: &handlerTransport{handler: handler}})
+	gc, err := NewGitHubClient(ctx, config)
+	if err != nil {
+		t.Fatalf("NewGitHubClient: %v", err)
+	}
+	return gc
+}
+
+// fakeGitHub serves the GitHub API for one open PR per path, recording the review
+// events submitted
+type fakeGitHub struct {
+	mu sync.Mutex
+	// draft marks every PR as a draft
+	draft bool
+	// body is the description of every PR
+	body string
+	// author is the login of every PR's author
+	author string
+	// archived marks every repository as archived
+	archived bool
+	// omitArchived leaves archived out of the PR's base repository, so only the
+	// repository itself says whether it is archived
+	omitArchived bool
+	// mergeableState, when set, is every PR's computed mergeable state
+	mergeableState string
+	// reviewStatus, when set, is the status of every create review request
+	reviewStatus int
+	// reviewError is the message of a failed create review request
+	reviewError string
+	// restrictApprovals refuses approving reviews the way GitHub does for the Actions token
+	restrictApprovals bool
+	reviews           []string
+	// reviewBodies lists the body of every submitted review
+	reviewBodies []string
+	statuses     []string
+	// dismissed lists the IDs of dismissed reviews
+	dismissed []int
+	// requests lists every request as "METHOD /path"
+	requests []string
+	// reviewGate, when set, holds every create review request until it is closed
+	reviewGate chan struct{}
+	// routes answers requests, given as "METHOD /path", with the JSON of their value
+	// ahead of any other handling
+	routes map[string]interface{}
+	// failures answers requests, given as "METHOD /path", with their status
+	failures map[string]int
+}
+
+// ServeHTTP answers PR, repository and review requests
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.reviewGate != nil && r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/reviews") {
+		<-f.reviewGate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	request := r.Method + " " + r.URL.Path
+	f.requests = append(f.requests, request)
+	if status, ok := f.failures[request]; ok {
+		writeJSON(w, status, map[string]string{"message": http.StatusText(status)})
+		return
+	}
+	if value, ok := f.routes[request]; ok {
+		writeJSON(w, http.StatusOK, value)
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/user":
+		writeJSON(w, http.StatusOK, map[string]string{"login": fakeBotLogin})
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "repos":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": parts[2], "full_name": parts[1] + "/" + parts[2], "archived": f.archived})
+	case r.Method == http.MethodGet && len(parts) == 5 && parts[3] == "pulls":
+		base := map[string]interface{}{
+			"name":      parts[2],
+			"full_name": parts[1] + "/" + parts[2],
+			"owner":     map[string]string{"login": parts[1]},
+		}
+		if !f.omitArchived {
+			base["archived"] = f.archived
+		}
+		pr := map[string]interface{}{
+			"number": 1,
+			"state":  "open",
+			"draft":  f.draft,
+			"body":   f.body,
+			"user":   map[string]string{"login": f.author},
+			"head":   map[string]string{"sha": "abc123"},
+			"base":   map[string]interface{}{"ref": "main", "repo": base},
+		}
+		if f.mergeableState != "" {
+			pr["mergeable"] = true
+			pr["mergeable_state"] = f.mergeableState
+		}
+		writeJSON(w, http.StatusOK, pr)
+	case r.Method == http.MethodGet && len(parts) == 6 && parts[5] == "reviews":
+		reviews := make([]map[string]interface{}, 0, len(f.reviews))
+		for i, event := range f.reviews {
+			reviews = append(reviews, map[string]interface{}{
+				"id":    i + 1,
+				"state": f.reviewState(i+1, event),
+				"user":  map[string]string{"login": fakeBotLogin},
+			})
+		}
+		writeJSON(w, http.StatusOK, reviews)
+	case r.Method == http.MethodPut && len(parts) == 8 && parts[7] == "dismissals":
+		id, _ := strconv.Atoi(parts[6])
+		f.dismissed = append(f.dismissed, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "state": "DISMISSED"})
+	case r.Method == http.MethodPost && len(parts) == 6 && parts[5] == "reviews":
+		var body struct {
+			Event string `json:"event"`
+			Body  string `json:"body"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.reviews = append(f.reviews, body.Event)
+		f.reviewBodies = append(f.reviewBodies, body.Body)
+		if f.restrictApprovals && body.Event == "APPROVE" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "GitHub Actions is not permitted to approve pull requests."})
+			return
+		}
+		if f.reviewStatus != 0 {
+			writeJSON(w, f.reviewStatus, map[string]string{"message": f.reviewError})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"id": len(f.reviews)})
+	case r.Method == http.MethodPost && len(parts) == 5 && parts[3] == "statuses":
+		f.statuses = append(f.statuses, parts[4])
+		writeJSON(w, http.StatusCreated, map[string]string{"state": "success"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fakeBotLogin is the login of the user the fake GitHub authenticates
+const fakeBotLogin = "lgtm-bot"
+
+// reviewState is the state GitHub lists for the review with id submitted as event
+func (f *fakeGitHub) reviewState(id int, event string) string {
+	for _, dismissed := range f.dismissed {
+		if dismissed == id {
+			return "DISMISSED"
+		}
+	}
+	switch event {
+	case "APPROVE":
+		return "APPROVED"
+	case "REQUEST_CHANGES":
+		return "CHANGES_REQUESTED"
+	}
+	return "COMMENTED"
+}
+
+// dismissals returns the IDs of dismissed reviews
+func (f *fakeGitHub) dismissals() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.dismissed...)
+}
+
+// requested reports whether a request was made, given as "METHOD /path"
+func (f *fakeGitHub) requested(request string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, made := range f.requests {
+		if made == request {
+			return true
+		}
+	}
+	return false
+}
+
+// submitted returns the review events submitted so far
+func (f *fakeGitHub) submitted() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.reviews...)
+}
+
+// bodies returns the body of every submitted review
+func (f *fakeGitHub) bodies() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.reviewBodies...)
+}
+
+// writeJSON writes value as a JSON response with status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// reviewEvent returns the event of a create review request body
+func reviewEvent(t *testing.T, r *http.Request) string {
+	t.Helper()
+	var body struct {
+		Event string `json:"event"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding review request: %v", err)
+	}
+	return body.Event
+}
+
+func TestApprovePRReportsSubmittedEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Configuration
+		req          *ApprovalRequest
+		wantEvent    string
+		wantApproved bool
+	}{
+		{
+			name:         "approve",
+			config:       &Configuration{},
+			req:          &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1},
+			wantEvent:    "APPROVE",
+			wantApproved: true,
+		},
+		{
+			name:      "repository review event",
+			config:    &Configuration{RepoReviewEvents: map[string]string{"o/r": "COMMENT"}},
+			req:       &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1},
+			wantEvent: "COMMENT",
+		},
+		{
+			name:      "requested event",
+			config:    &Configuration{},
+			req:       &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, RequestedEvent: "request_changes"},
+			wantEvent: "REQUEST_CHANGES",
+		},
+		{
+			name:      "pending team reviews",
+			config:    &Configuration{PendingTeamReviews: PendingTeamsComment},
+			req:       &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1},
+			wantEvent: "COMMENT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var submitted string
+			gc := newTestGitHubClient(t, tt.config, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls/1":
+					writeJSON(w, http.StatusOK, map[string]interface{}{
+						"number":          1,
+						"requested_teams": []map[string]string{{"slug": "platform"}},
+						"base":            map[string]interface{}{"repo": map[string]interface{}{"owner": map[string]string{"login": "o"}}},
+					})
+				case r.Method == http.MethodPost && r.URL.Path == "/repos/o/r/pulls/1/reviews":
+					submitted = reviewEvent(t, r)
+					writeJSON(w, http.StatusOK, map[string]int64{"id": 7})
+				default:
+					http.NotFound(w, r)
+				}
+			})
+
+			result, err := gc.ApprovePR(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("ApprovePR: %v", err)
+			}
+			if !result.Success || result.ReviewID != 7 {
+				t.Fatalf("result = %+v, want a successful review 7", result)
+			}
+			if submitted != tt.wantEvent || result.Event != tt.wantEvent {
+				t.Errorf("submitted %q, result event %q, want %q", submitted, result.Event, tt.wantEvent)
+			}
+			if result.approved() != tt.wantApproved {
+				t.Errorf("approved() = %v, want %v", result.approved(), tt.wantApproved)
+			}
+		})
+	}
+}
+
+func TestReviewDecision(t *testing.T) {
+	tests := []struct {
+		result       *ApprovalResult
+		wantDecision string
+		wantOutcome  string
+	}{
+		{&ApprovalResult{Success: true, Event: "APPROVE"}, decisionApproved, outcomeApproved},
+		{&ApprovalResult{Success: true, Event: "APPROVE", AlreadyApproved: true}, decisionApproved, outcomeApproved},
+		{&ApprovalResult{Success: true, Event: "COMMENT"}, decisionReviewed, outcomeReviewed},
+		{&ApprovalResult{Success: true, Event: "REQUEST_CHANGES"}, decisionReviewed, outcomeReviewed},
+	}
+
+	for _, tt := range tests {
+		decision := &approvalDecision{}
+		reviewDecision(decision, tt.result)
+		if decision.Decision != tt.wantDecision || decision.Outcome != tt.wantOutcome {
+			t.Errorf("reviewDecision(%+v) = %s/%s, want %s/%s", tt.result, decision.Decision, decision.Outcome, tt.wantDecision, tt.wantOutcome)
+		}
+	}
+}
+
+func TestCompositeOutcomeReviewed(t *testing.T) {
+	reviewed := &approvalDecision{Decision: decisionReviewed}
+	approved := &approvalDecision{Decision: decisionApproved}
+
+	if got := compositeOutcome([]*approvalDecision{reviewed, reviewed}); got != outcomeReviewed {
+		t.Errorf("all reviewed = %s, want %s", got, outcomeReviewed)
+	}
+	if got := compositeOutcome([]*approvalDecision{approved, reviewed}); got != outcomePartial {
+		t.Errorf("approved and reviewed = %s, want %s", got, outcomePartial)
+	}
+}
+
+func TestApprovalCheckbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"checked", "## Checklist\n- [x] Safe to auto-approve\n- [ ] Docs updated", false},
+		{"checked with capital X", "* [X] safe to auto-approve", false},
+		{"unchecked", "## Checklist\n- [ ] Safe to auto-approve\n- [x] Docs updated", true},
+		{"no template", "Fixes a typo", true},
+		{"mentioned in prose", "Is this safe to auto-approve? [x]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{RequireApprovalCheckbox: true, ApprovalCheckboxPattern: defaultApprovalCheckboxPattern}
+			gc := newTestGitHubClient(t, config, (&fakeGitHub{body: tt.body}).ServeHTTP)
+
+			err := gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy())
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+				}
+				return
+			}
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || policyErr.Policy != "approval-checkbox" {
+				t.Errorf("ValidatePRReference = %v, want an approval-checkbox policy error", err)
+			}
+		})
+	}
+}
+
+func TestApproveUnprocessableReview(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantSuccess bool
+		wantError   string
+	}{
+		{name: "review already submitted", message: "Review already submitted for this pull request", wantSuccess: true},
+		{name: "already approved", message: "You have already approved this pull request", wantSuccess: true},
+		{name: "closed", message: "Pull request is closed", wantError: "already merged or closed"},
+		{name: "merged", message: "Pull request has been merged", wantError: "already merged or closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{reviewStatus: http.StatusUnprocessableEntity, reviewError: tt.message}
+			gc := newTestGitHubClient(t, &Configuration{GitHubMaxRetries: 3}, gh.ServeHTTP)
+
+			result, err := gc.ApprovePRWithRetry(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+			if err != nil {
+				t.Fatalf("ApprovePRWithRetry: %v", err)
+			}
+			if result.Success != tt.wantSuccess || result.AlreadyApproved != tt.wantSuccess {
+				t.Errorf("result = %+v, want success and already approved %v", result, tt.wantSuccess)
+			}
+			if !strings.Contains(result.Error, tt.wantError) || (tt.wantError == "") != (result.Error == "") {
+				t.Errorf("error = %q, want %q", result.Error, tt.wantError)
+			}
+			if reviews := gh.submitted(); len(reviews) != 1 {
+				t.Errorf("submitted %d review(s), want a single attempt for a permanent 422", len(reviews))
+			}
+		})
+	}
+}
+
+func TestValidationRetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		failures     int
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "500 then 200", status: http.StatusInternalServerError, failures: 1, wantAttempts: 2},
+		{name: "502 twice then 200", status: http.StatusBadGateway, failures: 2, wantAttempts: 3},
+		{name: "500 until out of attempts", status: http.StatusInternalServerError, failures: 5, wantErr: true, wantAttempts: 3},
+		{name: "404 is permanent", status: http.StatusNotFound, failures: 5, wantErr: true, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			var mu sync.Mutex
+			attempts := 0
+			gc := newTestGitHubClient(t, &Configuration{GitHubMaxRetries: 3}, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls/1" {
+					mu.Lock()
+					attempts++
+					failing := attempts <= tt.failures
+					mu.Unlock()
+					if failing {
+						writeJSON(w, tt.status, map[string]string{"message": http.StatusText(tt.status)})
+						return
+					}
+				}
+				gh.ServeHTTP(w, r)
+			})
+
+			err := gc.ValidatePRReference(context.Background(), "o", "r", 1, Policy{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePRReference = %v, want error %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("fetched the PR %d time(s), want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSSORequiredMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "with an authorization URL",
+			header: "required; url=https://github.com/orgs/o/sso?authorization_request=abc",
+			want:   "token is not SSO-authorized for organization o; authorize it at https://github.com/orgs/o/sso?authorization_request=abc",
+			wantOK: true,
+		},
+		{
+			name:   "without an authorization URL",
+			header: "required",
+			want:   "token is not SSO-authorized for organization o; authorize it under your token's Configure SSO settings",
+			wantOK: true,
+		},
+		{name: "partial results", header: "partial-results; organizations=123"},
+		{name: "no header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &github.Response{Response: &http.Response{Header: http.Header{}}}
+			if tt.header != "" {
+				response.Header.Set("X-GitHub-SSO", tt.header)
+			}
+			got, ok := ssoRequiredMessage(response, "o")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ssoRequiredMessage = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSSORequiredValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantAuth   bool
+		wantReason string
+	}{
+		{name: "SSO required", header: "required; url=https://github.com/orgs/o/sso", wantAuth: true, wantReason: "not SSO-authorized"},
+		{name: "plain forbidden", wantReason: "insufficient permissions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc := newTestGitHubClient(t, &Configuration{}, func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("X-GitHub-SSO", tt.header)
+				}
+				writeJSON(w, http.StatusForbidden, map[string]string{"message": "Forbidden"})
+			})
+
+			err := gc.ValidatePRReference(context.Background(), "o", "r", 1, (&Configuration{}).GlobalPolicy())
+			var authErr *AuthenticationError
+			if got := errors.As(err, &authErr); got != tt.wantAuth {
+				t.Errorf("ValidatePRReference = %v, want an authentication error %v", err, tt.wantAuth)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantReason) {
+				t.Errorf("ValidatePRReference = %v, want it to mention %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestReviewBody(t *testing.T) {
+	tests := []struct {
+		message string
+		footer  string
+		want    string
+	}{
+		{message: "Approved by @alice", footer: "— automated via lgtm", want: "Approved by @alice\n\n— automated via lgtm"},
+		{message: "Approved by @alice", want: "Approved by @alice"},
+		{footer: "— automated via lgtm", want: "— automated via lgtm"},
+		{message: " \n", footer: "\n— automated via lgtm\n", want: "— automated via lgtm"},
+		{message: "  ", footer: "  "},
+	}
+
+	for _, tt := range tests {
+		if got := reviewBody(tt.message, tt.footer); got != tt.want {
+			t.Errorf("reviewBody(%q, %q) = %q, want %q", tt.message, tt.footer, got, tt.want)
+		}
+	}
+}
+
+func TestReviewFooter(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		footer  string
+		want    string
+	}{
+		{name: "footer only", footer: "— automated via lgtm", want: "— automated via lgtm"},
+		{name: "footer after the message", message: "Approved from Slack by @alice", footer: "— automated via lgtm", want: "Approved from Slack by @alice\n\n— automated via lgtm"},
+		{name: "blank footer", message: "Approved from Slack by @alice", footer: "   ", want: "Approved from Slack by @alice"},
+		{name: "no body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			gc := newTestGitHubClient(t, &Configuration{ReviewFooter: tt.footer}, gh.ServeHTTP)
+
+			result, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, Message: tt.message})
+			if err != nil || !result.Success {
+				t.Fatalf("ApprovePR = %+v, %v", result, err)
+			}
+			if got := gh.bodies(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("review bodies %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runHeartbeat logs a liveness summary every interval until ctx is canceled
+func runHeartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			metrics.Inc(metricHeartbeats)
+
+			connection := "disconnected"
+			if metrics.Gauge(metricSlackConnected) == 1 {
+				connection = "connected"
+			}
+
+			logInfo("Heartbeat: uptime=%v slack=%s ready=%t draining=%t paused=%t github_degraded=%t in_flight=%.0f messages=%d matches=%d approvals=%d skipped=%d failures=%d github_rate_remaining=%.0f",
+				metrics.Uptime().Round(time.Second),
+				connection,
+				metrics.Gauge(metricSlackReady) == 1,
+				metrics.Gauge(metricDraining) == 1,
+				metrics.Gauge(metricApprovalsPaused) == 1,
+				metrics.Gauge(metricGitHubDegraded) == 1,
+				metrics.Gauge(metricApprovalsInFlight),
+				metrics.Counter(metricMessagesReceived),
+				metrics.Counter(metricPatternMatches),
+				metrics.Counter(metricApprovals),
+				metrics.Counter(metricApprovalsSkipped),
+				metrics.Counter(metricApprovalFailures),
+				metrics.Gauge(metricGitHubRateRemaining))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// approvalHold is a reason approvals can't run right now, whichever way they were asked for
+type approvalHold struct {
+	outcome string
+	reason  string
+	skip    SkipReason
+	// retryAfter is how long until the hold may lift, zero when unknown
+	retryAfter time.Duration
+}
+
+// pausedHold holds approvals while the kill switch is on
+func (sc *SlackClient) pausedHold() *approvalHold {
+	if !sc.pause.isPaused() {
+		return nil
+	}
+	return &approvalHold{outcome: outcomePaused, reason: "approvals paused", skip: SkipPaused}
+}
+
+// freezeHold holds approvals by user during a deploy freeze, unless they may override it
+func (sc *SlackClient) freezeHold(user string) *approvalHold {
+	window, frozen := sc.config.frozenFor(user, time.Now())
+	if !frozen {
+		return nil
+	}
+	return &approvalHold{
+		outcome:    outcomeFrozen,
+		reason:     "deploy freeze until " + window.End.Format(time.RFC3339),
+		skip:       SkipFrozen,
+		retryAfter: time.Until(window.End),
+	}
+}
+
+// degradedHold holds approvals while GitHub is degraded
+func (sc *SlackClient) degradedHold() *approvalHold {
+	if !sc.githubClient.degraded.isActive() {
+		return nil
+	}
+	return &approvalHold{outcome: outcomeDegraded, reason: "GitHub degraded", skip: SkipDegraded}
+}
+
+// throttleHold takes a token from the approval throttle, holding the approval when none is left
+func (sc *SlackClient) throttleHold() *approvalHold {
+	ok, delay := sc.throttle.take()
+	if ok {
+		return nil
+	}
+	metrics.Inc(metricApprovalsThrottled)
+	return &approvalHold{
+		outcome:    outcomeThrottled,
+		reason:     fmt.Sprintf("approval throttle exceeded, next approval in about %v", delay.Round(time.Second)),
+		skip:       SkipThrottled,
+		retryAfter: delay,
+	}
+}
+
+// immediateHold checks the holds on every approval, in the order a Slack approval
+// meets them, without waiting any of them out: for callers that answer right away,
+// such as the approval API and the label queue. It returns the first hold that
+// applies, or nil, having taken a throttle token, when the approval may run now.
+func (sc *SlackClient) immediateHold(user string) *approvalHold {
+	if hold := sc.pausedHold(); hold != nil {
+		return hold
+	}
+	if hold := sc.freezeHold(user); hold != nil {
+		return hold
+	}
+	if hold := sc.degradedHold(); hold != nil {
+		return hold
+	}
+	return sc.throttleHold()
+}
+
+// skipHeld skips an approval that a hold stopped, freeing its dedupe claim so the
+// message can be tried again once the hold lifts
+func (sc *SlackClient) skipHeld(req *ApprovalRequest, decision *approvalDecision, hold *approvalHold) {
+	sc.dedupe.release(approvalKey(req))
+	metrics.Inc(metricApprovalsSkipped)
+	decision.Decision = decisionSkipped
+	decision.Outcome = hold.outcome
+	decision.Reason = hold.reason
+	decision.SkipReason = hold.skip
+	logInfo("Skipping PR %s/%s#%d: %s", req.Owner, req.Repository, req.PRNumber, hold.reason)
+	sc.reactOutcome(req, hold.outcome)
+}
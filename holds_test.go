@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSlackApprovalHolds(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Configuration
+		setup        func(sc *SlackClient)
+		wantReaction string
+	}{
+		{
+			name:         "frozen",
+			config:       &Configuration{FreezeWindows: []FreezeWindow{{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}}},
+			wantReaction: "lock",
+		},
+		{
+			name:         "throttled",
+			config:       &Configuration{MaxApprovalsPerMinute: 1},
+			setup:        func(sc *SlackClient) { sc.throttle.take() },
+			wantReaction: "snail",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, tt.config, gh)
+			if tt.setup != nil {
+				tt.setup(sc)
+			}
+
+			sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != 0 {
+				t.Errorf("submitted %v while held", reviews)
+			}
+			if !reactions.has(tt.wantReaction) {
+				t.Errorf("reactions %v, want %s", reactions.added, tt.wantReaction)
+			}
+		})
+	}
+}
+
+func TestFreezeOverrideUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        string
+		wantReviews int
+	}{
+		{name: "override user", user: "UOVERRIDE", wantReviews: 1},
+		{name: "anyone else", user: "U1", wantReviews: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, &Configuration{
+				FreezeWindows:       []FreezeWindow{{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}},
+				FreezeOverrideUsers: []string{"UOVERRIDE"},
+			}, gh)
+
+			msg := testMessage("lgtm https://github.com/o/r/pull/1")
+			msg.User = tt.user
+			sc.processMessage(context.Background(), msg)
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != tt.wantReviews {
+				t.Errorf("submitted %v during a freeze, want %d review(s)", reviews, tt.wantReviews)
+			}
+			if frozen := reactions.has("lock"); frozen != (tt.wantReviews == 0) {
+				t.Errorf("reactions %v, want lock %v", reactions.added, tt.wantReviews == 0)
+			}
+			if hold := sc.immediateHold(tt.user); (hold != nil) != (tt.wantReviews == 0) {
+				t.Errorf("immediateHold(%s) = %+v", tt.user, hold)
+			}
+		})
+	}
+}
+
+func TestImmediateHoldOrder(t *testing.T) {
+	sc, _ := newTestSlackClient(t, &Configuration{
+		MaxApprovalsPerMinute: 1,
+		FreezeWindows:         []FreezeWindow{{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}},
+	}, &fakeGitHub{})
+	sc.pause.pause()
+
+	if hold := sc.immediateHold("U1"); hold == nil || hold.outcome != outcomePaused {
+		t.Fatalf("hold = %+v, want paused first", hold)
+	}
+	sc.pause.resume()
+	if hold := sc.immediateHold("U1"); hold == nil || hold.outcome != outcomeFrozen {
+		t.Fatalf("hold = %+v, want frozen", hold)
+	}
+
+	// Holds ahead of the throttle don't use up its token
+	sc.config.FreezeWindows = nil
+	if hold := sc.immediateHold("U1"); hold != nil {
+		t.Fatalf("hold = %+v, want none", hold)
+	}
+	if hold := sc.immediateHold("U1"); hold == nil || hold.outcome != outcomeThrottled {
+		t.Fatalf("hold = %+v, want throttled", hold)
+	}
+}
+
+func TestDelayedApprovalReaction(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{MaxApprovalsPerMinute: 1200, QueueWhenThrottled: true}, gh)
+	for ok, _ := sc.throttle.take(); ok; ok, _ = sc.throttle.take() {
+	}
+
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if len(gh.submitted()) != 1 {
+		t.Fatalf("submitted %v, want one approval once the throttle refills", gh.submitted())
+	}
+	if !reactions.has("hourglass_flowing_sand") || !reactions.has("white_check_mark") {
+		t.Errorf("reactions %v, want the queued hourglass, then white_check_mark", reactions.added)
+	}
+	// The hourglass is replaced even without ReplaceInterimReactions
+	if len(reactions.removed) != 1 || reactions.removed[0] != "hourglass_flowing_sand" {
+		t.Errorf("removed %v, want the queued hourglass", reactions.removed)
+	}
+	if entries, _ := sc.store.Entries(context.Background(), storeNamespaceQueuedReactions); len(entries) != 0 {
+		t.Errorf("queued reactions %v left after the approval was decided", entries)
+	}
+}
+
+func TestQueuedReactionsAcrossRestart(t *testing.T) {
+	t.Run("cancelled by shutdown", func(t *testing.T) {
+		sc, reactions := newTestSlackClient(t, &Configuration{MaxApprovalsPerMinute: 1, QueueWhenThrottled: true}, &fakeGitHub{})
+		sc.throttle.take()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1"))
+		deadline := time.Now().Add(5 * time.Second)
+		for !reactions.has("hourglass_flowing_sand") {
+			if time.Now().After(deadline) {
+				t.Fatalf("reactions %v, want the queued hourglass", reactions.added)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		waitForApprovals(t, sc)
+
+		// The next start removes the hourglass the stopped queue left behind
+		if entries, _ := sc.store.Entries(context.Background(), storeNamespaceQueuedReactions); len(entries) != 1 {
+			t.Fatalf("queued reactions %v, want the cancelled approval's", entries)
+		}
+	})
+
+	t.Run("reconciled on start", func(t *testing.T) {
+		sc, _ := newTestSlackClient(t, &Configuration{DedupeWindow: time.Hour}, &fakeGitHub{})
+		req := &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, SourceChannel: "C1", SourceMessage: testMessage("lgtm o/r#1")}
+		sc.dedupe.claim(approvalKey(req))
+		sc.queuedReactions.record(req, "double_vertical_bar")
+
+		sc.reconcileQueuedReactions(context.Background())
+
+		if entries, _ := sc.store.Entries(context.Background(), storeNamespaceQueuedReactions); len(entries) != 0 {
+			t.Errorf("queued reactions %v left after reconciling", entries)
+		}
+		if !sc.dedupe.claim(approvalKey(req)) {
+			t.Error("the lost approval is still claimed, so it can't be requested again")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// approvalSlots counts the approval goroutines in flight and, with a limit, refuses
+// new ones beyond it. It is a safety ceiling against overload and goroutine leaks,
+// not a queue: a refused approval is dropped.
+type approvalSlots struct {
+	mu     sync.Mutex
+	limit  int
+	active int
+}
+
+// newApprovalSlots creates the counter; a limit of zero only counts
+func newApprovalSlots(limit int) *approvalSlots {
+	metrics.SetGauge(metricApprovalsInFlight, 0)
+	return &approvalSlots{limit: limit}
+}
+
+// acquire takes a slot, reporting false when the limit is reached
+func (as *approvalSlots) acquire() bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.limit > 0 && as.active >= as.limit {
+		return false
+	}
+	as.active++
+	metrics.SetGauge(metricApprovalsInFlight, float64(as.active))
+	return true
+}
+
+// release gives a slot back
+func (as *approvalSlots) release() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.active--
+	metrics.SetGauge(metricApprovalsInFlight, float64(as.active))
+}
+
+// count returns the number of approvals in flight
+func (as *approvalSlots) count() int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	return as.active
+}
+
+// spawnApproval processes an approval in its own goroutine. It is rejected with the
+// draining reaction while the instance drains, and with the overloaded reaction when
+// MaxInFlightApprovals goroutines are already running.
+func (sc *SlackClient) spawnApproval(ctx context.Context, req *ApprovalRequest) {
+	if sc.drain.active() {
+		logInfo("Draining, rejecting PR %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+		sc.rejectApproval(req, outcomeDraining, "instance is draining", SkipDraining)
+		return
+	}
+	if !sc.inFlight.acquire() {
+		metrics.Inc(metricApprovalsRejected)
+		logWarn("Too many approvals in flight (%d), rejecting PR %s/%s#%d", sc.config.MaxInFlightApprovals, req.Owner, req.Repository, req.PRNumber)
+		sc.rejectApproval(req, outcomeOverloaded, fmt.Sprintf("%d approvals already in flight", sc.config.MaxInFlightApprovals), SkipOverloaded)
+		return
+	}
+
+	go func() {
+		defer sc.inFlight.release()
+		sc.processApproval(ctx, req)
+	}()
+}
+
+// rejectApproval skips an approval that was never started, freeing its dedupe claim
+// so a redelivery or another instance can take it
+func (sc *SlackClient) rejectApproval(req *ApprovalRequest, outcome, reason string, skip SkipReason) {
+	sc.dedupe.release(approvalKey(req))
+	metrics.Inc(metricApprovalsSkipped)
+
+	decision := newApprovalDecision(req)
+	decision.Decision = decisionSkipped
+	decision.Outcome = outcome
+	decision.Reason = reason
+	decision.SkipReason = skip
+	sc.logSkip(req.SourceMessage, decision)
+	sc.reactOutcome(req, outcome)
+	sc.finishBatchDecision(req, decision)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestApprovalSlots(t *testing.T) {
+	slots := newApprovalSlots(2)
+	if !slots.acquire() || !slots.acquire() {
+		t.Fatal("acquire failed below the limit")
+	}
+	if slots.acquire() {
+		t.Error("acquire succeeded at the limit")
+	}
+	if got := metrics.Gauge(metricApprovalsInFlight); got != 2 {
+		t.Errorf("in-flight gauge = %v, want 2", got)
+	}
+	slots.release()
+	if !slots.acquire() {
+		t.Error("acquire failed after a release")
+	}
+
+	unlimited := newApprovalSlots(0)
+	for i := 0; i < 100; i++ {
+		if !unlimited.acquire() {
+			t.Fatalf("acquire %d failed without a limit", i)
+		}
+	}
+	if unlimited.count() != 100 {
+		t.Errorf("count = %d, want 100", unlimited.count())
+	}
+}
+
+func TestInFlightCapHoldsUnderFlood(t *testing.T) {
+	gh := &fakeGitHub{reviewGate: make(chan struct{})}
+	sc, reactions := newTestSlackClient(t, &Configuration{MaxInFlightApprovals: 2}, gh)
+	rejected := metrics.Counter(metricApprovalsRejected)
+
+	for i := 1; i <= 5; i++ {
+		msg := testMessage(fmt.Sprintf("lgtm https://github.com/o/r/pull/%d", i))
+		msg.Timestamp = fmt.Sprintf("1700000000.00010%d", i)
+		sc.processMessage(context.Background(), msg)
+	}
+
+	if got := sc.inFlight.count(); got != 2 {
+		t.Errorf("%d approvals in flight, want the cap of 2", got)
+	}
+	if got := metrics.Gauge(metricApprovalsInFlight); got != 2 {
+		t.Errorf("in-flight gauge = %v, want 2", got)
+	}
+	if got := metrics.Counter(metricApprovalsRejected) - rejected; got != 3 {
+		t.Errorf("rejected %d approvals, want 3", got)
+	}
+	if !reactions.has("no_entry_sign") {
+		t.Errorf("reactions %v, want the overloaded reaction", reactions.added)
+	}
+
+	close(gh.reviewGate)
+	waitForApprovals(t, sc)
+	if got := gh.submitted(); len(got) != 2 {
+		t.Errorf("submitted %v, want only the 2 approvals that got a slot", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Gauge(metricApprovalsInFlight) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight gauge = %v after the flood, want 0", metrics.Gauge(metricApprovalsInFlight))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMaxInFlightApprovalsValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--max-in-flight-approvals", "-1"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "MaxInFlightApprovals" {
+		t.Errorf("validateConfiguration = %v, want a MaxInFlightApprovals error", err)
+	}
+}
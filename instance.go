@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// instanceNamePattern matches a valid instance name
+var instanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// instanceMarkerPattern finds the hidden instance marker in a review body
+var instanceMarkerPattern = regexp.MustCompile(`<!-- lgtm:instance=([A-Za-z0-9_.-]+) -->`)
+
+// instanceMarker is the hidden marker added to an instance's reviews, empty for an unnamed instance
+func instanceMarker(instance string) string {
+	if instance == "" {
+		return ""
+	}
+	return fmt.Sprintf("<!-- lgtm:instance=%s -->", instance)
+}
+
+// ownedByInstance reports whether a bot review was submitted by this instance. Reviews
+// without a marker belong to the unnamed instance, so bots sharing a GitHub user don't
+// dismiss or report each other's approvals.
+func ownedByInstance(body, instance string) bool {
+	match := instanceMarkerPattern.FindStringSubmatch(body)
+	if match == nil {
+		return instance == ""
+	}
+	return match[1] == instance
+}
+
+// instanceStore keeps an instance's state apart from other instances sharing a store
+// by prefixing every namespace with the instance name
+type instanceStore struct {
+	Store
+	instance string
+}
+
+// newInstanceStore wraps a store for a named instance; an unnamed instance uses it as is
+func newInstanceStore(store Store, instance string) Store {
+	if instance == "" {
+		return store
+	}
+	return &instanceStore{Store: store, instance: instance}
+}
+
+func (s *instanceStore) namespace(namespace string) string {
+	return s.instance + "/" + namespace
+}
+
+func (s *instanceStore) Get(ctx context.Context, namespace, key string) (string, bool, error) {
+	return s.Store.Get(ctx, s.namespace(namespace), key)
+}
+
+func (s *instanceStore) Put(ctx context.Context, namespace, key, value string, ttl time.Duration) error {
+	return s.Store.Put(ctx, s.namespace(namespace), key, value, ttl)
+}
+
+func (s *instanceStore) PutIfAbsent(ctx context.Context, namespace, key, value string, ttl time.Duration) (bool, error) {
+	return s.Store.PutIfAbsent(ctx, s.namespace(namespace), key, value, ttl)
+}
+
+func (s *instanceStore) Delete(ctx context.Context, namespace, key string) error {
+	return s.Store.Delete(ctx, s.namespace(namespace), key)
+}
+
+func (s *instanceStore) List(ctx context.Context, namespace string) (map[string]string, error) {
+	return s.Store.List(ctx, s.namespace(namespace))
+}
+
+func (s *instanceStore) Entries(ctx context.Context, namespace string) ([]StoreEntry, error) {
+	return s.Store.Entries(ctx, s.namespace(namespace))
+}
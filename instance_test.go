@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOwnedByInstance(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		instance string
+		want     bool
+	}{
+		{name: "own marker", body: "Approved\n\n" + instanceMarker("blue"), instance: "blue", want: true},
+		{name: "other instance's marker", body: "Approved\n\n" + instanceMarker("green"), instance: "blue"},
+		{name: "unmarked review, named instance", body: "Approved", instance: "blue"},
+		{name: "unmarked review, unnamed instance", body: "Approved", want: true},
+		{name: "marked review, unnamed instance", body: instanceMarker("blue")},
+		{name: "marker of a longer name", body: instanceMarker("blue-2"), instance: "blue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownedByInstance(tt.body, tt.instance); got != tt.want {
+				t.Errorf("ownedByInstance(%q, %q) = %v, want %v", tt.body, tt.instance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewsCarryInstanceMarker(t *testing.T) {
+	gh := &fakeGitHub{}
+	gc := newTestGitHubClient(t, &Configuration{InstanceName: "blue"}, gh.ServeHTTP)
+
+	if _, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, Message: "Approved"}); err != nil {
+		t.Fatalf("ApprovePR: %v", err)
+	}
+	if got := gh.bodies(); len(got) != 1 || !strings.HasSuffix(got[0], "<!-- lgtm:instance=blue -->") {
+		t.Errorf("review bodies %q, want the instance marker", got)
+	}
+}
+
+func TestDismissOnlyOwnInstanceApprovals(t *testing.T) {
+	tests := []struct {
+		name          string
+		instance      string
+		wantDismissed bool
+	}{
+		{name: "own approval", instance: "blue", wantDismissed: true},
+		{name: "another instance's approval", instance: "green"},
+		{name: "unnamed instance", instance: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/pulls/1/reviews": []map[string]interface{}{
+					{"id": 1, "state": "APPROVED", "body": "Approved\n\n" + instanceMarker("blue"), "user": map[string]string{"login": fakeBotLogin}},
+				},
+			}}
+			gc := newTestGitHubClient(t, &Configuration{InstanceName: tt.instance}, gh.ServeHTTP)
+
+			dismissed, err := gc.DismissBotApproval(context.Background(), "o", "r", 1, "Slack message deleted")
+			if err != nil {
+				t.Fatalf("DismissBotApproval: %v", err)
+			}
+			if dismissed != tt.wantDismissed {
+				t.Errorf("DismissBotApproval = %v, want %v", dismissed, tt.wantDismissed)
+			}
+		})
+	}
+}
+
+func TestInstanceStoresAreSeparate(t *testing.T) {
+	ctx := context.Background()
+	shared := newMemoryStore()
+	blue, green := newInstanceStore(shared, "blue"), newInstanceStore(shared, "green")
+
+	if err := blue.Put(ctx, "dedupe", "o/r#1", "claimed", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := green.Get(ctx, "dedupe", "o/r#1"); ok {
+		t.Error("one instance saw another instance's entry")
+	}
+	if claimed, err := green.PutIfAbsent(ctx, "dedupe", "o/r#1", "claimed", 0); err != nil || !claimed {
+		t.Errorf("PutIfAbsent = %v, %v, want the other instance to claim the key too", claimed, err)
+	}
+	entries, err := blue.List(ctx, "dedupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, map[string]string{"o/r#1": "claimed"}) {
+		t.Errorf("List = %v, want only the instance's own entry", entries)
+	}
+	if newInstanceStore(shared, "") != shared {
+		t.Error("the unnamed instance's store is wrapped")
+	}
+}
+
+func TestInstanceNameValidation(t *testing.T) {
+	if err := validateConfiguration(validConfig(t, "--instance-name", "blue-1.prod")); err != nil {
+		t.Errorf("validateConfiguration: %v", err)
+	}
+
+	err := validateConfiguration(validConfig(t, "--instance-name", "blue team"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "InstanceName" {
+		t.Errorf("validateConfiguration = %v, want an InstanceName error", err)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ways to read a bare #5 in a message that also links a PR numbered 5
+const (
+	// BareNumbersLink reads the bare number as the linked PR
+	BareNumbersLink = "link"
+	// BareNumbersSeparate resolves the bare number against the default repository on its own
+	BareNumbersSeparate = "separate"
+)
+
+// intentReasonPattern matches the free-text reason at the end of a message, e.g.
+// "lgtm #12 because the hotfix is tested" or "lgtm #12 reason: docs only"
+var intentReasonPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:reason:\s*|because\s+)(.+)$`)
+
+// intentFlagPattern matches the +flags that ask for more than a review, e.g. "+merge"
+var intentFlagPattern = regexp.MustCompile(`(?i)(?:^|\s)\+(auto-?merge|merge)\b`)
+
+// intentNegationPattern matches a negation right before the trigger, e.g. the "not" of
+// "not lgtm" or the "don't" of "don't lgtm this", with straight or curly apostrophes
+var intentNegationPattern = regexp.MustCompile(`(?i)(?:^|[^[:alnum:]'’])(?:not|no|never|cannot|(?:do|does|did|is|are|ca|could|should|would|wo)n['’]?t)\s*$`)
+
+// githubHostBoundary starts a GitHub URL pattern, so github.com is not matched inside
+// another host such as evilgithub.com or gist.github.com. It consumes the character
+// before the URL, which githubURL strips again.
+const githubHostBoundary = `(?:^|[^[:alnum:].-])`
+
+// githubURL returns the URL of a githubHostBoundary match without the character before it
+func githubURL(match string) string {
+	// Every URL starts with https://, www. or github.com
+	if match == "" || match[0] == 'h' || match[0] == 'w' || match[0] == 'g' {
+		return match
+	}
+	_, size := utf8.DecodeRuneInString(match)
+	return match[size:]
+}
+
+// negatedTriggerNote heads the COMMENT review submitted for a negated trigger
+const negatedTriggerNote = "The Slack message asking for this review negates its trigger (e.g. \"not lgtm\"), so it was not taken as an approval. Please approve again with an unambiguous message if one was intended."
+
+// ApprovalIntent is what a matching message asks for: which PRs, with which review
+// event, and why. Whether a message matches at all is the PatternMatcher's job.
+type ApprovalIntent struct {
+	// Action is the review event shared by every reference, empty when they differ or
+	// when none was given, in which case the policy's review event applies
+	Action     string
+	References []PRReference
+	// Reason is the text after "because" or "reason:" without any flags, empty when none is given
+	Reason string
+	// Merge and AutoMerge are set by the +merge and +automerge flags
+	Merge     bool
+	AutoMerge bool
+	// Negated is set when the trigger is negated, e.g. "not lgtm", so the message may
+	// not be asking for an approval at all
+	Negated bool
+}
+
+// IntentParser turns message text into an ApprovalIntent. It is configured once and
+// shared by the matcher and every place that reads PR references from text.
+type IntentParser struct {
+	aliases map[string]RepoTarget
+	// commits enables commit URL references, resolved to their PRs later
+	commits bool
+	// branches enables blob and tree link references, resolved to their branch's PR later
+	branches bool
+	// actions enables per-reference action keywords such as "request changes on #2"
+	actions bool
+	// repoCase is how owner and repository names are normalized, lowercase when empty
+	repoCase string
+	// bareNumbers is how a bare number that a link also names is read, BareNumbersLink when empty
+	bareNumbers string
+}
+
+// NewIntentParser creates a parser that finds PR URLs and numbers, with aliases,
+// commit links and action keywords disabled
+func NewIntentParser() *IntentParser {
+	return &IntentParser{}
+}
+
+// Parse reads the intent of a message: its PR references, tagged with their action
+// keywords when per-reference actions are enabled, plus any reason and flags
+func (ip *IntentParser) Parse(text string) (*ApprovalIntent, error) {
+	extract := ip.ExtractPRReferences
+	if ip.actions {
+		extract = ip.extractActionReferences
+	}
+	refs, err := extract(text)
+	if err != nil {
+		return nil, err
+	}
+	return ip.ParseWithReferences(text, refs), nil
+}
+
+// ParseWithReferences reads the reason and flags of a message whose references are
+// already known, e.g. from a pattern's named groups
+func (ip *IntentParser) ParseWithReferences(text string, refs []PRReference) *ApprovalIntent {
+	intent := &ApprovalIntent{References: canonicalReferences(refs, ip.repoCase)}
+
+	for i, ref := range intent.References {
+		if i == 0 {
+			intent.Action = ref.Action
+		} else if ref.Action != intent.Action {
+			intent.Action = ""
+			break
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if match := intentReasonPattern.FindStringSubmatch(line); match != nil {
+			intent.Reason = strings.TrimSpace(intentFlagPattern.ReplaceAllString(match[1], ""))
+			break
+		}
+	}
+
+	for _, match := range intentFlagPattern.FindAllStringSubmatch(text, -1) {
+		switch strings.ToLower(strings.ReplaceAll(match[1], "-", "")) {
+		case "automerge":
+			intent.AutoMerge = true
+		case "merge":
+			intent.Merge = true
+		}
+	}
+
+	return intent
+}
+
+// negatesTrigger reports whether the trigger matched at start is preceded by a negation
+// on its line. Only the words right before it count, so "lgtm, not a blocker" is not negated.
+func negatesTrigger(message string, start int) bool {
+	before := message[:start]
+	if i := strings.LastIndex(before, "\n"); i >= 0 {
+		before = before[i+1:]
+	}
+	return intentNegationPattern.MatchString(before)
+}
+
+// ExtractPRReferences finds GitHub PR references in text, without action keywords
+func (ip *IntentParser) ExtractPRReferences(text string) ([]PRReference, error) {
+	var references []PRReference
+
+	// GitHub PR URL pattern: https://github.com/owner/repo/pull/123 (matches your bash script).
+	// The scheme is optional for shortened links shared from mobile, and any trailing
+	// path, query string or fragment (/files, ?diff=split, #discussion_r123) is consumed
+	// so it is not mistaken for a bare PR number below.
+	prURLPattern := regexp.MustCompile(githubHostBoundary + `(?:https?://)?(?:www\.)?github\.com/([^[:space:]/]+)/([^[:space:]/]+)/pull/([0-9]+)([/?#][^[:space:]|>]*)?`)
+
+	// Simple PR number pattern: #123, PR-456, PR #123
+	prNumberPattern := regexp.MustCompile(`(?:#|PR-?)\s*(\d+)`)
+
+	// Extract full URLs first
+	urlMatches := prURLPattern.FindAllStringSubmatch(text, -1)
+	for _, match := range urlMatches {
+		if len(match) == 5 {
+			number, err := strconv.Atoi(match[3])
+			if err != nil {
+				continue
+			}
+
+			// Validate the URL format
+			if err := validateGitHubURL(githubURL(match[0])); err != nil {
+				continue
+			}
+
+			references = append(references, PRReference{
+				Owner:      match[1],
+				Repository: match[2],
+				Number:     number,
+				URL:        fmt.Sprintf("https://github.com/%s/%s/pull/%d", match[1], match[2], number),
+			})
+		}
+	}
+
+	// Text left over once URLs have been handled, so their parts aren't matched again
+	remaining := prURLPattern.ReplaceAllString(text, " ")
+
+	// Extract commit URLs: https://github.com/owner/repo/commit/<sha>
+	if ip.commits {
+		commitURLPattern := regexp.MustCompile(githubHostBoundary + `(?:https?://)?(?:www\.)?github\.com/([^[:space:]/]+)/([^[:space:]/]+)/commit/([0-9a-fA-F]{7,40})\b([/?#][^[:space:]|>]*)?`)
+		for _, match := range commitURLPattern.FindAllStringSubmatch(remaining, -1) {
+			if err := validateGitHubURL(githubURL(match[0])); err != nil {
+				continue
+			}
+
+			sha := strings.ToLower(match[3])
+			references = append(references, PRReference{
+				Owner:      match[1],
+				Repository: match[2],
+				URL:        fmt.Sprintf("https://github.com/%s/%s/commit/%s", match[1], match[2], sha),
+				CommitSHA:  sha,
+			})
+		}
+		remaining = commitURLPattern.ReplaceAllString(remaining, " ")
+	}
+
+	// File and directory links (github.com/owner/repo/blob/main/file.go#L10) are not PRs,
+	// and their fragment or a branch like PR-12 must not be read as a PR number below
+	blobURLPattern := regexp.MustCompile(githubHostBoundary + `(?:https?://)?(?:www\.)?github\.com/([^[:space:]/]+)/([^[:space:]/]+)/(blob|tree)/([^[:space:]?#|>]+)([?#][^[:space:]|>]*)?`)
+	if ip.branches {
+		for _, match := range blobURLPattern.FindAllStringSubmatch(remaining, -1) {
+			if err := validateGitHubURL(githubURL(match[0])); err != nil {
+				continue
+			}
+
+			branch := strings.Trim(match[4], "/")
+			references = append(references, PRReference{
+				Owner:      match[1],
+				Repository: match[2],
+				URL:        fmt.Sprintf("https://github.com/%s/%s/%s/%s", match[1], match[2], match[3], branch),
+				Branch:     branch,
+			})
+		}
+	}
+	remaining = blobURLPattern.ReplaceAllString(remaining, " ")
+
+	// Extract aliased references: alias#123 where alias is configured in RepoAliases
+	if len(ip.aliases) > 0 {
+		aliasedPattern := regexp.MustCompile(`(^|[^[:alnum:]_./-])([A-Za-z0-9_.-]+)#([0-9]+)`)
+		remaining = aliasedPattern.ReplaceAllStringFunc(remaining, func(token string) string {
+			match := aliasedPattern.FindStringSubmatch(token)
+			target, ok := ip.aliases[match[2]]
+			if !ok {
+				return token // Unknown alias, handled as a bare number below
+			}
+
+			number, err := strconv.Atoi(match[3])
+			if err != nil {
+				return token
+			}
+
+			references = append(references, PRReference{
+				Owner:      target.Owner,
+				Repository: target.Repo,
+				Number:     number,
+				URL:        fmt.Sprintf("https://github.com/%s/%s/pull/%d", target.Owner, target.Repo, number),
+			})
+			return match[1] + " "
+		})
+	}
+
+	// Extract simple PR numbers (these will need default owner/repo)
+	numberMatches := prNumberPattern.FindAllStringSubmatch(remaining, -1)
+	for _, match := range numberMatches {
+		if len(match) == 2 {
+			number, err := strconv.Atoi(match[1])
+			if err != nil {
+				continue
+			}
+
+			// Only add if we don't already have this PR, or one with its number from a URL
+			alreadyExists := false
+			for _, existing := range references {
+				if existing.Owner == "" && existing.Number == number || ip.namesLinkedPR(existing, PRReference{Number: number}) {
+					alreadyExists = true
+					break
+				}
+			}
+
+			if !alreadyExists {
+				references = append(references, PRReference{
+					Number: number,
+					// Owner and Repository will need to be filled from config
+				})
+			}
+		}
+	}
+
+	return references, nil
+}
+
+// namesLinkedPR reports whether one reference is a bare number and the other a link to a PR
+// with that number in a known repository, so the bare number means the linked PR. In
+// BareNumbersSeparate mode they never do: the bare number is resolved on its own later, and
+// only approved separately when that lands in a different repository.
+func (ip *IntentParser) namesLinkedPR(a, b PRReference) bool {
+	if ip.bareNumbers == BareNumbersSeparate || a.linked() || b.linked() || a.Number != b.Number {
+		return false
+	}
+	return (a.Owner == "") != (b.Owner == "")
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatchNegatedTrigger(t *testing.T) {
+	matcher, err := NewPatternMatcher(`(?i)\blgtm\b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		message string
+		negated bool
+	}{
+		{"lgtm https://github.com/o/r/pull/1", false},
+		{"not lgtm https://github.com/o/r/pull/1", true},
+		{"don't lgtm this https://github.com/o/r/pull/1", true},
+		{"Don’t lgtm this https://github.com/o/r/pull/1", true},
+		{"we can't lgtm https://github.com/o/r/pull/1", true},
+		{"knot lgtm https://github.com/o/r/pull/1", false},
+		{"lgtm, not a draft anymore https://github.com/o/r/pull/1", false},
+		{"not now\nlgtm https://github.com/o/r/pull/1", false},
+	}
+
+	for _, tt := range tests {
+		match, err := matcher.Match(tt.message)
+		if err != nil || match == nil {
+			t.Fatalf("Match(%q) = %v, %v, want a match", tt.message, match, err)
+		}
+		if match.Intent.Negated != tt.negated {
+			t.Errorf("Match(%q) negated = %v, want %v", tt.message, match.Intent.Negated, tt.negated)
+		}
+		if len(match.PRReferences) != 1 {
+			t.Errorf("Match(%q) found %d PR references, want 1", tt.message, len(match.PRReferences))
+		}
+	}
+}
+
+func TestIntentParserParse(t *testing.T) {
+	link := func(owner, repo string, number int) PRReference {
+		return PRReference{Owner: owner, Repository: repo, Number: number, URL: fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, number)}
+	}
+
+	tests := []struct {
+		name    string
+		parser  *IntentParser
+		text    string
+		want    ApprovalIntent
+		wantRef []PRReference
+	}{
+		{
+			name:    "link",
+			parser:  NewIntentParser(),
+			text:    "lgtm https://github.com/o/r/pull/1",
+			wantRef: []PRReference{link("o", "r", 1)},
+		},
+		{
+			name:    "bare numbers",
+			parser:  NewIntentParser(),
+			text:    "lgtm #1 and PR-2",
+			wantRef: []PRReference{{Number: 1}, {Number: 2}},
+		},
+		{
+			name:    "bare number naming the link",
+			parser:  NewIntentParser(),
+			text:    "lgtm #1 https://github.com/o/r/pull/1",
+			wantRef: []PRReference{link("o", "r", 1)},
+		},
+		{
+			name:    "bare number resolved separately",
+			parser:  &IntentParser{bareNumbers: BareNumbersSeparate},
+			text:    "lgtm #1 https://github.com/o/r/pull/1",
+			wantRef: []PRReference{link("o", "r", 1), {Number: 1}},
+		},
+		{
+			name:    "alias",
+			parser:  &IntentParser{aliases: map[string]RepoTarget{"api": {Owner: "o", Repo: "api"}}},
+			text:    "lgtm api#7",
+			wantRef: []PRReference{link("o", "api", 7)},
+		},
+		{
+			name:    "names are lowercased",
+			parser:  NewIntentParser(),
+			text:    "lgtm https://github.com/Org/Repo/pull/3",
+			wantRef: []PRReference{{Owner: "org", Repository: "repo", Number: 3, URL: "https://github.com/Org/Repo/pull/3"}},
+		},
+		{
+			name:    "reason",
+			parser:  NewIntentParser(),
+			text:    "lgtm #1 because the hotfix is tested",
+			want:    ApprovalIntent{Reason: "the hotfix is tested"},
+			wantRef: []PRReference{{Number: 1}},
+		},
+		{
+			name:    "reason prefix and flags",
+			parser:  NewIntentParser(),
+			text:    "lgtm #1 +merge reason: docs only +automerge",
+			want:    ApprovalIntent{Reason: "docs only", Merge: true, AutoMerge: true},
+			wantRef: []PRReference{{Number: 1}},
+		},
+		{
+			name:    "shared action",
+			parser:  &IntentParser{actions: true},
+			text:    "lgtm, request changes on #1 and #2",
+			want:    ApprovalIntent{Action: "REQUEST_CHANGES"},
+			wantRef: []PRReference{{Number: 1, Action: "REQUEST_CHANGES"}, {Number: 2, Action: "REQUEST_CHANGES"}},
+		},
+		{
+			name:    "mixed actions",
+			parser:  &IntentParser{actions: true},
+			text:    "approve #1, comment on #2",
+			wantRef: []PRReference{{Number: 1, Action: "APPROVE"}, {Number: 2, Action: "COMMENT"}},
+		},
+		{
+			name:    "conflicting actions fall back to the default",
+			parser:  &IntentParser{actions: true},
+			text:    "approve #1, comment on #1",
+			wantRef: []PRReference{{Number: 1}},
+		},
+		{
+			name:    "actions disabled",
+			parser:  NewIntentParser(),
+			text:    "lgtm, request changes on #1",
+			wantRef: []PRReference{{Number: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent, err := tt.parser.Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.text, err)
+			}
+			if intent.Action != tt.want.Action || intent.Reason != tt.want.Reason || intent.Merge != tt.want.Merge || intent.AutoMerge != tt.want.AutoMerge {
+				t.Errorf("Parse(%q) = action %q, reason %q, merge %v, automerge %v, want %+v", tt.text, intent.Action, intent.Reason, intent.Merge, intent.AutoMerge, tt.want)
+			}
+			if !reflect.DeepEqual(intent.References, tt.wantRef) {
+				t.Errorf("Parse(%q) references = %+v, want %+v", tt.text, intent.References, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestExtractPRURLHosts(t *testing.T) {
+	pr := PRReference{Owner: "o", Repository: "r", Number: 1, URL: "https://github.com/o/r/pull/1"}
+	tests := []struct {
+		text    string
+		wantRef []PRReference
+	}{
+		{text: "lgtm https://github.com/o/r/pull/1", wantRef: []PRReference{pr}},
+		{text: "lgtm https://www.github.com/o/r/pull/1", wantRef: []PRReference{pr}},
+		{text: "lgtm github.com/o/r/pull/1", wantRef: []PRReference{pr}},
+		{text: "github.com/o/r/pull/1 lgtm", wantRef: []PRReference{pr}},
+		{text: "lgtm <https://github.com/o/r/pull/1|o/r#1>", wantRef: []PRReference{pr}},
+		{text: "lgtm (github.com/o/r/pull/1)", wantRef: []PRReference{pr}},
+		{text: "lgtm “github.com/o/r/pull/1”", wantRef: []PRReference{pr}},
+		// github.com inside another host is not GitHub
+		{text: "lgtm https://evilgithub.com/o/r/pull/1"},
+		{text: "lgtm evilgithub.com/o/r/pull/1"},
+		{text: "lgtm https://gist.github.com/o/r/pull/1"},
+		{text: "lgtm gist.github.com/o/r/pull/1"},
+		{text: "lgtm https://my-github.com/o/r/pull/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			refs, err := NewIntentParser().ExtractPRReferences(tt.text)
+			if err != nil {
+				t.Fatalf("ExtractPRReferences(%q): %v", tt.text, err)
+			}
+			if len(refs) != len(tt.wantRef) || (len(refs) > 0 && !reflect.DeepEqual(refs, tt.wantRef)) {
+				t.Errorf("ExtractPRReferences(%q) = %+v, want %+v", tt.text, refs, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestExtractCommitURLHosts(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	commit := PRReference{Owner: "o", Repository: "r", URL: "https://github.com/o/r/commit/" + sha, CommitSHA: sha}
+	tests := []struct {
+		text    string
+		wantRef []PRReference
+	}{
+		{text: "lgtm https://github.com/o/r/commit/" + sha, wantRef: []PRReference{commit}},
+		{text: "lgtm github.com/o/r/commit/" + sha, wantRef: []PRReference{commit}},
+		{text: "lgtm <https://github.com/o/r/commit/" + sha + "|" + sha[:7] + ">", wantRef: []PRReference{commit}},
+		// github.com inside another host is not GitHub
+		{text: "lgtm https://evilgithub.com/o/r/commit/" + sha},
+		{text: "lgtm evilgithub.com/o/r/commit/" + sha},
+		{text: "lgtm https://gist.github.com/o/r/commit/" + sha},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			refs, err := (&IntentParser{commits: true}).ExtractPRReferences(tt.text)
+			if err != nil {
+				t.Fatalf("ExtractPRReferences(%q): %v", tt.text, err)
+			}
+			if len(refs) != len(tt.wantRef) || (len(refs) > 0 && !reflect.DeepEqual(refs, tt.wantRef)) {
+				t.Errorf("ExtractPRReferences(%q) = %+v, want %+v", tt.text, refs, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestExtractBlobURLHosts(t *testing.T) {
+	branch := PRReference{Owner: "o", Repository: "r", URL: "https://github.com/o/r/tree/feature", Branch: "feature"}
+	tests := []struct {
+		text    string
+		wantRef []PRReference
+	}{
+		{text: "lgtm https://github.com/o/r/tree/feature", wantRef: []PRReference{branch}},
+		{text: "lgtm github.com/o/r/tree/feature/", wantRef: []PRReference{branch}},
+		{text: "lgtm <https://github.com/o/r/tree/feature|feature>", wantRef: []PRReference{branch}},
+		// github.com inside another host is not GitHub
+		{text: "lgtm https://evilgithub.com/o/r/tree/feature"},
+		{text: "lgtm evilgithub.com/o/r/blob/feature/main.go"},
+		{text: "lgtm https://gist.github.com/o/r/tree/feature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			refs, err := (&IntentParser{branches: true}).ExtractPRReferences(tt.text)
+			if err != nil {
+				t.Fatalf("ExtractPRReferences(%q): %v", tt.text, err)
+			}
+			if len(refs) != len(tt.wantRef) || (len(refs) > 0 && !reflect.DeepEqual(refs, tt.wantRef)) {
+				t.Errorf("ExtractPRReferences(%q) = %+v, want %+v", tt.text, refs, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestBareNumberConflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		defaultRepo string
+		want        []string
+	}{
+		{name: "the link wins", mode: BareNumbersLink, defaultRepo: "orgb/repo", want: []string{"POST /repos/orga/repo/pulls/5/reviews"}},
+		{name: "separate in another repository", mode: BareNumbersSeparate, defaultRepo: "orgb/repo", want: []string{"POST /repos/orga/repo/pulls/5/reviews", "POST /repos/orgb/repo/pulls/5/reviews"}},
+		{name: "separate in the linked repository", mode: BareNumbersSeparate, defaultRepo: "orga/repo", want: []string{"POST /repos/orga/repo/pulls/5/reviews"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, repo, _ := strings.Cut(tt.defaultRepo, "/")
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{DefaultOwner: owner, DefaultRepo: repo}, gh)
+			sc.matcher.SetBareNumberConflict(tt.mode)
+
+			sc.processMessage(context.Background(), testMessage("lgtm https://github.com/orga/repo/pull/5 and #5"))
+			waitForApprovals(t, sc)
+
+			var reviews []string
+			for _, request := range gh.requests {
+				if strings.HasPrefix(request, "POST ") && strings.HasSuffix(request, "/reviews") {
+					reviews = append(reviews, request)
+				}
+			}
+			sort.Strings(reviews)
+			if !reflect.DeepEqual(reviews, tt.want) {
+				t.Errorf("reviews %v, want %v", reviews, tt.want)
+			}
+		})
+	}
+}
+
+func TestBareNumberConflictValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--bare-number-conflict", "ignore"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "BareNumberConflict" {
+		t.Errorf("validateConfiguration = %v, want a BareNumberConflict error", err)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// labelQueue approves the open PRs carrying a label across a set of repositories on a
+// cron schedule, removing the label from each PR it approves so the queue drains
+type labelQueue struct {
+	schedule *cronSchedule
+	label    string
+	repos    []RepoTarget
+	github   *GitHubClient
+	config   *Configuration
+	receipts *receiptLog
+	// slack is the Slack client whose pause, freeze, degraded and throttle holds scheduled approvals share
+	slack *SlackClient
+	// running is set while a run is in progress; a run due meanwhile is skipped
+	running atomic.Bool
+}
+
+// labelQueueRun counts what one run of the queue did
+type labelQueueRun struct {
+	Approved int
+	Reviewed int
+	Skipped  int
+	Failed   int
+}
+
+// newLabelQueue creates the queue for cron mode alongside a Slack client's approval
+// engine; it returns nil when cron mode is off
+func newLabelQueue(config *Configuration, slackClient *SlackClient) (*labelQueue, error) {
+	if !config.CronMode {
+		return nil, nil
+	}
+	schedule, err := parseCronSchedule(config.CronSchedule)
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]RepoTarget, 0, len(config.CronRepos))
+	for _, value := range config.CronRepos {
+		repo, err := parseRepoTarget(value)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return &labelQueue{
+		schedule: schedule,
+		label:    config.CronLabel,
+		repos:    repos,
+		github:   slackClient.githubClient,
+		config:   config,
+		receipts: slackClient.receipts,
+		slack:    slackClient,
+	}, nil
+}
+
+// run drains the queue at every scheduled time until ctx is cancelled
+func (lq *labelQueue) run(ctx context.Context) {
+	for {
+		next := lq.schedule.next(time.Now())
+		if next.IsZero() {
+			logError("Cron schedule %q never matches, label queue stopped", lq.config.CronSchedule)
+			return
+		}
+		logDebug("Next label queue run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		go lq.trigger(ctx)
+	}
+}
+
+// trigger starts a run unless the previous one is still going
+func (lq *labelQueue) trigger(ctx context.Context) {
+	if !lq.running.CompareAndSwap(false, true) {
+		logWarn("Label queue run skipped: the previous run is still in progress")
+		return
+	}
+	defer lq.running.Store(false)
+
+	start := time.Now()
+	result := lq.drain(ctx)
+	logInfo("Label queue run finished in %v: approved=%d reviewed=%d skipped=%d failed=%d", time.Since(start).Round(time.Second), result.Approved, result.Reviewed, result.Skipped, result.Failed)
+}
+
+// drain lists the labelled PRs of every repository and approves each in turn
+func (lq *labelQueue) drain(ctx context.Context) labelQueueRun {
+	var result labelQueueRun
+	for _, repo := range lq.repos {
+		numbers, err := lq.github.ListLabeledPRs(ctx, repo.Owner, repo.Repo, lq.label)
+		if err != nil {
+			logError("Failed to list PRs labelled %q in %s/%s: %v", lq.label, repo.Owner, repo.Repo, err)
+			result.Failed++
+			continue
+		}
+		logDebug("Label queue found %d PR(s) labelled %q in %s/%s", len(numbers), lq.label, repo.Owner, repo.Repo)
+
+		for _, number := range numbers {
+			if ctx.Err() != nil {
+				return result
+			}
+			switch lq.approve(ctx, repo, number).Decision {
+			case decisionApproved:
+				result.Approved++
+			case decisionReviewed, decisionFallback:
+				result.Reviewed++
+			case decisionSkipped:
+				result.Skipped++
+			default:
+				result.Failed++
+			}
+		}
+	}
+	return result
+}
+
+// approve validates and approves one queued PR, then takes it off the queue. PRs that
+// fail a gate keep the label and are tried again on the next run.
+func (lq *labelQueue) approve(ctx context.Context, repo RepoTarget, number int) *approvalDecision {
+	req := &ApprovalRequest{
+		Owner:      canonicalRepoName(repo.Owner, lq.config.RepoCase),
+		Repository: canonicalRepoName(repo.Repo, lq.config.RepoCase),
+		PRNumber:   number,
+		Message:    fmt.Sprintf("Approved on schedule from the %q label queue.", lq.label),
+		Timestamp:  time.Now(),
+		Policy:     lq.config.GlobalPolicy(),
+	}
+	ctx = withRequestSource(ctx, "cron")
+
+	decision := newApprovalDecision(req)
+	decision.Channel = "cron"
+	defer logDecision(decision)
+	defer lq.receipts.record(decision)
+
+	// Scheduled approvals count as in flight like Slack ones, and stop while draining;
+	// the PR keeps its label either way
+	if lq.slack.drain.active() {
+		metrics.Inc(metricApprovalsSkipped)
+		decision.Decision, decision.Outcome, decision.Reason, decision.SkipReason = decisionSkipped, outcomeDraining, "instance is draining", SkipDraining
+		return decision
+	}
+	if !lq.slack.inFlight.acquire() {
+		metrics.Inc(metricApprovalsRejected)
+		metrics.Inc(metricApprovalsSkipped)
+		decision.Decision, decision.Outcome, decision.Reason, decision.SkipReason = decisionSkipped, outcomeOverloaded, fmt.Sprintf("%d approvals already in flight", lq.config.MaxInFlightApprovals), SkipOverloaded
+		return decision
+	}
+	defer lq.slack.inFlight.release()
+
+	// A held PR keeps its label, so the next run tries it again
+	if hold := lq.slack.immediateHold(""); hold != nil {
+		metrics.Inc(metricApprovalsSkipped)
+		decision.Decision, decision.Outcome, decision.Reason, decision.SkipReason = decisionSkipped, hold.outcome, hold.reason, hold.skip
+		return decision
+	}
+
+	err := lq.github.ValidatePRReference(ctx, req.Owner, req.Repository, req.PRNumber, req.Policy)
+	if err == nil {
+		err = lq.github.checkExternalPolicy(ctx, req)
+	}
+	if err != nil {
+		metrics.Inc(metricApprovalsSkipped)
+		decision.Decision, decision.Outcome, decision.Reason = decisionSkipped, outcomeNone, err.Error()
+		if outcome := skipOutcome(err); outcome != "" {
+			decision.Outcome = outcome
+		}
+		return decision
+	}
+
+	result, err := lq.github.ApprovePRWithRetry(ctx, req)
+	if err != nil {
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision, decision.Outcome, decision.Reason = decisionFailed, outcomeFailed, err.Error()
+		return decision
+	}
+	decision.Retries = result.RetryAttempts
+	if !result.Success {
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision, decision.Outcome, decision.Reason = decisionFailed, outcomeFailed, result.Error
+		return decision
+	}
+	reviewDecision(decision, result)
+
+	// Without the label the PR isn't reviewed again on the next run, even when the
+	// review was a comment rather than an approval
+	if err := lq.github.RemoveLabel(ctx, req.Owner, req.Repository, req.PRNumber, lq.label); err != nil {
+		logWarn("Approved PR %s/%s#%d but failed to remove label %q: %v", req.Owner, req.Repository, req.PRNumber, lq.label, err)
+	}
+	return decision
+}
+
+// ListLabeledPRs returns the numbers of the open PRs in a repository that carry a label
+func (gc *GitHubClient) ListLabeledPRs(ctx context.Context, owner, repo, label string) ([]int, error) {
+	options := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{label},
+		ListOptions: github.ListOptions{PerPage: gc.perPage()},
+	}
+
+	var numbers []int
+	for {
+		issues, response, err := gc.client.Issues.ListByRepo(ctx, owner, repo, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			// The issues API lists PRs too; plain issues have no pull request links
+			if issue.IsPullRequest() {
+				numbers = append(numbers, issue.GetNumber())
+			}
+		}
+		if response.NextPage == 0 {
+			return numbers, nil
+		}
+		options.ListOptions.Page = response.NextPage
+	}
+}
+
+// RemoveLabel removes a label from a PR; a label that is already gone is not an error
+func (gc *GitHubClient) RemoveLabel(ctx context.Context, owner, repo string, prNumber int, label string) error {
+	response, err := gc.client.Issues.RemoveLabelForIssue(ctx, owner, repo, prNumber, label)
+	if err != nil && response != nil && response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
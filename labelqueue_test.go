@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// labelRemoval is the request taking the queue label off PR o/r#1
+const labelRemoval = "DELETE /repos/o/r/issues/1/labels/queue"
+
+// newTestLabelQueue returns a label queue draining the "queue" label of o/r against gh
+func newTestLabelQueue(t *testing.T, config *Configuration, gh *fakeGitHub) (*labelQueue, *SlackClient) {
+	t.Helper()
+	config.CronMode, config.CronSchedule, config.CronLabel, config.CronRepos = true, "0 * * * *", "queue", []string{"o/r"}
+	if gh.routes == nil {
+		gh.routes = map[string]interface{}{}
+	}
+	if _, ok := gh.routes["GET /repos/o/r/issues"]; !ok {
+		gh.routes["GET /repos/o/r/issues"] = []map[string]interface{}{
+			{"number": 1, "pull_request": map[string]string{"url": "https://api.github.com/repos/o/r/pulls/1"}},
+			{"number": 2},
+		}
+	}
+	gh.routes[labelRemoval] = []interface{}{}
+
+	sc, _ := newTestSlackClient(t, config, gh)
+	lq, err := newLabelQueue(config, sc)
+	if err != nil {
+		t.Fatalf("newLabelQueue: %v", err)
+	}
+	return lq, sc
+}
+
+// requestCount counts the requests gh received for request
+func requestCount(gh *fakeGitHub, request string) int {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	count := 0
+	for _, r := range gh.requests {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestNewLabelQueueDisabled(t *testing.T) {
+	lq, err := newLabelQueue(&Configuration{}, nil)
+	if lq != nil || err != nil {
+		t.Errorf("newLabelQueue = %v, %v, want nil without cron mode", lq, err)
+	}
+}
+
+func TestLabelQueueDrain(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Configuration
+		gh          *fakeGitHub
+		draining    bool
+		want        labelQueueRun
+		wantRemoved bool
+	}{
+		{name: "approved", gh: &fakeGitHub{}, want: labelQueueRun{Approved: 1}, wantRemoved: true},
+		{name: "comment review", config: Configuration{ReviewEvent: "COMMENT"}, gh: &fakeGitHub{}, want: labelQueueRun{Reviewed: 1}, wantRemoved: true},
+		{name: "policy failed", config: Configuration{SkipDrafts: true}, gh: &fakeGitHub{draft: true}, want: labelQueueRun{Skipped: 1}},
+		{name: "draining", gh: &fakeGitHub{}, draining: true, want: labelQueueRun{Skipped: 1}},
+		{name: "review refused", gh: &fakeGitHub{reviewStatus: http.StatusUnprocessableEntity, reviewError: "Validation Failed"}, want: labelQueueRun{Failed: 1}},
+		{name: "list failed", gh: &fakeGitHub{failures: map[string]int{"GET /repos/o/r/issues": http.StatusInternalServerError}}, want: labelQueueRun{Failed: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			lq, sc := newTestLabelQueue(t, &config, tt.gh)
+			if tt.draining {
+				sc.drain.start()
+			}
+
+			if got := lq.drain(context.Background()); got != tt.want {
+				t.Errorf("drain = %+v, want %+v", got, tt.want)
+			}
+			if removed := requestCount(tt.gh, labelRemoval) == 1; removed != tt.wantRemoved {
+				t.Errorf("label removed %v, want %v", removed, tt.wantRemoved)
+			}
+			if sc.inFlight.count() != 0 {
+				t.Errorf("%d approval(s) still in flight after the run", sc.inFlight.count())
+			}
+		})
+	}
+}
+
+func TestLabelQueueSkipsOverlappingRun(t *testing.T) {
+	gh := &fakeGitHub{}
+	lq, _ := newTestLabelQueue(t, &Configuration{}, gh)
+	lq.running.Store(true)
+
+	lq.trigger(context.Background())
+	if got := requestCount(gh, "GET /repos/o/r/issues"); got != 0 {
+		t.Errorf("listed the queue %d times while a run was in progress, want none", got)
+	}
+}
+
+func TestRemoveLabelAlreadyGone(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{labelRemoval: http.StatusNotFound}}
+	gc := newTestGitHubClient(t, &Configuration{}, gh.ServeHTTP)
+	if err := gc.RemoveLabel(context.Background(), "o", "r", 1, "queue"); err != nil {
+		t.Errorf("RemoveLabel = %v, want a missing label to be no error", err)
+	}
+
+	gh.failures[labelRemoval] = http.StatusForbidden
+	if err := gc.RemoveLabel(context.Background(), "o", "r", 1, "queue"); err == nil {
+		t.Error("RemoveLabel succeeded, want the permission error")
+	}
+}
+
+func TestCronModeValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantField string
+	}{
+		{name: "valid", args: []string{"--cron-mode", "--cron-label", "queue", "--cron-repo", "o/r"}},
+		{name: "bad schedule", args: []string{"--cron-mode", "--cron-schedule", "* * *", "--cron-label", "queue", "--cron-repo", "o/r"}, wantField: "CronSchedule"},
+		{name: "no label", args: []string{"--cron-mode", "--cron-repo", "o/r"}, wantField: "CronLabel"},
+		{name: "no repos", args: []string{"--cron-mode", "--cron-label", "queue"}, wantField: "CronRepos"},
+		{name: "bad repo", args: []string{"--cron-mode", "--cron-label", "queue", "--cron-repo", "o"}, wantField: "CronRepos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfiguration(validConfig(t, tt.args...))
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("validateConfiguration = %v, want no error", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != tt.wantField {
+				t.Errorf("validateConfiguration = %v, want a %s error", err, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// closingKeywordPattern matches GitHub's closing keywords followed by an issue reference:
+// #12, owner/repo#12 or a full issue URL, e.g. "Fixes #12" or "closes: org/app#3"
+var closingKeywordPattern = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:([\w.-]+)/([\w.-]+)#|https?://github\.com/([\w.-]+)/([\w.-]+)/issues/|#)(\d+)\b`)
+
+// issueReference is an issue a PR closes
+type issueReference struct {
+	Owner      string
+	Repository string
+	Number     int
+}
+
+// linkedIssues returns the issues a PR description closes, with bare #12 references
+// resolved against the PR's own repository
+func linkedIssues(body, owner, repo string) []issueReference {
+	var refs []issueReference
+	for _, match := range closingKeywordPattern.FindAllStringSubmatch(body, -1) {
+		number, err := strconv.Atoi(match[5])
+		if err != nil {
+			continue
+		}
+
+		ref := issueReference{Owner: owner, Repository: repo, Number: number}
+		switch {
+		case match[1] != "":
+			ref.Owner, ref.Repository = match[1], match[2]
+		case match[3] != "":
+			ref.Owner, ref.Repository = match[3], match[4]
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// checkLinkedIssue fails for PRs whose description doesn't close an issue. With
+// VerifyLinkedIssue, at least one referenced issue must also exist and not be a PR.
+func (gc *GitHubClient) checkLinkedIssue(ctx context.Context, pr *github.PullRequest) error {
+	owner := pr.GetBase().GetRepo().GetOwner().GetLogin()
+	repo := pr.GetBase().GetRepo().GetName()
+
+	refs := linkedIssues(pr.GetBody(), owner, repo)
+	if len(refs) == 0 {
+		return &PolicyError{Policy: "linked-issue", Message: fmt.Sprintf("PR #%d does not close an issue with a keyword such as \"Fixes #123\"", pr.GetNumber())}
+	}
+	if !gc.config.VerifyLinkedIssue {
+		return nil
+	}
+
+	for _, ref := range refs {
+		issue, response, err := gc.client.Issues.Get(ctx, ref.Owner, ref.Repository, ref.Number)
+		if err != nil {
+			if response != nil && response.StatusCode == http.StatusNotFound {
+				logDebug("PR #%d links %s/%s#%d, which does not exist", pr.GetNumber(), ref.Owner, ref.Repository, ref.Number)
+				continue
+			}
+			return fmt.Errorf("failed to get issue %s/%s#%d linked from PR #%d: %v", ref.Owner, ref.Repository, ref.Number, pr.GetNumber(), err)
+		}
+		if !issue.IsPullRequest() {
+			return nil
+		}
+	}
+
+	return &PolicyError{Policy: "linked-issue", Message: fmt.Sprintf("PR #%d references no existing issue in its closing keywords", pr.GetNumber())}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLinkedIssues(t *testing.T) {
+	tests := []struct {
+		body string
+		want []issueReference
+	}{
+		{body: "Fixes #12", want: []issueReference{{Owner: "o", Repository: "r", Number: 12}}},
+		{body: "closes: org/app#3", want: []issueReference{{Owner: "org", Repository: "app", Number: 3}}},
+		{body: "Resolves https://github.com/org/app/issues/7", want: []issueReference{{Owner: "org", Repository: "app", Number: 7}}},
+		{body: "This fixed #1 and closes #2", want: []issueReference{{Owner: "o", Repository: "r", Number: 1}, {Owner: "o", Repository: "r", Number: 2}}},
+		{body: "Related to #12"},
+		{body: "prefixes #12"},
+		{body: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := linkedIssues(tt.body, "o", "r"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("linkedIssues(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkedIssueGate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		verify  bool
+		routes  map[string]interface{}
+		wantErr bool
+	}{
+		{name: "closes an issue", body: "Fixes #12"},
+		{name: "no closing keyword", body: "Adds a button", wantErr: true},
+		{name: "verified issue", body: "Fixes #12", verify: true, routes: map[string]interface{}{"GET /repos/o/r/issues/12": map[string]int{"number": 12}}},
+		{name: "missing issue", body: "Fixes #12", verify: true, wantErr: true},
+		{
+			name:    "references a PR",
+			body:    "Fixes #12",
+			verify:  true,
+			routes:  map[string]interface{}{"GET /repos/o/r/issues/12": map[string]interface{}{"number": 12, "pull_request": map[string]string{"url": "https://api.github.com/repos/o/r/pulls/12"}}},
+			wantErr: true,
+		},
+		{
+			name:   "one of several exists",
+			body:   "Fixes #11, fixes #12",
+			verify: true,
+			routes: map[string]interface{}{"GET /repos/o/r/issues/12": map[string]int{"number": 12}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{body: tt.body, routes: tt.routes}
+
+			err := validatePR(t, &Configuration{RequireLinkedIssue: true, VerifyLinkedIssue: tt.verify}, gh)
+			if got := failedPolicy(err) == "linked-issue"; got != tt.wantErr {
+				t.Errorf("ValidatePRReference = %v, want no linked issue %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+		})
+	}
+}
+
+func TestLinkedIssueLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{body: "Fixes #12", failures: map[string]int{"GET /repos/o/r/issues/12": http.StatusForbidden}}
+
+	err := validatePR(t, &Configuration{RequireLinkedIssue: true, VerifyLinkedIssue: true}, gh)
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want a lookup error", err)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestApprovalFailuresError(t *testing.T) {
+	tests := []struct {
+		failOnError bool
+		failed      int64
+		wantErr     bool
+	}{
+		{false, 0, false},
+		{false, 3, false},
+		{true, 0, false},
+		{true, 1, true},
+	}
+
+	for _, tt := range tests {
+		err := approvalFailuresError(&Configuration{FailOnApprovalError: tt.failOnError}, tt.failed)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("approvalFailuresError(fail=%v, failed=%d) = %v, want error %v", tt.failOnError, tt.failed, err, tt.wantErr)
+		}
+	}
+}
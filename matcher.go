@@ -8,25 +8,121 @@ import (
 	"strings"
 )
 
+// Match modes for matchers with several patterns
+const (
+	// MatchModeFirst stops at the first matching pattern
+	MatchModeFirst = "first"
+	// MatchModeAll evaluates every pattern and records which ones matched
+	MatchModeAll = "all"
+	// MatchModeCombined joins all patterns into one alternation and matches in a single pass
+	MatchModeCombined = "combined"
+)
+
 // PatternMatcher handles message pattern matching
 type PatternMatcher struct {
-	pattern *regexp.Regexp
+	patterns []*regexp.Regexp
+	mode     string
+	// intents turns a matching message into the action and PRs it asks for
+	intents *IntentParser
 }
 
 // NewPatternMatcher creates a new pattern matcher with compiled regex
 func NewPatternMatcher(pattern string) (*PatternMatcher, error) {
-	if pattern == "" {
-		pattern = ".*" // Match all messages by default
+	return NewMultiPatternMatcher([]string{pattern}, MatchModeFirst)
+}
+
+// NewMultiPatternMatcher creates a pattern matcher that matches any of several patterns
+func NewMultiPatternMatcher(patterns []string, mode string) (*PatternMatcher, error) {
+	if len(patterns) == 0 {
+		patterns = []string{".*"} // Match all messages by default
+	}
+	
+	if mode == "" {
+		mode = MatchModeFirst
+	}
+	
+	var sources []string
+	for _, pattern := range patterns {
+		if pattern == "" {
+			pattern = ".*" // Match all messages by default
+		}
+		sources = append(sources, pattern)
+	}
+	
+	// A single alternation scans the message once however many patterns there are,
+	// at the cost of not knowing which pattern matched
+	if mode == MatchModeCombined && len(sources) > 1 {
+		groups := make([]string, len(sources))
+		for i, source := range sources {
+			groups[i] = "(?:" + source + ")"
+		}
+		sources = []string{strings.Join(groups, "|")}
+	}
+	
+	pm := &PatternMatcher{mode: mode, intents: NewIntentParser()}
+	for _, source := range sources {
+		compiledPattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, err
+		}
+		pm.patterns = append(pm.patterns, compiledPattern)
 	}
 	
-	compiledPattern, err := regexp.Compile(pattern)
+	return pm, nil
+}
+
+// messagePatterns returns the configured patterns, the main pattern first
+func (config *Configuration) messagePatterns() []string {
+	return append([]string{config.MessagePattern}, config.ExtraPatterns...)
+}
+
+// parseChannelPatterns parses channel=pattern pairs; a channel may be given several patterns
+func parseChannelPatterns(values []string) (map[string][]string, error) {
+	pairs, err := parseKeyValues(values, "ChannelPatterns", "channel pattern", "channel=pattern")
 	if err != nil {
 		return nil, err
 	}
-	
-	return &PatternMatcher{
-		pattern: compiledPattern,
-	}, nil
+	channelPatterns := make(map[string][]string)
+	for _, pair := range pairs {
+		channelPatterns[pair.key] = append(channelPatterns[pair.key], pair.value)
+	}
+	return channelPatterns, nil
+}
+
+// SetRepoAliases configures the aliases resolved in alias#123 references
+func (pm *PatternMatcher) SetRepoAliases(aliases map[string]RepoTarget) {
+	pm.intents.aliases = aliases
+}
+
+// SetResolveCommits enables extracting commit URLs as references to their pull requests
+func (pm *PatternMatcher) SetResolveCommits(enabled bool) {
+	pm.intents.commits = enabled
+}
+
+// SetResolveBranchLinks enables extracting blob and tree links as references to the
+// pull requests of their branches
+func (pm *PatternMatcher) SetResolveBranchLinks(enabled bool) {
+	pm.intents.branches = enabled
+}
+
+// SetBareNumberConflict configures how a bare number that a link in the same message also names is read
+func (pm *PatternMatcher) SetBareNumberConflict(mode string) {
+	pm.intents.bareNumbers = mode
+}
+
+// SetPerReferenceActions enables action keywords that pick the review event per reference
+func (pm *PatternMatcher) SetPerReferenceActions(enabled bool) {
+	pm.intents.actions = enabled
+}
+
+// SetRepoCase configures how owner and repository names in references are normalized
+func (pm *PatternMatcher) SetRepoCase(repoCase string) {
+	pm.intents.repoCase = repoCase
+}
+
+// ExtractPRReferences finds GitHub PR references in text
+func (pm *PatternMatcher) ExtractPRReferences(text string) ([]PRReference, error) {
+	return pm.intents.ExtractPRReferences(text)
 }
 
 // PatternMatch represents a successful pattern match
@@ -35,6 +131,12 @@ type PatternMatch struct {
 	MatchedText   string
 	PRReferences  []PRReference
 	SourceMessage *SlackMessage
+	// MatchedPatterns lists every pattern that matched in MatchModeAll
+	MatchedPatterns []string
+	// TriggerReaction is the emoji that triggered the match in reaction-trigger mode
+	TriggerReaction string
+	// Intent is the structured request parsed from the message; PRReferences mirrors its references
+	Intent *ApprovalIntent
 }
 
 // PRReference represents a GitHub pull request reference
@@ -43,6 +145,26 @@ type PRReference struct {
 	Repository string
 	Number     int
 	URL        string
+	// CommitSHA is set instead of Number for a commit reference whose PR is not yet known
+	CommitSHA string
+	// Branch is set instead of Number for a blob or tree link whose PR is not yet known. It is
+	// the link's ref followed by its path, e.g. feature/login/main.go, as branches may contain slashes.
+	Branch string
+	// Action is the review event requested for this reference, empty for the policy's default
+	Action string
+}
+
+// linked reports whether a reference is a commit or file link still to be resolved to its PR
+func (ref PRReference) linked() bool {
+	return ref.CommitSHA != "" || ref.Branch != ""
+}
+
+// linkDescription names a commit or file link in log lines and replies
+func (ref PRReference) linkDescription() string {
+	if ref.CommitSHA != "" {
+		return "commit " + ref.CommitSHA
+	}
+	return "link " + ref.URL
 }
 
 // SlackMessage represents a Slack message
@@ -52,98 +174,152 @@ type SlackMessage struct {
 	User      string
 	Timestamp string
 	ThreadTS  string
+	// Team and Enterprise carry the workspace context on Enterprise Grid; either may be empty
+	Team       string
+	Enterprise string
 }
 
 // Match tests if a message matches the configured pattern
 func (pm *PatternMatcher) Match(message string) (*PatternMatch, error) {
-	if !pm.pattern.MatchString(message) {
-		return nil, nil // No match
+	var patternMatch *PatternMatch
+	var matched []*regexp.Regexp
+	var start int
+	
+	for _, pattern := range pm.patterns {
+		// Find the matched substring
+		loc := pattern.FindStringIndex(message)
+		if loc == nil {
+			continue
+		}
+		
+		if patternMatch == nil {
+			// Create pattern match from the first matching pattern
+			patternMatch = &PatternMatch{
+				Pattern:     pattern.String(),
+				MatchedText: message[loc[0]:loc[1]],
+			}
+			start = loc[0]
+		}
+		patternMatch.MatchedPatterns = append(patternMatch.MatchedPatterns, pattern.String())
+		matched = append(matched, pattern)
+		
+		if pm.mode != MatchModeAll {
+			break
+		}
 	}
 	
-	// Find the matched substring
-	matchedText := pm.pattern.FindString(message)
+	if patternMatch == nil {
+		return nil, nil // No match
+	}
 	
-	// Create pattern match and extract PR references
-	patternMatch := &PatternMatch{
-		Pattern:     pm.pattern.String(),
-		MatchedText: matchedText,
+	// Patterns with a named number group say exactly where the PR is, so the
+	// URL and number heuristics are only used when none of the matches have one
+	var namedRefs []PRReference
+	for _, pattern := range matched {
+		if hasNamedPRGroups(pattern) {
+			namedRefs = appendUniqueReferences(namedRefs, namedGroupReferences(pattern, message))
+		}
+	}
+	if namedRefs != nil {
+		patternMatch.Intent = pm.intents.ParseWithReferences(message, namedRefs)
+		patternMatch.Intent.Negated = negatesTrigger(message, start)
+		patternMatch.PRReferences = patternMatch.Intent.References
+		return patternMatch, nil
 	}
 	
-	// Extract PR references from the entire message (not just matched text)
-	prRefs, err := pm.ExtractPRReferences(message)
+	// What the message asks for is read from the entire message (not just matched text)
+	intent, err := pm.intents.Parse(message)
 	if err != nil {
 		return nil, err
 	}
-	patternMatch.PRReferences = prRefs
+	intent.Negated = negatesTrigger(message, start)
+	patternMatch.Intent = intent
+	patternMatch.PRReferences = intent.References
 	
 	return patternMatch, nil
 }
 
-// ExtractPRReferences finds GitHub PR references in text
-func (pm *PatternMatcher) ExtractPRReferences(text string) ([]PRReference, error) {
-	var references []PRReference
-	
-	// GitHub PR URL pattern: https://github.com/owner/repo/pull/123 (matches your bash script)
-	prURLPattern := regexp.MustCompile(`https?://github\.com/([^[:space:]/]+)/([^[:space:]/]+)/pull/([0-9]+)`)
-	
-	// Simple PR number pattern: #123, PR-456, PR #123
-	prNumberPattern := regexp.MustCompile(`(?:#|PR-?)\s*(\d+)`)
+// hasNamedPRGroups reports whether a pattern captures the PR number in a (?P<number>...) group
+func hasNamedPRGroups(pattern *regexp.Regexp) bool {
+	return pattern.SubexpIndex("number") >= 0
+}
+
+// validateNamedGroups checks that a pattern capturing owner or repo also captures the number
+func validateNamedGroups(pattern *regexp.Regexp) error {
+	if hasNamedPRGroups(pattern) {
+		return nil
+	}
+	if pattern.SubexpIndex("owner") >= 0 || pattern.SubexpIndex("repo") >= 0 {
+		return fmt.Errorf("pattern has an owner or repo group but no (?P<number>...) group")
+	}
+	return nil
+}
+
+// namedGroupReferences builds PR references from the owner, repo and number groups of every
+// match. Missing owner or repo are filled from configuration later. When the same name is
+// used more than once, e.g. in combined mode, the first group that participated wins.
+func namedGroupReferences(pattern *regexp.Regexp, message string) []PRReference {
+	names := pattern.SubexpNames()
+	refs := []PRReference{}
 	
-	// Extract full URLs first
-	urlMatches := prURLPattern.FindAllStringSubmatch(text, -1)
-	for _, match := range urlMatches {
-		if len(match) == 4 {
-			number, err := strconv.Atoi(match[3])
-			if err != nil {
-				continue
-			}
-			
-			// Validate the URL format
-			if err := validateGitHubURL(match[0]); err != nil {
-				continue
+	for _, submatch := range pattern.FindAllStringSubmatch(message, -1) {
+		groups := make(map[string]string)
+		for i, name := range names {
+			if name != "" && submatch[i] != "" && groups[name] == "" {
+				groups[name] = submatch[i]
 			}
-			
-			references = append(references, PRReference{
-				Owner:      match[1],
-				Repository: match[2],
-				Number:     number,
-				URL:        match[0],
-			})
 		}
+		
+		if groups["number"] == "" {
+			continue
+		}
+		number, err := strconv.Atoi(groups["number"])
+		if err != nil {
+			logWarn("Ignoring named group number %q: not an integer", groups["number"])
+			continue
+		}
+		
+		ref := PRReference{
+			Owner:      groups["owner"],
+			Repository: groups["repo"],
+			Number:     number,
+		}
+		if ref.Owner != "" && ref.Repository != "" {
+			ref.URL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", ref.Owner, ref.Repository, number)
+		}
+		refs = appendUniqueReferences(refs, []PRReference{ref})
 	}
 	
-	// Extract simple PR numbers (these will need default owner/repo)
-	numberMatches := prNumberPattern.FindAllStringSubmatch(text, -1)
-	for _, match := range numberMatches {
-		if len(match) == 2 {
-			number, err := strconv.Atoi(match[1])
-			if err != nil {
-				continue
-			}
-			
-			// Only add if we don't already have this PR from a URL
-			alreadyExists := false
-			for _, existing := range references {
-				if existing.Number == number {
-					alreadyExists = true
-					break
-				}
-			}
-			
-			if !alreadyExists {
-				references = append(references, PRReference{
-					Number: number,
-					// Owner and Repository will need to be filled from config
-				})
+	return refs
+}
+
+// appendUniqueReferences appends the references not already present
+func appendUniqueReferences(refs, more []PRReference) []PRReference {
+	if refs == nil {
+		refs = []PRReference{}
+	}
+	for _, ref := range more {
+		duplicate := false
+		for _, existing := range refs {
+			if existing.Owner == ref.Owner && existing.Repository == ref.Repository && existing.Number == ref.Number {
+				duplicate = true
+				break
 			}
 		}
+		if !duplicate {
+			refs = append(refs, ref)
+		}
 	}
-	
-	return references, nil
+	return refs
 }
 
-// validateGitHubURL validates that a URL is a proper GitHub URL
+// validateGitHubURL validates that a URL is a proper GitHub URL.
+// Links without a scheme are treated as HTTPS; query strings and fragments are allowed.
 func validateGitHubURL(urlStr string) error {
+	if !strings.Contains(urlStr, "://") {
+		urlStr = "https://" + urlStr
+	}
+	
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return err
@@ -153,8 +329,9 @@ func validateGitHubURL(urlStr string) error {
 		return fmt.Errorf("only HTTPS URLs are supported")
 	}
 	
-	if !strings.EqualFold(parsedURL.Host, "github.com") {
-		return err
+	host := strings.TrimPrefix(strings.ToLower(parsedURL.Host), "www.")
+	if host != "github.com" {
+		return fmt.Errorf("unsupported host %q", parsedURL.Host)
 	}
 	
 	return nil
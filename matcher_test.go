@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExtractPRReferencesWithLinkSuffixes(t *testing.T) {
+	matcher, err := NewPatternMatcher(`(?i)\blgtm\b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		"lgtm https://github.com/o/r/pull/5",
+		"lgtm https://github.com/o/r/pull/5?diff=split",
+		"lgtm https://github.com/o/r/pull/5#discussion_r123",
+		"lgtm https://github.com/o/r/pull/5/files?diff=split#discussion_r123",
+		"lgtm <https://github.com/o/r/pull/5?diff=split|o/r#5>",
+		"lgtm github.com/o/r/pull/5?diff=split",
+		"lgtm www.github.com/o/r/pull/5#discussion_r123",
+	}
+
+	for _, text := range tests {
+		refs, err := matcher.ExtractPRReferences(text)
+		if err != nil {
+			t.Errorf("ExtractPRReferences(%q): %v", text, err)
+			continue
+		}
+		if len(refs) != 1 {
+			t.Errorf("ExtractPRReferences(%q) = %+v, want only o/r#5", text, refs)
+			continue
+		}
+		ref := refs[0]
+		if ref.Owner != "o" || ref.Repository != "r" || ref.Number != 5 || ref.URL != "https://github.com/o/r/pull/5" {
+			t.Errorf("ExtractPRReferences(%q) = %+v, want o/r#5", text, ref)
+		}
+	}
+}
+
+func TestValidateGitHubURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://github.com/o/r/pull/5?diff=split", false},
+		{"https://github.com/o/r/pull/5#discussion_r123", false},
+		{"github.com/o/r/pull/5", false},
+		{"https://www.github.com/o/r/pull/5", false},
+		{"http://github.com/o/r/pull/5", true},
+		{"https://gitlab.com/o/r/pull/5", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateGitHubURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("validateGitHubURL(%q) = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+// benchmarkPatterns are twenty patterns of which only the last matches benchmarkMessage
+var benchmarkPatterns = func() []string {
+	patterns := make([]string, 0, 20)
+	for i := 0; i < 19; i++ {
+		patterns = append(patterns, fmt.Sprintf(`(?i)\bship-%d\b`, i))
+	}
+	return append(patterns, `(?i)\blgtm\b`)
+}()
+
+// benchmarkMessage is a long message with a PR link and the trigger at the end
+var benchmarkMessage = strings.Repeat("some discussion about the change ", 30) + "https://github.com/o/r/pull/5 lgtm"
+
+func benchmarkMatch(b *testing.B, patterns []string, mode string) {
+	matcher, err := NewMultiPatternMatcher(patterns, mode)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if match, err := matcher.Match(benchmarkMessage); err != nil || match == nil {
+			b.Fatalf("Match = %v, %v, want a match", match, err)
+		}
+	}
+}
+
+func BenchmarkMatchSinglePattern(b *testing.B) {
+	benchmarkMatch(b, []string{`(?i)\blgtm\b`}, MatchModeFirst)
+}
+
+func BenchmarkMatchFirst(b *testing.B) {
+	benchmarkMatch(b, benchmarkPatterns, MatchModeFirst)
+}
+
+func BenchmarkMatchAll(b *testing.B) {
+	benchmarkMatch(b, benchmarkPatterns, MatchModeAll)
+}
+
+func BenchmarkMatchCombined(b *testing.B) {
+	benchmarkMatch(b, benchmarkPatterns, MatchModeCombined)
+}
+
+func TestNamedGroupReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		message string
+		want    []PRReference
+	}{
+		{
+			name:    "owner, repo and number",
+			pattern: `ship (?P<owner>\w+)/(?P<repo>\w+) (?P<number>\d+)`,
+			message: "ship o/r 12",
+			want:    []PRReference{{Owner: "o", Repository: "r", Number: 12, URL: "https://github.com/o/r/pull/12"}},
+		},
+		{
+			name:    "number only",
+			pattern: `deploy #(?P<number>\d+)`,
+			message: "deploy #7 please",
+			want:    []PRReference{{Number: 7}},
+		},
+		{
+			name:    "every match",
+			pattern: `deploy #(?P<number>\d+)`,
+			message: "deploy #7 and deploy #8 and deploy #7",
+			want:    []PRReference{{Number: 7}, {Number: 8}},
+		},
+		{
+			name:    "number out of range",
+			pattern: `deploy #(?P<number>\d+)`,
+			message: "deploy #99999999999999999999",
+			want:    []PRReference{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := namedGroupReferences(regexp.MustCompile(tt.pattern), tt.message)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("namedGroupReferences = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamedGroupsWinOverURLs(t *testing.T) {
+	matcher, err := NewPatternMatcher(`ship o/r (?P<number>\d+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := matcher.Match("ship o/r 12, see https://github.com/o/r/pull/5")
+	if err != nil {
+		t.Fatalf("Match: %v", err)
+	}
+	if match == nil || len(match.PRReferences) != 1 || match.PRReferences[0].Number != 12 {
+		t.Errorf("Match = %+v, want only PR 12", match)
+	}
+}
+
+func TestValidateNamedGroups(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: `(?i)\blgtm\b`},
+		{pattern: `(?P<owner>\w+)/(?P<repo>\w+)#(?P<number>\d+)`},
+		{pattern: `#(?P<number>\d+)`},
+		{pattern: `(?P<owner>\w+)/(?P<repo>\w+)`, wantErr: true},
+		{pattern: `in (?P<repo>\w+)`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := validateNamedGroups(regexp.MustCompile(tt.pattern))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateNamedGroups = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNamedGroupPatternValidation(t *testing.T) {
+	config := validConfig(t, "--slack-pattern", `ship (?P<repo>\w+)`)
+	err := validateConfiguration(config)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "MessagePattern" {
+		t.Errorf("validateConfiguration = %v, want a MessagePattern error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// Defaults for waiting on GitHub's asynchronously computed mergeable state
+const (
+	defaultMergeableStateTimeout  = 6 * time.Second
+	defaultMergeableStateInterval = time.Second
+)
+
+// mergeableStateKnown reports whether GitHub has finished computing a PR's mergeability;
+// until then mergeable is null and mergeable_state is empty or "unknown"
+func mergeableStateKnown(pr *github.PullRequest) bool {
+	state := pr.GetMergeableState()
+	return pr.Mergeable != nil && state != "" && state != "unknown"
+}
+
+// needsMergeableState reports whether an enabled gate reads the mergeable state
+func needsMergeableState(gates []prGate) bool {
+	for _, gate := range gates {
+		if gate.Enabled && gate.Name == "up-to-date" {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitMergeableState re-fetches an open PR with exponential backoff until GitHub has
+// computed its mergeable state or MergeableStateTimeout passes, returning the latest
+// copy. A PR whose state is still unknown at the deadline is returned as is.
+func (gc *GitHubClient) awaitMergeableState(ctx context.Context, owner, repo string, pr *github.PullRequest) (*github.PullRequest, error) {
+	if pr.GetState() != "open" || mergeableStateKnown(pr) {
+		return pr, nil
+	}
+
+	timeout := gc.config.MergeableStateTimeout
+	if timeout <= 0 {
+		timeout = defaultMergeableStateTimeout
+	}
+	delay := gc.config.MergeableStateInterval
+	if delay <= 0 {
+		delay = defaultMergeableStateInterval
+	}
+	deadline := time.Now().Add(timeout)
+
+	for attempt := 1; ; attempt++ {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			logDebug("Mergeable state of PR %s/%s#%d still unknown after %v", owner, repo, pr.GetNumber(), timeout)
+			return pr, nil
+		}
+
+		select {
+		case <-time.After(min(delay, remaining)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		refreshed, err := gc.getPR(ctx, owner, repo, pr.GetNumber())
+		if err != nil {
+			return nil, err
+		}
+		pr = refreshed
+		if mergeableStateKnown(pr) {
+			logDebug("Mergeable state of PR %s/%s#%d computed after %d re-fetch(es): %s", owner, repo, pr.GetNumber(), attempt, pr.GetMergeableState())
+			return pr, nil
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+func TestMergeableStateKnown(t *testing.T) {
+	tests := []struct {
+		name      string
+		mergeable *bool
+		state     string
+		want      bool
+	}{
+		{name: "computed", mergeable: github.Ptr(true), state: "clean", want: true},
+		{name: "behind", mergeable: github.Ptr(true), state: "behind", want: true},
+		{name: "null mergeable", state: "clean"},
+		{name: "empty state", mergeable: github.Ptr(false)},
+		{name: "unknown state", mergeable: github.Ptr(false), state: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &github.PullRequest{Mergeable: tt.mergeable, MergeableState: github.Ptr(tt.state)}
+			if got := mergeableStateKnown(pr); got != tt.want {
+				t.Errorf("mergeableStateKnown = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// computingPR serves a PR whose mergeable state is null for the first fetches,
+// then state. A failing fetch answers with failStatus.
+type computingPR struct {
+	gh         *fakeGitHub
+	nullFor    int
+	state      string
+	failStatus int
+	fetches    int
+}
+
+// serve answers the PR fetches and hands everything else to the fake GitHub
+func (c *computingPR) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls/1" {
+		c.fetches++
+		if c.fetches > 1 && c.failStatus != 0 {
+			writeJSON(w, c.failStatus, map[string]string{"message": http.StatusText(c.failStatus)})
+			return
+		}
+		if c.fetches > c.nullFor {
+			c.gh.mergeableState = c.state
+		}
+	}
+	c.gh.ServeHTTP(w, r)
+}
+
+func TestMergeableStateIsAwaited(t *testing.T) {
+	tests := []struct {
+		name        string
+		pr          *computingPR
+		requireUp   bool
+		wantPolicy  string
+		wantFetches int
+		wantCompare bool
+	}{
+		{name: "computed after re-fetching", pr: &computingPR{nullFor: 2, state: "behind"}, requireUp: true, wantPolicy: "up-to-date", wantFetches: 3},
+		{name: "computed on the first fetch", pr: &computingPR{state: "clean"}, requireUp: true, wantFetches: 1},
+		{name: "still unknown at the deadline", pr: &computingPR{nullFor: 1 << 30}, requireUp: true, wantCompare: true},
+		{name: "no gate needs it", pr: &computingPR{nullFor: 1 << 30}, wantFetches: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pr.gh = &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/compare/main...abc123": map[string]int{"behind_by": 0},
+			}}
+			config := &Configuration{RequireUpToDate: tt.requireUp, MergeableStateTimeout: 50 * time.Millisecond, MergeableStateInterval: time.Millisecond}
+			gc := newTestGitHubClient(t, config, tt.pr.serve)
+
+			err := gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy())
+			if got := failedPolicy(err); got != tt.wantPolicy {
+				t.Errorf("ValidatePRReference = %v, want policy %q", err, tt.wantPolicy)
+			}
+			if tt.wantPolicy == "" && err != nil {
+				t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+			}
+			if tt.wantFetches != 0 && tt.pr.fetches != tt.wantFetches {
+				t.Errorf("fetched the PR %d times, want %d", tt.pr.fetches, tt.wantFetches)
+			}
+			if tt.wantCompare && tt.pr.fetches < 2 {
+				t.Errorf("fetched the PR %d times, want it re-fetched until the deadline", tt.pr.fetches)
+			}
+			if got := tt.pr.gh.requested("GET /repos/o/r/compare/main...abc123"); got != tt.wantCompare {
+				t.Errorf("compared with the base branch = %v, want %v", got, tt.wantCompare)
+			}
+		})
+	}
+}
+
+func TestMergeableStateRefetchFailure(t *testing.T) {
+	pr := &computingPR{gh: &fakeGitHub{}, nullFor: 1 << 30, failStatus: http.StatusNotFound}
+	config := &Configuration{RequireUpToDate: true, MergeableStateTimeout: time.Second, MergeableStateInterval: time.Millisecond}
+	gc := newTestGitHubClient(t, config, pr.serve)
+
+	err := gc.ValidatePRReference(context.Background(), "o", "r", 1, config.GlobalPolicy())
+	if err == nil || failedPolicy(err) != "" {
+		t.Errorf("ValidatePRReference = %v, want the re-fetch error", err)
+	}
+}
+
+func TestMergeableStateWaitIsCancelled(t *testing.T) {
+	pr := &computingPR{gh: &fakeGitHub{}, nullFor: 1 << 30}
+	config := &Configuration{RequireUpToDate: true, MergeableStateTimeout: time.Minute, MergeableStateInterval: time.Minute}
+	gc := newTestGitHubClient(t, config, pr.serve)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := gc.ValidatePRReference(ctx, "o", "r", 1, config.GlobalPolicy()); err == nil {
+		t.Error("ValidatePRReference succeeded after its context was cancelled")
+	}
+}
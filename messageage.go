@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// messageAgeTimestamp returns the ts a message's age is measured from: its own, or for a
+// thread reply with MessageAgeFromThreadParent, its parent's. Slack sends the parent's ts
+// as the reply's thread_ts, so no lookup is needed.
+func (config *Configuration) messageAgeTimestamp(msg *SlackMessage) string {
+	if config.MessageAgeFromThreadParent && msg.ThreadTS != "" {
+		return msg.ThreadTS
+	}
+	return msg.Timestamp
+}
+
+// staleMessage reports whether a message is older than MaxMessageAge, e.g. an old message
+// edited or reacted to, or a thread reply whose parent is old. A message without a usable
+// timestamp is treated as stale.
+func (config *Configuration) staleMessage(msg *SlackMessage, now time.Time) (bool, string) {
+	ts := config.messageAgeTimestamp(msg)
+	postedAt, ok := parseSlackTimestamp(ts)
+	if !ok {
+		return true, "message has no timestamp"
+	}
+
+	age := now.Sub(postedAt)
+	if age <= config.MaxMessageAge {
+		return false, ""
+	}
+	if ts != msg.Timestamp {
+		return true, fmt.Sprintf("thread parent is %v old", age.Round(time.Second))
+	}
+	return true, fmt.Sprintf("message is %v old", age.Round(time.Second))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// slackTimestamp formats t as a Slack message ts
+func slackTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+}
+
+func TestStaleMessageReplyAgeVersusParentAge(t *testing.T) {
+	now := time.Now()
+	fresh, old := slackTimestamp(now.Add(-time.Minute)), slackTimestamp(now.Add(-2*time.Hour))
+
+	tests := []struct {
+		name       string
+		fromParent bool
+		msg        *SlackMessage
+		wantStale  bool
+	}{
+		{name: "fresh message", msg: &SlackMessage{Timestamp: fresh}},
+		{name: "old message", msg: &SlackMessage{Timestamp: old}, wantStale: true},
+		{name: "fresh reply in an old thread by reply age", msg: &SlackMessage{Timestamp: fresh, ThreadTS: old}},
+		{name: "fresh reply in an old thread by parent age", fromParent: true, msg: &SlackMessage{Timestamp: fresh, ThreadTS: old}, wantStale: true},
+		{name: "fresh reply in a fresh thread by parent age", fromParent: true, msg: &SlackMessage{Timestamp: fresh, ThreadTS: fresh}},
+		{name: "fresh top-level message by parent age", fromParent: true, msg: &SlackMessage{Timestamp: fresh}},
+		{name: "no timestamp", msg: &SlackMessage{}, wantStale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{MaxMessageAge: time.Hour, MessageAgeFromThreadParent: tt.fromParent}
+			if stale, reason := config.staleMessage(tt.msg, now); stale != tt.wantStale {
+				t.Errorf("staleMessage = %v (%s), want %v", stale, reason, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestReplyInOldThreadIsSkippedByParentAge(t *testing.T) {
+	now := time.Now()
+	for _, fromParent := range []bool{false, true} {
+		t.Run(fmt.Sprintf("from parent %v", fromParent), func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{MaxMessageAge: time.Hour, MessageAgeFromThreadParent: fromParent}, gh)
+			msg := testMessage("lgtm https://github.com/o/r/pull/1")
+			msg.Timestamp, msg.ThreadTS = slackTimestamp(now), slackTimestamp(now.Add(-2*time.Hour))
+			sc.processMessage(context.Background(), msg)
+			waitForApprovals(t, sc)
+
+			if approved := len(gh.submitted()) > 0; approved == fromParent {
+				t.Errorf("approved = %v, want %v", approved, !fromParent)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// messageApprovalMemory is how long the PRs approved from a message are remembered,
+// and so how long after an approval deleting its message still dismisses it
+const messageApprovalMemory = 7 * 24 * time.Hour
+
+// messageApprovals remembers which PRs each Slack message approved, so deleting the
+// message can dismiss them. Entries live in the store, one per message, holding one
+// owner/repo#number per line. A nil tracker remembers nothing.
+type messageApprovals struct {
+	mu    sync.Mutex
+	store Store
+}
+
+// newMessageApprovals creates the tracker; it returns nil when dismissal on deletion is disabled
+func newMessageApprovals(enabled bool, store Store) *messageApprovals {
+	if !enabled {
+		return nil
+	}
+	return &messageApprovals{store: store}
+}
+
+// record adds a PR approved from a message
+func (ma *messageApprovals) record(ctx context.Context, channel, timestamp string, req *ApprovalRequest) {
+	if ma == nil {
+		return
+	}
+
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	key := channel + "/" + timestamp
+	pr := fmt.Sprintf("%s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+	value, _, err := ma.store.Get(ctx, storeNamespaceMessageApprovals, key)
+	if err == nil && value != "" {
+		for _, existing := range strings.Split(value, "\n") {
+			if existing == pr {
+				return
+			}
+		}
+		pr = value + "\n" + pr
+	}
+	if err := ma.store.Put(ctx, storeNamespaceMessageApprovals, key, pr, messageApprovalMemory); err != nil {
+		logWarn("Failed to remember approval of %s from message %s: %v", pr, timestamp, err)
+	}
+}
+
+// take returns and forgets the PRs approved from a message; a message the tracker
+// doesn't know, e.g. one older than messageApprovalMemory, has none
+func (ma *messageApprovals) take(ctx context.Context, channel, timestamp string) ([]PRReference, error) {
+	ma.mu.Lock()
+	defer ma.mu.Unlock()
+
+	key := channel + "/" + timestamp
+	value, ok, err := ma.store.Get(ctx, storeNamespaceMessageApprovals, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if err := ma.store.Delete(ctx, storeNamespaceMessageApprovals, key); err != nil {
+		logWarn("Failed to forget approvals of message %s: %v", timestamp, err)
+	}
+
+	var prs []PRReference
+	for _, line := range strings.Split(value, "\n") {
+		repoPath, number, ok := strings.Cut(line, "#")
+		owner, repo, hasRepo := strings.Cut(repoPath, "/")
+		prNumber, err := strconv.Atoi(number)
+		if !ok || !hasRepo || err != nil {
+			logWarn("Ignoring unreadable approval %q remembered for message %s", line, timestamp)
+			continue
+		}
+		prs = append(prs, PRReference{Owner: owner, Repository: repo, Number: prNumber})
+	}
+	return prs, nil
+}
+
+// handleMessageDeleted dismisses the bot's approvals of the PRs a deleted message approved
+func (sc *SlackClient) handleMessageDeleted(ctx context.Context, channel, timestamp string) {
+	if sc.messageApprovals == nil || timestamp == "" {
+		return
+	}
+
+	prs, err := sc.messageApprovals.take(ctx, channel, timestamp)
+	if err != nil {
+		logError("Failed to look up approvals of deleted message %s in channel %s: %v", timestamp, channel, err)
+		return
+	}
+	if len(prs) == 0 {
+		logDebug("Deleted message %s in channel %s approved no PRs", timestamp, channel)
+		return
+	}
+
+	logInfo("Message %s in channel %s was deleted, dismissing %d approval(s)", timestamp, channel, len(prs))
+	for _, pr := range prs {
+		if sc.config.DryRun {
+			logInfo("[DRY_RUN] would dismiss approval of PR %s/%s#%d", pr.Owner, pr.Repository, pr.Number)
+			continue
+		}
+		dismissed, err := sc.githubClient.DismissBotApproval(ctx, pr.Owner, pr.Repository, pr.Number, "Approval withdrawn: the Slack message that requested it was deleted")
+		if err != nil {
+			logError("Failed to dismiss approval of PR %s/%s#%d: %v", pr.Owner, pr.Repository, pr.Number, err)
+			continue
+		}
+		if !dismissed {
+			logInfo("No approval by the bot to dismiss on PR %s/%s#%d", pr.Owner, pr.Repository, pr.Number)
+			continue
+		}
+		logInfo("Dismissed approval of PR %s/%s#%d", pr.Owner, pr.Repository, pr.Number)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDismissOnMessageDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+		// deleted is the timestamp of the deleted message
+		deleted       string
+		wantDismissed int
+	}{
+		{name: "approving message deleted", deleted: "1700000000.000100", wantDismissed: 1},
+		{name: "other message deleted", deleted: "1700000000.000200", wantDismissed: 0},
+		{name: "dry run", dryRun: true, deleted: "1700000000.000100", wantDismissed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			config := &Configuration{DismissOnMessageDeleted: true}
+			sc, _ := newTestSlackClient(t, config, gh)
+			ctx := context.Background()
+
+			sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			// A restart into dry-run mode keeps the approvals remembered before it
+			config.DryRun = tt.dryRun
+			sc.handleMessageDeleted(ctx, "C1", tt.deleted)
+
+			if got := len(gh.dismissals()); got != tt.wantDismissed {
+				t.Errorf("dismissed %d review(s), want %d", got, tt.wantDismissed)
+			}
+			if tt.dryRun && gh.requested("GET /repos/o/r/pulls/1/reviews") {
+				t.Error("dry run looked up reviews to dismiss")
+			}
+		})
+	}
+}
+
+func TestDismissOnMessageDeletedIgnoresUnreadableApprovals(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, _ := newTestSlackClient(t, &Configuration{DismissOnMessageDeleted: true}, gh)
+	ctx := context.Background()
+
+	sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	key := "C1/1700000000.000100"
+	value, _, _ := sc.messageApprovals.store.Get(ctx, storeNamespaceMessageApprovals, key)
+	if err := sc.messageApprovals.store.Put(ctx, storeNamespaceMessageApprovals, key, "garbage\n"+value, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	sc.handleMessageDeleted(ctx, "C1", "1700000000.000100")
+
+	if got := gh.dismissals(); len(got) != 1 {
+		t.Errorf("dismissed %v, want the one readable approval", got)
+	}
+
+	// The approvals are forgotten once dismissed
+	sc.handleMessageDeleted(ctx, "C1", "1700000000.000100")
+	if got := gh.dismissals(); len(got) != 1 {
+		t.Errorf("dismissed %v after a second deletion, want one", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metric names shared by every place that reports bot activity
+const (
+	metricMessagesReceived = "lgtm_messages_received_total"
+	metricPatternMatches   = "lgtm_pattern_matches_total"
+	metricApprovals        = "lgtm_approvals_total"
+	metricApprovalsSkipped = "lgtm_approvals_skipped_total"
+	metricApprovalFailures = "lgtm_approval_failures_total"
+	metricReviews          = "lgtm_reviews_total"
+	metricFallbacks        = "lgtm_approval_fallbacks_total"
+	metricHeartbeats       = "lgtm_heartbeats_total"
+	metricSlackConnected   = "lgtm_slack_connected"
+	metricSlackReady       = "lgtm_slack_ready"
+	metricDraining         = "lgtm_draining"
+	metricUptimeSeconds    = "lgtm_uptime_seconds"
+	metricApprovalsPaused  = "lgtm_approvals_paused"
+
+	metricApprovalsThrottled = "lgtm_approvals_throttled_total"
+	metricThrottleTokens     = "lgtm_approval_throttle_tokens"
+
+	metricApprovalsInFlight = "lgtm_approvals_in_flight"
+	metricApprovalsRejected = "lgtm_approvals_rejected_total"
+
+	metricGitHubDegraded = "lgtm_github_degraded"
+
+	metricApprovalsRetriedLater = "lgtm_approvals_retried_later_total"
+
+	metricGitHubRateLimit     = "lgtm_github_rate_limit"
+	metricGitHubRateRemaining = "lgtm_github_rate_limit_remaining"
+	metricGitHubRateReset     = "lgtm_github_rate_limit_reset_timestamp_seconds"
+)
+
+// metricsRegistry holds process-wide counters and gauges
+type metricsRegistry struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	counters  map[string]int64
+	gauges    map[string]float64
+	labels    map[string]string
+	// statsd, when set, gets every update too; tags caches the labels in its format
+	statsd *statsdSink
+	tags   string
+}
+
+// metrics is the registry used by the running bot
+var metrics = newMetricsRegistry()
+
+// newMetricsRegistry creates an empty registry
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		startedAt: time.Now(),
+		counters:  make(map[string]int64),
+		gauges:    make(map[string]float64),
+		labels:    make(map[string]string),
+	}
+}
+
+// Inc increments a counter by one
+func (m *metricsRegistry) Inc(name string) {
+	m.Add(name, 1)
+}
+
+// Add increments a counter by delta
+func (m *metricsRegistry) Add(name string, delta int64) {
+	m.mu.Lock()
+	m.counters[name] += delta
+	statsd, tags := m.statsd, m.tags
+	m.mu.Unlock()
+
+	if statsd != nil {
+		statsd.count(name, delta, tags)
+	}
+}
+
+// SetGauge sets a gauge to value
+func (m *metricsRegistry) SetGauge(name string, value float64) {
+	m.mu.Lock()
+	m.gauges[name] = value
+	statsd, tags := m.statsd, m.tags
+	m.mu.Unlock()
+
+	if statsd != nil {
+		statsd.gauge(name, value, tags)
+	}
+}
+
+// SetLabel adds a constant label, such as the instance name, to every metric
+func (m *metricsRegistry) SetLabel(name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.labels[name] = value
+	m.tags = statsdTags(m.labels)
+}
+
+// SetStatsD mirrors every later update to a StatsD server, and sends the current
+// gauges so it starts from the same state
+func (m *metricsRegistry) SetStatsD(sink *statsdSink) {
+	m.mu.Lock()
+	m.statsd = sink
+	gauges := make(map[string]float64, len(m.gauges))
+	for name, value := range m.gauges {
+		gauges[name] = value
+	}
+	tags := m.tags
+	m.mu.Unlock()
+
+	for name, value := range gauges {
+		sink.gauge(name, value, tags)
+	}
+}
+
+// Counter returns the current value of a counter
+func (m *metricsRegistry) Counter(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[name]
+}
+
+// Gauge returns the current value of a gauge
+func (m *metricsRegistry) Gauge(name string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gauges[name]
+}
+
+// Uptime returns how long the registry has existed
+func (m *metricsRegistry) Uptime() time.Duration {
+	return time.Since(m.startedAt)
+}
+
+// metricSample is one named value in a snapshot
+type metricSample struct {
+	Name    string
+	Labels  map[string]string
+	Value   float64
+	Counter bool
+}
+
+// Snapshot returns all metrics sorted by name, including uptime, with the constant labels
+func (m *metricsRegistry) Snapshot() []metricSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := make(map[string]string, len(m.labels))
+	for name, value := range m.labels {
+		labels[name] = value
+	}
+
+	samples := []metricSample{{Name: metricUptimeSeconds, Labels: labels, Value: time.Since(m.startedAt).Seconds()}}
+	for name, value := range m.counters {
+		samples = append(samples, metricSample{Name: name, Labels: labels, Value: float64(value), Counter: true})
+	}
+	for name, value := range m.gauges {
+		samples = append(samples, metricSample{Name: name, Labels: labels, Value: value})
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	return samples
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Ways to handle Slack mention tokens when normalizing message text
+const (
+	MentionsKeep    = "keep"
+	MentionsStrip   = "strip"
+	MentionsRewrite = "rewrite"
+)
+
+// Ways to handle the case of owner and repository names in PR references
+const (
+	RepoCaseLower    = "lower"
+	RepoCasePreserve = "preserve"
+)
+
+// slackTokenPattern matches Slack's angle-bracket tokens: <@U123>, <#C123|general>, <!here>, <https://...|label>
+var slackTokenPattern = regexp.MustCompile(`<([@#!])?([^<>|]+)(?:\|([^<>]*))?>`)
+
+// normalizeMessageText rewrites Slack's wire format into the text the user typed.
+// Links lose their angle brackets and labels, mentions are kept, stripped or rewritten
+// to @user / #channel / @here, and HTML entities are unescaped last so an escaped
+// &lt; can't be mistaken for a token.
+func normalizeMessageText(text, mentions string) string {
+	text = slackTokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		match := slackTokenPattern.FindStringSubmatch(token)
+		sigil, value, label := match[1], match[2], match[3]
+
+		if sigil == "" {
+			// A link; the URL is what patterns and PR extraction care about
+			return value
+		}
+
+		switch mentions {
+		case MentionsStrip:
+			return ""
+		case MentionsRewrite:
+			switch sigil {
+			case "@":
+				return "@" + value
+			case "#":
+				if label != "" {
+					return "#" + label
+				}
+				return "#" + value
+			default:
+				// <!here>, <!channel>, <!subteam^S123|@team>
+				if label != "" {
+					return label
+				}
+				return "@" + strings.SplitN(value, "^", 2)[0]
+			}
+		default:
+			return token
+		}
+	})
+
+	return html.UnescapeString(text)
+}
+
+// canonicalRepoName returns an owner or repository name in the configured case.
+// GitHub treats names case-insensitively, so Org/Repo and org/repo are the same
+// repository and the lowercase form is accepted everywhere the API takes one.
+func canonicalRepoName(name, repoCase string) string {
+	if repoCase == RepoCasePreserve {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// canonicalReferences rewrites the owner and repository of each reference in the configured case
+func canonicalReferences(refs []PRReference, repoCase string) []PRReference {
+	for i := range refs {
+		refs[i].Owner = canonicalRepoName(refs[i].Owner, repoCase)
+		refs[i].Repository = canonicalRepoName(refs[i].Repository, repoCase)
+	}
+	return refs
+}
+
+// matchText returns the text patterns are matched against
+func (sc *SlackClient) matchText(text string) string {
+	if !sc.config.NormalizeText {
+		return text
+	}
+	return normalizeMessageText(text, sc.config.MentionHandling)
+}
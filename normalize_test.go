@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMessageText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		mentions string
+		want     string
+	}{
+		{name: "link with label", text: "lgtm <https://github.com/o/r/pull/1|o/r#1>", mentions: MentionsKeep, want: "lgtm https://github.com/o/r/pull/1"},
+		{name: "bare link", text: "lgtm <https://github.com/o/r/pull/1>", mentions: MentionsKeep, want: "lgtm https://github.com/o/r/pull/1"},
+		{name: "entities", text: "lgtm &amp; ship &lt;it&gt;", mentions: MentionsKeep, want: "lgtm & ship <it>"},
+		{name: "escaped token stays text", text: "&lt;@U1&gt; lgtm", mentions: MentionsStrip, want: "<@U1> lgtm"},
+		{name: "keep mentions", text: "<@U1> lgtm <!here>", mentions: MentionsKeep, want: "<@U1> lgtm <!here>"},
+		{name: "strip mentions", text: "<@U1> lgtm <#C1|general>", mentions: MentionsStrip, want: " lgtm "},
+		{name: "rewrite user", text: "<@U1> lgtm", mentions: MentionsRewrite, want: "@U1 lgtm"},
+		{name: "rewrite channel", text: "lgtm in <#C1|general>", mentions: MentionsRewrite, want: "lgtm in #general"},
+		{name: "rewrite channel without name", text: "lgtm in <#C1>", mentions: MentionsRewrite, want: "lgtm in #C1"},
+		{name: "rewrite here", text: "<!here> lgtm", mentions: MentionsRewrite, want: "@here lgtm"},
+		{name: "rewrite user group", text: "<!subteam^S1|@platform> lgtm", mentions: MentionsRewrite, want: "@platform lgtm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeMessageText(tt.text, tt.mentions); got != tt.want {
+				t.Errorf("normalizeMessageText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizedTextIsMatched(t *testing.T) {
+	tests := []struct {
+		name      string
+		normalize bool
+		want      []string
+	}{
+		{name: "normalized", normalize: true, want: []string{"APPROVE"}},
+		{name: "raw", normalize: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			config := &Configuration{NormalizeText: tt.normalize, MentionHandling: MentionsKeep}
+			sc, _ := newTestSlackClient(t, config, gh)
+			matcher, err := NewPatternMatcher(`^ship & merge`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sc.matcher = matcher
+
+			sc.processMessage(context.Background(), testMessage("ship &amp; merge <https://github.com/o/r/pull/1|o/r#1>"))
+			waitForApprovals(t, sc)
+
+			if got := gh.submitted(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("submitted reviews %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionHandlingValidation(t *testing.T) {
+	for _, value := range []string{MentionsKeep, MentionsStrip, MentionsRewrite} {
+		if err := validateConfiguration(validConfig(t, "--mention-handling", value)); err != nil {
+			t.Errorf("validateConfiguration with %s mentions: %v", value, err)
+		}
+	}
+
+	err := validateConfiguration(validConfig(t, "--mention-handling", "drop"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "MentionHandling" {
+		t.Errorf("validateConfiguration = %v, want a MentionHandling error", err)
+	}
+}
+
+func TestCanonicalRepoName(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoCase string
+		want     string
+	}{
+		{name: "Org-Name", repoCase: RepoCaseLower, want: "org-name"},
+		{name: "Org-Name", repoCase: "", want: "org-name"},
+		{name: "Org-Name", repoCase: RepoCasePreserve, want: "Org-Name"},
+	}
+
+	for _, tt := range tests {
+		if got := canonicalRepoName(tt.name, tt.repoCase); got != tt.want {
+			t.Errorf("canonicalRepoName(%q, %q) = %q, want %q", tt.name, tt.repoCase, got, tt.want)
+		}
+	}
+}
+
+func TestMixedCaseReferences(t *testing.T) {
+	tests := []struct {
+		repoCase string
+		want     string
+	}{
+		{repoCase: RepoCaseLower, want: "org/repo"},
+		{repoCase: RepoCasePreserve, want: "Org/Repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repoCase, func(t *testing.T) {
+			matcher, err := NewPatternMatcher(`(?i)\blgtm\b`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matcher.SetRepoCase(tt.repoCase)
+
+			match, err := matcher.Match("lgtm https://github.com/Org/Repo/pull/1")
+			if err != nil || match == nil {
+				t.Fatalf("Match = %+v, %v", match, err)
+			}
+			if len(match.PRReferences) != 1 {
+				t.Fatalf("references %+v, want one", match.PRReferences)
+			}
+			if ref := match.PRReferences[0]; ref.Owner+"/"+ref.Repository != tt.want {
+				t.Errorf("reference %s/%s, want %s", ref.Owner, ref.Repository, tt.want)
+			}
+		})
+	}
+}
+
+func TestMixedCaseReferencesAreDeduplicated(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, _ := newTestSlackClient(t, &Configuration{RepoCase: RepoCaseLower, DedupeWindow: time.Hour}, gh)
+
+	// The message edited to spell the repository differently
+	for _, text := range []string{"lgtm https://github.com/Org/Repo/pull/1", "lgtm https://github.com/org/REPO/pull/1"} {
+		sc.processMessage(context.Background(), testMessage(text))
+		waitForApprovals(t, sc)
+	}
+
+	if got := gh.submitted(); len(got) != 1 {
+		t.Errorf("submitted %v, want one review for both spellings", got)
+	}
+	if !gh.requested("POST /repos/org/repo/pulls/1/reviews") {
+		t.Errorf("requests %v, want the review submitted for org/repo", gh.requests)
+	}
+}
+
+func TestRepoCaseValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--repo-case", "upper"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "RepoCase" {
+		t.Errorf("validateConfiguration = %v, want a RepoCase error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+)
+
+// plainOutput disables color and symbols in CLI output. It is set when stdout
+// isn't a terminal, when --no-color is passed, or when NO_COLOR is set.
+var plainOutput bool
+
+// ANSI color codes used for interactive terminals
+const (
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+)
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// configureOutput decides between rich and plain CLI output
+func configureOutput(noColor bool) {
+	plainOutput = noColor || os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout)
+}
+
+// okMark returns the marker for a passed check or successful operation
+func okMark() string {
+	if plainOutput {
+		return "[OK]"
+	}
+	return colorGreen + "✓" + colorReset
+}
+
+// failMark returns the marker for a failed check or operation
+func failMark() string {
+	if plainOutput {
+		return "[FAIL]"
+	}
+	return colorRed + "✗" + colorReset
+}
+
+// skipMark returns the marker for a check that was not run
+func skipMark() string {
+	if plainOutput {
+		return "[SKIP]"
+	}
+	return "-"
+}
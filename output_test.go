@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConfigureOutputWithoutTerminal(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reader.Close(); writer.Close() })
+
+	stdout, plain := os.Stdout, plainOutput
+	t.Cleanup(func() { os.Stdout, plainOutput = stdout, plain })
+	os.Stdout = writer
+	t.Setenv("NO_COLOR", "")
+
+	if isTerminal(writer) {
+		t.Fatal("a pipe was taken for a terminal")
+	}
+	configureOutput(false)
+	if !plainOutput {
+		t.Fatal("output piped to another program is not plain")
+	}
+	for mark, want := range map[string]string{okMark(): "[OK]", failMark(): "[FAIL]", skipMark(): "[SKIP]"} {
+		if mark != want {
+			t.Errorf("mark = %q, want %q", mark, want)
+		}
+	}
+}
+
+func TestTerminalOutputMarks(t *testing.T) {
+	plain := plainOutput
+	t.Cleanup(func() { plainOutput = plain })
+	plainOutput = false
+
+	for mark, want := range map[string]string{okMark(): colorGreen + "✓" + colorReset, failMark(): colorRed + "✗" + colorReset, skipMark(): "-"} {
+		if mark != want {
+			t.Errorf("mark = %q, want %q", mark, want)
+		}
+	}
+}
+
+func TestNoColorOutput(t *testing.T) {
+	plain := plainOutput
+	t.Cleanup(func() { plainOutput = plain })
+
+	t.Setenv("NO_COLOR", "")
+	configureOutput(true)
+	if !plainOutput {
+		t.Error("--no-color output is not plain")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	configureOutput(false)
+	if !plainOutput {
+		t.Error("NO_COLOR output is not plain")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pauseCommandPattern matches the "lgtm pause" and "lgtm resume" admin commands
+var pauseCommandPattern = regexp.MustCompile(`(?i)^\s*lgtm\s+(pause|resume)\s*$`)
+
+// pauseStateKey is the store key holding when approvals were paused
+const pauseStateKey = "paused_since"
+
+// maxPausedApprovals bounds how many requests are queued while paused, as they are
+// held in memory for as long as the pause lasts
+const maxPausedApprovals = 1000
+
+// pauseSwitch is the global kill switch that holds every approval while paused.
+// The paused state is saved in the store; queued requests are not.
+type pauseSwitch struct {
+	mu     sync.Mutex
+	paused bool
+	since  time.Time
+	queue  []*ApprovalRequest
+	store  Store
+}
+
+// newPauseSwitch creates a switch, restoring a pause saved in the store
+func newPauseSwitch(store Store) *pauseSwitch {
+	ps := &pauseSwitch{store: store}
+
+	value, ok, err := store.Get(context.Background(), storeNamespacePause, pauseStateKey)
+	if err != nil {
+		logWarn("Failed to load the paused state: %v", err)
+	}
+	if ok {
+		ps.paused = true
+		if ps.since, err = time.Parse(time.RFC3339, value); err != nil {
+			ps.since = time.Now()
+		}
+		logWarn("Approvals are paused since %s", ps.since.Format(time.RFC3339))
+	}
+
+	metrics.SetGauge(metricApprovalsPaused, 0)
+	if ps.paused {
+		metrics.SetGauge(metricApprovalsPaused, 1)
+	}
+	return ps
+}
+
+// pause stops approvals; it reports false when they were already paused
+func (ps *pauseSwitch) pause() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.paused {
+		return false
+	}
+	ps.paused = true
+	ps.since = time.Now()
+	metrics.SetGauge(metricApprovalsPaused, 1)
+
+	if err := ps.store.Put(context.Background(), storeNamespacePause, pauseStateKey, ps.since.UTC().Format(time.RFC3339), 0); err != nil {
+		logWarn("Failed to save the paused state: %v", err)
+	}
+	return true
+}
+
+// resume lets approvals through again and returns the requests queued while paused
+// along with how long approvals were paused
+func (ps *pauseSwitch) resume() ([]*ApprovalRequest, time.Duration, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if !ps.paused {
+		return nil, 0, false
+	}
+	queued, pausedFor := ps.queue, time.Since(ps.since)
+	ps.paused = false
+	ps.queue = nil
+	metrics.SetGauge(metricApprovalsPaused, 0)
+
+	if err := ps.store.Delete(context.Background(), storeNamespacePause, pauseStateKey); err != nil {
+		logWarn("Failed to clear the saved paused state: %v", err)
+	}
+	return queued, pausedFor, true
+}
+
+// isPaused reports whether approvals are paused
+func (ps *pauseSwitch) isPaused() bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	return ps.paused
+}
+
+// hold reports whether approvals are paused and, when queue is set, whether req was
+// queued; it isn't once maxPausedApprovals are waiting
+func (ps *pauseSwitch) hold(req *ApprovalRequest, queue bool) (bool, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if !ps.paused {
+		return false, false
+	}
+	if !queue || len(ps.queue) >= maxPausedApprovals {
+		return true, false
+	}
+	ps.queue = append(ps.queue, req)
+	return true, true
+}
+
+// isAdmin reports whether a Slack user may toggle the kill switch
+func (config *Configuration) isAdmin(user string) bool {
+	for _, admin := range config.AdminSlackUsers {
+		if admin == user {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePauseCommand toggles the kill switch for "lgtm pause" and "lgtm resume".
+// It reports whether the message was a pause command, so it is not matched as an approval.
+func (sc *SlackClient) handlePauseCommand(ctx context.Context, msg *SlackMessage) bool {
+	if len(sc.config.AdminSlackUsers) == 0 {
+		return false
+	}
+
+	match := pauseCommandPattern.FindStringSubmatch(msg.Text)
+	if match == nil {
+		return false
+	}
+
+	if !sc.config.isAdmin(msg.User) {
+		logWarn("Ignoring %q from Slack user %s: not an admin", match[0], sc.userLabel(ctx, msg.User))
+		sc.react(msg.Channel, msg.Timestamp, outcomeDenied)
+		return true
+	}
+
+	if strings.EqualFold(match[1], "pause") {
+		if sc.pause.pause() {
+			logWarn("Approvals paused by Slack user %s", sc.userLabel(ctx, msg.User))
+			sc.replyInChannel(msg.Channel, fmt.Sprintf(":double_vertical_bar: Approvals paused by <@%s>. Send `lgtm resume` to continue.", msg.User))
+		} else {
+			sc.replyInChannel(msg.Channel, "Approvals are already paused.")
+		}
+		return true
+	}
+
+	queued, pausedFor, resumed := sc.pause.resume()
+	if !resumed {
+		sc.replyInChannel(msg.Channel, "Approvals aren't paused.")
+		return true
+	}
+
+	logInfo("Approvals resumed by Slack user %s after %v, processing %d queued request(s)", sc.userLabel(ctx, msg.User), pausedFor.Round(time.Second), len(queued))
+	sc.replyInChannel(msg.Channel, fmt.Sprintf(":arrow_forward: Approvals resumed by <@%s>.", msg.User))
+	for _, req := range queued {
+		sc.spawnApproval(ctx, req)
+	}
+	return true
+}
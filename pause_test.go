@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueuedPauseHoldIsDecidedOnce(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "skips.jsonl")
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{
+		AdminSlackUsers:   []string{"UADMIN"},
+		QueueWhilePaused:  true,
+		CompositeReaction: true,
+		SkipAuditFile:     auditFile,
+	}, gh)
+	ctx := context.Background()
+	skipped := metrics.Counter(metricApprovalsSkipped)
+
+	sc.pause.pause()
+	sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1 https://github.com/o/r/pull/2"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 {
+		t.Fatalf("submitted %v while paused", reviews)
+	}
+	if len(sc.pause.queue) != 2 {
+		t.Fatalf("queued %d request(s), want 2", len(sc.pause.queue))
+	}
+	batch := sc.pause.queue[0].batch
+	if batch.pending != 2 || len(batch.decisions) != 0 {
+		t.Errorf("batch has %d pending and %d decision(s) while queued, want 2 and 0", batch.pending, len(batch.decisions))
+	}
+	if got := metrics.Counter(metricApprovalsSkipped) - skipped; got != 0 {
+		t.Errorf("queued requests counted %d skip(s)", got)
+	}
+	if _, err := os.Stat(auditFile); !os.IsNotExist(err) {
+		t.Errorf("queued requests were written to the skip audit: %v", err)
+	}
+
+	sc.handlePauseCommand(ctx, &SlackMessage{Text: "lgtm resume", Channel: "C1", User: "UADMIN", Timestamp: "1700000001.000100"})
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 2 {
+		t.Fatalf("submitted %v after resume, want two approvals", reviews)
+	}
+	if batch.pending != 0 || len(batch.decisions) != 2 {
+		t.Errorf("batch has %d pending and %d decision(s) after resume, want 0 and 2", batch.pending, len(batch.decisions))
+	}
+	if !reactions.has("white_check_mark") {
+		t.Errorf("reactions %v, want the composite approved reaction", reactions.added)
+	}
+}
+
+func TestPauseHoldWithoutQueueIsSkipped(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{}, gh)
+	skipped := metrics.Counter(metricApprovalsSkipped)
+
+	sc.pause.pause()
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if len(gh.submitted()) != 0 || len(sc.pause.queue) != 0 {
+		t.Fatalf("submitted %v and queued %d while paused without a queue", gh.submitted(), len(sc.pause.queue))
+	}
+	if got := metrics.Counter(metricApprovalsSkipped) - skipped; got != 1 {
+		t.Errorf("counted %d skip(s), want 1", got)
+	}
+	if !reactions.has("double_vertical_bar") {
+		t.Errorf("reactions %v, want double_vertical_bar", reactions.added)
+	}
+}
+
+func TestPauseQueueIsCapped(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{QueueWhilePaused: true}, gh)
+	skipped := metrics.Counter(metricApprovalsSkipped)
+
+	sc.pause.pause()
+	for i := 0; i < maxPausedApprovals; i++ {
+		if paused, queued := sc.pause.hold(&ApprovalRequest{}, true); !paused || !queued {
+			t.Fatalf("request %d: paused %v, queued %v, want both", i, paused, queued)
+		}
+	}
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if len(sc.pause.queue) != maxPausedApprovals {
+		t.Errorf("queued %d request(s), want the cap of %d", len(sc.pause.queue), maxPausedApprovals)
+	}
+	if got := metrics.Counter(metricApprovalsSkipped) - skipped; got != 1 {
+		t.Errorf("counted %d skip(s), want the dropped request", got)
+	}
+	if !reactions.has("double_vertical_bar") {
+		t.Errorf("reactions %v, want double_vertical_bar", reactions.added)
+	}
+}
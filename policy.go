@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Policy holds the approval rules applied to a PR. Channel policies override
+// the global policy field by field; empty fields fall back to the global value.
+type Policy struct {
+	RequiredLabels []string `yaml:"required_labels" json:"required_labels"`
+	AllowedAuthors []string `yaml:"allowed_authors" json:"allowed_authors"`
+	ReviewEvent    string   `yaml:"review_event" json:"review_event"`
+}
+
+// validReviewEvents lists the review events GitHub accepts when submitting a review
+var validReviewEvents = map[string]bool{
+	"APPROVE":         true,
+	"COMMENT":         true,
+	"REQUEST_CHANGES": true,
+}
+
+// GlobalPolicy returns the policy built from the top-level configuration
+func (config *Configuration) GlobalPolicy() Policy {
+	return Policy{
+		RequiredLabels: config.RequiredLabels,
+		AllowedAuthors: config.AllowedAuthors,
+		ReviewEvent:    config.ReviewEvent,
+	}
+}
+
+// PolicyFor returns the effective policy for a Slack channel
+func (config *Configuration) PolicyFor(channel string) Policy {
+	policy := config.GlobalPolicy()
+
+	channelPolicy, ok := config.ChannelPolicies[channel]
+	if !ok {
+		return policy
+	}
+
+	if len(channelPolicy.RequiredLabels) > 0 {
+		policy.RequiredLabels = channelPolicy.RequiredLabels
+	}
+	if len(channelPolicy.AllowedAuthors) > 0 {
+		policy.AllowedAuthors = channelPolicy.AllowedAuthors
+	}
+	if channelPolicy.ReviewEvent != "" {
+		policy.ReviewEvent = channelPolicy.ReviewEvent
+	}
+	return policy
+}
+
+// EffectiveReviewEvent returns the review event to submit, defaulting to APPROVE
+func (p Policy) EffectiveReviewEvent() string {
+	if p.ReviewEvent == "" {
+		return "APPROVE"
+	}
+	return strings.ToUpper(p.ReviewEvent)
+}
+
+// reviewEventFor returns the review event to submit for a request: the action the message
+// asked for, else the repository's default, else the request's policy
+func (config *Configuration) reviewEventFor(req *ApprovalRequest) string {
+	if req.RequestedEvent != "" {
+		return strings.ToUpper(req.RequestedEvent)
+	}
+	for repo, event := range config.RepoReviewEvents {
+		if strings.EqualFold(repo, req.Owner+"/"+req.Repository) {
+			return strings.ToUpper(event)
+		}
+	}
+	return req.Policy.EffectiveReviewEvent()
+}
+
+// parseRepoReviewEvents parses per-repository review events in owner/repo=EVENT form
+func parseRepoReviewEvents(values []string) (map[string]string, error) {
+	pairs, err := parseKeyValues(values, "RepoReviewEvents", "repo review event", "owner/repo=EVENT")
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]string)
+	for _, pair := range pairs {
+		event := strings.TrimSpace(pair.value)
+		if event == "" {
+			return nil, &ConfigError{Field: "RepoReviewEvents", Message: fmt.Sprintf("repo review event %q must be in owner/repo=EVENT form", pair.key+"=")}
+		}
+		events[pair.key] = event
+	}
+	return events, nil
+}
+
+// validatePolicy checks a policy for invalid values
+func validatePolicy(name string, policy Policy) error {
+	if policy.ReviewEvent != "" && !validReviewEvents[strings.ToUpper(policy.ReviewEvent)] {
+		return &ConfigError{Field: name, Message: fmt.Sprintf("review event %q must be one of: APPROVE, COMMENT, REQUEST_CHANGES", policy.ReviewEvent)}
+	}
+
+	for _, label := range policy.RequiredLabels {
+		if strings.TrimSpace(label) == "" {
+			return &ConfigError{Field: name, Message: "required labels cannot be empty"}
+		}
+	}
+
+	for _, author := range policy.AllowedAuthors {
+		if strings.TrimSpace(author) == "" {
+			return &ConfigError{Field: name, Message: "allowed authors cannot be empty"}
+		}
+	}
+
+	return nil
+}
+
+// parseChannelPolicies parses channel policies from a JSON object keyed by channel ID
+func parseChannelPolicies(value string) (map[string]Policy, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var policies map[string]Policy
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		return nil, &ConfigError{Field: "ChannelPolicies", Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return policies, nil
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
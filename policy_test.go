@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyFor(t *testing.T) {
+	config := &Configuration{
+		RequiredLabels: []string{"safe"},
+		AllowedAuthors: []string{"octocat"},
+		ReviewEvent:    "APPROVE",
+		ChannelPolicies: map[string]Policy{
+			"CDOCS":  {ReviewEvent: "COMMENT"},
+			"CINFRA": {RequiredLabels: []string{"infra", "reviewed"}, AllowedAuthors: []string{"ops-bot"}},
+		},
+	}
+
+	tests := []struct {
+		channel string
+		want    Policy
+	}{
+		{channel: "COTHER", want: Policy{RequiredLabels: []string{"safe"}, AllowedAuthors: []string{"octocat"}, ReviewEvent: "APPROVE"}},
+		{channel: "CDOCS", want: Policy{RequiredLabels: []string{"safe"}, AllowedAuthors: []string{"octocat"}, ReviewEvent: "COMMENT"}},
+		{channel: "CINFRA", want: Policy{RequiredLabels: []string{"infra", "reviewed"}, AllowedAuthors: []string{"ops-bot"}, ReviewEvent: "APPROVE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			if got := config.PolicyFor(tt.channel); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PolicyFor(%s) = %+v, want %+v", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChannelPolicies(t *testing.T) {
+	policies, err := parseChannelPolicies(`{"C1": {"required_labels": ["docs"], "review_event": "comment"}}`)
+	if err != nil {
+		t.Fatalf("parseChannelPolicies: %v", err)
+	}
+	want := map[string]Policy{"C1": {RequiredLabels: []string{"docs"}, ReviewEvent: "comment"}}
+	if !reflect.DeepEqual(policies, want) {
+		t.Errorf("parseChannelPolicies = %+v, want %+v", policies, want)
+	}
+
+	if policies, err := parseChannelPolicies("  "); err != nil || policies != nil {
+		t.Errorf("parseChannelPolicies of nothing = %v, %v, want no policies", policies, err)
+	}
+
+	var configErr *ConfigError
+	if _, err := parseChannelPolicies(`{"C1": ["docs"]}`); !errors.As(err, &configErr) || configErr.Field != "ChannelPolicies" {
+		t.Errorf("parseChannelPolicies of invalid JSON = %v, want a ChannelPolicies error", err)
+	}
+}
+
+func TestChannelPolicyValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		policies  string
+		wantField string
+	}{
+		{name: "valid", policies: `{"C1": {"review_event": "comment", "allowed_authors": ["octocat"]}}`},
+		{name: "invalid review event", policies: `{"C1": {"review_event": "MERGE"}}`, wantField: "ChannelPolicies[C1]"},
+		{name: "empty label", policies: `{"C1": {"required_labels": [" "]}}`, wantField: "ChannelPolicies[C1]"},
+		{name: "empty author", policies: `{"C1": {"allowed_authors": [""]}}`, wantField: "ChannelPolicies[C1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfiguration(validConfig(t, "--channel-policies", tt.policies))
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("validateConfiguration: %v", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != tt.wantField {
+				t.Errorf("validateConfiguration = %v, want a %s error", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestChannelPolicyReviewEvent(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, _ := newTestSlackClient(t, &Configuration{ChannelPolicies: map[string]Policy{"C1": {ReviewEvent: "COMMENT"}}}, gh)
+
+	msg := testMessage("lgtm https://github.com/o/r/pull/1")
+	sc.processMessage(context.Background(), msg)
+	other := testMessage("lgtm https://github.com/o/r/pull/2")
+	other.Channel = "C2"
+	sc.processMessage(context.Background(), other)
+	waitForApprovals(t, sc)
+
+	reviews := gh.submitted()
+	if len(reviews) != 2 || !containsFold(reviews, "COMMENT") || !containsFold(reviews, "APPROVE") {
+		t.Errorf("submitted %v, want a comment in C1 and an approval in C2", reviews)
+	}
+}
+
+func TestParseRepoReviewEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", want: map[string]string{}},
+		{name: "events", values: []string{"o/docs=COMMENT", " o/infra = request_changes "}, want: map[string]string{"o/docs": "COMMENT", "o/infra": "request_changes"}},
+		{name: "invalid event is left to validation", values: []string{"o/r=MERGE"}, want: map[string]string{"o/r": "MERGE"}},
+		{name: "empty event", values: []string{"o/r= "}, wantErr: true},
+		{name: "missing event", values: []string{"o/r"}, wantErr: true},
+		{name: "missing repo", values: []string{"=COMMENT"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRepoReviewEvents(tt.values)
+			if tt.wantErr {
+				var configErr *ConfigError
+				if !errors.As(err, &configErr) || configErr.Field != "RepoReviewEvents" {
+					t.Errorf("parseRepoReviewEvents = %v, want a RepoReviewEvents error", err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRepoReviewEvents = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoReviewEventValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "valid", value: "o/r=comment"},
+		{name: "invalid event", value: "o/r=MERGE", wantErr: `review event "MERGE" for o/r must be one of: APPROVE, COMMENT, REQUEST_CHANGES`},
+		{name: "invalid repository", value: "docs=COMMENT", wantErr: `"docs" is not in owner/repo form`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfiguration(validConfig(t, "--repo-review-event", tt.value))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateConfiguration: %v", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != "RepoReviewEvents" || configErr.Message != tt.wantErr {
+				t.Errorf("validateConfiguration = %v, want RepoReviewEvents error %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReviewEventFor(t *testing.T) {
+	config := &Configuration{RepoReviewEvents: map[string]string{"O/Docs": "comment"}}
+	tests := []struct {
+		name string
+		req  *ApprovalRequest
+		want string
+	}{
+		{name: "policy", req: &ApprovalRequest{Owner: "o", Repository: "r", Policy: Policy{ReviewEvent: "request_changes"}}, want: "REQUEST_CHANGES"},
+		{name: "repository", req: &ApprovalRequest{Owner: "o", Repository: "docs", Policy: Policy{ReviewEvent: "APPROVE"}}, want: "COMMENT"},
+		{name: "requested", req: &ApprovalRequest{Owner: "o", Repository: "docs", RequestedEvent: "approve"}, want: "APPROVE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.reviewEventFor(tt.req); got != tt.want {
+				t.Errorf("reviewEventFor = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
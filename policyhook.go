@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults and limits of the external policy hook
+const (
+	defaultPolicyTimeout     = 5 * time.Second
+	maxPolicyResponseBytes   = 64 * 1024
+	externalPolicyName       = "external-policy"
+	defaultPolicyDenyMessage = "denied by the policy endpoint"
+)
+
+// policyInput is the approval context POSTed to the decision endpoint, wrapped in
+// {"input": ...} so an OPA data API path can be used as is
+type policyInput struct {
+	Owner       string      `json:"owner"`
+	Repository  string      `json:"repository"`
+	PRNumber    int         `json:"pr_number"`
+	Title       string      `json:"title"`
+	Author      string      `json:"author"`
+	BaseBranch  string      `json:"base_branch"`
+	HeadBranch  string      `json:"head_branch"`
+	HeadSHA     string      `json:"head_sha"`
+	Draft       bool        `json:"draft"`
+	Labels      []string    `json:"labels"`
+	URL         string      `json:"url"`
+	ReviewEvent string      `json:"review_event"`
+	Slack       policySlack `json:"slack"`
+}
+
+// policySlack describes who asked for the approval in Slack
+type policySlack struct {
+	User    string `json:"user,omitempty"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// policyDecision is the allow/deny answer; OPA nests it under "result", either as
+// a bare boolean or as an object
+type policyDecision struct {
+	Allow  *bool  `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+// parsePolicyDecision reads {"allow": bool, "reason": ...}, {"result": bool} or
+// {"result": {"allow": bool, "reason": ...}}
+func parsePolicyDecision(body []byte) (bool, string, error) {
+	var response struct {
+		policyDecision
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false, "", fmt.Errorf("invalid decision: %v", err)
+	}
+
+	decision := response.policyDecision
+	if len(response.Result) > 0 {
+		var allow bool
+		if err := json.Unmarshal(response.Result, &allow); err == nil {
+			return allow, "", nil
+		}
+		if err := json.Unmarshal(response.Result, &decision); err != nil {
+			return false, "", fmt.Errorf("invalid decision result: %v", err)
+		}
+	}
+	if decision.Allow == nil {
+		return false, "", fmt.Errorf("decision has no allow field")
+	}
+	return *decision.Allow, decision.Reason, nil
+}
+
+// checkExternalPolicy asks the PolicyURL endpoint whether the PR may be approved. A deny
+// is a PolicyError; when the endpoint can't be reached or answers badly, the approval
+// is blocked unless PolicyFailOpen is set.
+func (gc *GitHubClient) checkExternalPolicy(ctx context.Context, req *ApprovalRequest) error {
+	if gc.config.PolicyURL == "" {
+		return nil
+	}
+
+	pr, err := gc.getPR(ctx, req.Owner, req.Repository, req.PRNumber)
+	if err != nil {
+		return err
+	}
+
+	input := policyInput{
+		Owner:       req.Owner,
+		Repository:  req.Repository,
+		PRNumber:    req.PRNumber,
+		Title:       pr.GetTitle(),
+		Author:      pr.GetUser().GetLogin(),
+		BaseBranch:  pr.GetBase().GetRef(),
+		HeadBranch:  pr.GetHead().GetRef(),
+		HeadSHA:     pr.GetHead().GetSHA(),
+		Draft:       pr.GetDraft(),
+		Labels:      []string{},
+		URL:         pr.GetHTMLURL(),
+		ReviewEvent: gc.config.reviewEventFor(req),
+		Slack:       policySlack{User: req.SourceUser, Channel: req.SourceChannel},
+	}
+	for _, label := range pr.Labels {
+		input.Labels = append(input.Labels, label.GetName())
+	}
+
+	allow, reason, err := gc.askPolicyEndpoint(ctx, input)
+	if err != nil {
+		if gc.config.PolicyFailOpen {
+			logWarn("Policy endpoint failed for PR %s/%s#%d, allowing it (fail open): %v", req.Owner, req.Repository, req.PRNumber, err)
+			return nil
+		}
+		return &PolicyError{Policy: externalPolicyName, Message: fmt.Sprintf("policy endpoint failed: %v", err)}
+	}
+	if !allow {
+		if reason == "" {
+			reason = defaultPolicyDenyMessage
+		}
+		return &PolicyError{Policy: externalPolicyName, Message: reason}
+	}
+
+	logDebug("Policy endpoint allowed PR %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+	return nil
+}
+
+// askPolicyEndpoint POSTs the input and parses the decision
+func (gc *GitHubClient) askPolicyEndpoint(ctx context.Context, input policyInput) (bool, string, error) {
+	payload, err := json.Marshal(map[string]policyInput{"input": input})
+	if err != nil {
+		return false, "", err
+	}
+
+	timeout := gc.config.PolicyTimeout
+	if timeout <= 0 {
+		timeout = defaultPolicyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, gc.config.PolicyURL, bytes.NewReader(payload))
+	if err != nil {
+		return false, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := gc.policyClient.Do(request)
+	if err != nil {
+		return false, "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxPolicyResponseBytes))
+	if err != nil {
+		return false, "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("status %d", response.StatusCode)
+	}
+	return parsePolicyDecision(body)
+}
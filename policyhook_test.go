@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePolicyDecision(t *testing.T) {
+	tests := []struct {
+		body       string
+		wantAllow  bool
+		wantReason string
+		wantErr    bool
+	}{
+		{body: `{"allow": true}`, wantAllow: true},
+		{body: `{"allow": false, "reason": "author is on call"}`, wantReason: "author is on call"},
+		{body: `{"result": true}`, wantAllow: true},
+		{body: `{"result": {"allow": false, "reason": "main is frozen"}}`, wantReason: "main is frozen"},
+		{body: `{"result": "yes"}`, wantErr: true},
+		{body: `{"reason": "no decision"}`, wantErr: true},
+		{body: `{}`, wantErr: true},
+		{body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			allow, reason, err := parsePolicyDecision([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePolicyDecision error = %v, want error %v", err, tt.wantErr)
+			}
+			if allow != tt.wantAllow || reason != tt.wantReason {
+				t.Errorf("parsePolicyDecision = %v, %q, want %v, %q", allow, reason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
+
+// decisionServer answers policy requests with status and body, recording the last input
+func decisionServer(t *testing.T, status int, body string, input *policyInput) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Input policyInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err == nil && input != nil {
+			*input = request.Input
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestExternalPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		failOpen   bool
+		wantDenied bool
+		wantReason string
+	}{
+		{name: "allowed", status: http.StatusOK, body: `{"result": {"allow": true}}`},
+		{name: "denied", status: http.StatusOK, body: `{"result": {"allow": false, "reason": "main is frozen"}}`, wantDenied: true, wantReason: "main is frozen"},
+		{name: "denied without a reason", status: http.StatusOK, body: `{"allow": false}`, wantDenied: true, wantReason: defaultPolicyDenyMessage},
+		{name: "endpoint error fails closed", status: http.StatusInternalServerError, body: `{}`, wantDenied: true, wantReason: "policy endpoint failed: status 500"},
+		{name: "endpoint error fails open", status: http.StatusInternalServerError, body: `{}`, failOpen: true},
+		{name: "bad decision fails closed", status: http.StatusOK, body: `{"result": "maybe"}`, wantDenied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Configuration{PolicyURL: decisionServer(t, tt.status, tt.body, nil), PolicyFailOpen: tt.failOpen}
+			gc := newTestGitHubClient(t, config, (&fakeGitHub{}).ServeHTTP)
+
+			err := gc.checkExternalPolicy(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+			if got := failedPolicy(err) == externalPolicyName; got != tt.wantDenied {
+				t.Fatalf("checkExternalPolicy = %v, want denied %v", err, tt.wantDenied)
+			}
+			var policyErr *PolicyError
+			if tt.wantReason != "" && (!errors.As(err, &policyErr) || policyErr.Message != tt.wantReason) {
+				t.Errorf("checkExternalPolicy = %v, want reason %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestExternalPolicyInput(t *testing.T) {
+	var input policyInput
+	config := &Configuration{PolicyURL: decisionServer(t, http.StatusOK, `{"allow": true}`, &input)}
+	gc := newTestGitHubClient(t, config, (&fakeGitHub{author: "alice"}).ServeHTTP)
+
+	err := gc.checkExternalPolicy(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, SourceUser: "U1", SourceChannel: "C1"})
+	if err != nil {
+		t.Fatalf("checkExternalPolicy: %v", err)
+	}
+	if input.Owner != "o" || input.Repository != "r" || input.PRNumber != 1 || input.Author != "alice" || input.HeadSHA != "abc123" || input.ReviewEvent != "APPROVE" {
+		t.Errorf("input %+v, want the PR's details", input)
+	}
+	if input.Slack.User != "U1" || input.Slack.Channel != "C1" {
+		t.Errorf("input Slack context %+v, want U1 in C1", input.Slack)
+	}
+}
+
+func TestExternalPolicyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	config := &Configuration{PolicyURL: server.URL, PolicyTimeout: 10 * time.Millisecond}
+	gc := newTestGitHubClient(t, config, (&fakeGitHub{}).ServeHTTP)
+
+	err := gc.checkExternalPolicy(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+	if failedPolicy(err) != externalPolicyName {
+		t.Errorf("checkExternalPolicy = %v, want a slow endpoint to block the approval", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// queuedReaction is the store entry of a queued approval's interim reactions
+type queuedReaction struct {
+	Channel   string   `json:"channel"`
+	Timestamp string   `json:"timestamp"`
+	Emojis    []string `json:"emojis"`
+}
+
+// queuedReactions remembers the interim reactions of approvals that are queued, keyed by
+// approval, so the reactions of a queue lost to a restart can be taken back off. The
+// queues themselves live in memory; with a persistent store these entries don't.
+type queuedReactions struct {
+	mu    sync.Mutex
+	store Store
+	// recorded holds the approvals with an entry, so finishing one that never queued
+	// costs no store write
+	recorded map[string]bool
+}
+
+// newQueuedReactions creates the tracker
+func newQueuedReactions(store Store) *queuedReactions {
+	return &queuedReactions{store: store, recorded: make(map[string]bool)}
+}
+
+// record adds an interim reaction applied to a queued approval's message
+func (qr *queuedReactions) record(req *ApprovalRequest, emoji string) {
+	qr.mu.Lock()
+	defer qr.mu.Unlock()
+
+	ctx := context.Background()
+	key := approvalKey(req)
+	entry := queuedReaction{Channel: req.SourceChannel, Timestamp: req.SourceMessage.Timestamp}
+	if value, ok, err := qr.store.Get(ctx, storeNamespaceQueuedReactions, key); err == nil && ok {
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			logWarn("Replacing unreadable queued reactions of %s: %v", key, err)
+		}
+	}
+	if containsEmoji(entry.Emojis, emoji) {
+		qr.recorded[key] = true
+		return
+	}
+	entry.Emojis = append(entry.Emojis, emoji)
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		logWarn("Failed to encode queued reactions of %s: %v", key, err)
+		return
+	}
+	if err := qr.store.Put(ctx, storeNamespaceQueuedReactions, key, string(value), 0); err != nil {
+		logWarn("Failed to remember queued reaction %s of %s: %v", emoji, key, err)
+		return
+	}
+	qr.recorded[key] = true
+}
+
+// finish forgets a decided approval's interim reactions, reporting whether it had any
+func (qr *queuedReactions) finish(req *ApprovalRequest) bool {
+	qr.mu.Lock()
+	defer qr.mu.Unlock()
+
+	key := approvalKey(req)
+	if !qr.recorded[key] {
+		return false
+	}
+	delete(qr.recorded, key)
+	if err := qr.store.Delete(context.Background(), storeNamespaceQueuedReactions, key); err != nil {
+		logWarn("Failed to forget queued reactions of %s: %v", key, err)
+	}
+	return true
+}
+
+// finishQueued settles a queued approval once it is decided. Its outcome's reaction
+// already replaced the queued reactions; one decided without a reaction has them
+// removed. An approval cancelled by shutdown keeps its entry for the next start.
+func (sc *SlackClient) finishQueued(ctx context.Context, req *ApprovalRequest) {
+	if ctx.Err() != nil {
+		return
+	}
+	if !sc.queuedReactions.finish(req) {
+		return
+	}
+	// A batch's composite reaction replaces them once its last PR is decided
+	if req.batch == nil {
+		sc.reactions.settle(req.SourceChannel, req.SourceMessage.Timestamp)
+	}
+}
+
+// reconcileQueuedReactions removes the interim reactions left by approvals that were
+// still queued when the bot last stopped, and frees their dedupe claims so they can be
+// requested again. Those approvals were never decided and won't be.
+func (sc *SlackClient) reconcileQueuedReactions(ctx context.Context) {
+	entries, err := sc.store.Entries(ctx, storeNamespaceQueuedReactions)
+	if err != nil {
+		logWarn("Failed to load the reactions of queued approvals: %v", err)
+		return
+	}
+
+	for _, stored := range entries {
+		var entry queuedReaction
+		if err := json.Unmarshal([]byte(stored.Value), &entry); err != nil {
+			logWarn("Dropping unreadable queued reactions of %s: %v", stored.Key, err)
+		} else {
+			logInfo("Approval %s was still queued when the bot stopped; removing its reactions %v", stored.Key, entry.Emojis)
+			for _, emoji := range entry.Emojis {
+				sc.deleteReaction(entry.Channel, entry.Timestamp, emoji)
+			}
+		}
+		sc.dedupe.release(stored.Key)
+		if err := sc.store.Delete(ctx, storeNamespaceQueuedReactions, stored.Key); err != nil {
+			logWarn("Failed to forget queued reactions of %s: %v", stored.Key, err)
+		}
+	}
+}
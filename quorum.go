@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// quorumRetention is how long per-message reactor state is kept after the last change
+const quorumRetention = 24 * time.Hour
+
+// quorumState tracks the distinct users who added a trigger reaction to one message
+type quorumState struct {
+	reactors map[string]bool
+	reached  bool
+	updated  time.Time
+}
+
+// quorumTracker counts trigger reactions per message so that approval fires exactly
+// once, when the number of distinct reactors first reaches the quorum. It is safe for
+// concurrent reaction events.
+type quorumTracker struct {
+	mu       sync.Mutex
+	required int
+	messages map[string]*quorumState
+}
+
+// newQuorumTracker creates a tracker requiring the given number of distinct reactors
+func newQuorumTracker(required int) *quorumTracker {
+	return &quorumTracker{
+		required: required,
+		messages: make(map[string]*quorumState),
+	}
+}
+
+// add records a user's trigger reaction and reports whether it completed the quorum
+func (qt *quorumTracker) add(message, user string) (reached bool, count int) {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	now := time.Now()
+	qt.prune(now)
+
+	state, exists := qt.messages[message]
+	if !exists {
+		state = &quorumState{reactors: make(map[string]bool)}
+		qt.messages[message] = state
+	}
+	state.reactors[user] = true
+	state.updated = now
+
+	if state.reached || len(state.reactors) < qt.required {
+		return false, len(state.reactors)
+	}
+	state.reached = true
+	return true, len(state.reactors)
+}
+
+// remove forgets a user's trigger reaction. Once every reactor is gone the message
+// state is dropped, so the quorum can be reached again from scratch.
+func (qt *quorumTracker) remove(message, user string) {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+
+	state, exists := qt.messages[message]
+	if !exists {
+		return
+	}
+	delete(state.reactors, user)
+	state.updated = time.Now()
+	if len(state.reactors) == 0 {
+		delete(qt.messages, message)
+	}
+}
+
+// prune drops message state that hasn't changed within the retention; callers hold the lock
+func (qt *quorumTracker) prune(now time.Time) {
+	for message, state := range qt.messages {
+		if now.Sub(state.updated) > quorumRetention {
+			delete(qt.messages, message)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuorumReachedOnceUnderConcurrentReactions(t *testing.T) {
+	const required, reactors = 3, 50
+	qt := newQuorumTracker(required)
+
+	var reached atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < reactors; i++ {
+		for repeat := 0; repeat < 4; repeat++ {
+			wg.Add(1)
+			go func(user string) {
+				defer wg.Done()
+				if ok, count := qt.add("C1/1.1", user); ok {
+					reached.Add(1)
+					if count != required {
+						t.Errorf("quorum reached with %d reactors, want %d", count, required)
+					}
+				}
+			}(fmt.Sprintf("U%d", i))
+		}
+	}
+	wg.Wait()
+
+	if got := reached.Load(); got != 1 {
+		t.Errorf("quorum reached %d time(s), want exactly once", got)
+	}
+	if _, count := qt.add("C1/1.1", "U0"); count != reactors {
+		t.Errorf("counted %d distinct reactors, want %d", count, reactors)
+	}
+}
+
+func TestQuorumRepeatedReactorCountsOnce(t *testing.T) {
+	qt := newQuorumTracker(2)
+	for i := 0; i < 3; i++ {
+		if reached, _ := qt.add("C1/1.1", "U1"); reached {
+			t.Fatal("one user reacting repeatedly reached a quorum of 2")
+		}
+	}
+	if reached, count := qt.add("C1/1.1", "U2"); !reached || count != 2 {
+		t.Errorf("second user: reached %v with %d reactors, want the quorum at 2", reached, count)
+	}
+}
+
+func TestQuorumStateCleanup(t *testing.T) {
+	qt := newQuorumTracker(2)
+	qt.add("C1/1.1", "U1")
+	qt.add("C1/1.1", "U2")
+
+	// Once every reactor is gone the message can reach the quorum again
+	qt.remove("C1/1.1", "U1")
+	qt.remove("C1/1.1", "U2")
+	if len(qt.messages) != 0 {
+		t.Errorf("%d message(s) tracked after every reactor left, want none", len(qt.messages))
+	}
+	qt.add("C1/1.1", "U1")
+	if reached, _ := qt.add("C1/1.1", "U2"); !reached {
+		t.Error("quorum not reached again after the reactions were cleared")
+	}
+
+	// Stale messages are pruned on the next reaction to any message
+	qt.messages["C1/1.1"].updated = time.Now().Add(-quorumRetention - time.Minute)
+	qt.add("C1/2.2", "U1")
+	if _, stale := qt.messages["C1/1.1"]; stale {
+		t.Error("message unchanged past the retention was not pruned")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// rateLimitTransport records the GitHub rate-limit headers of every response as gauges
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		recordRateLimitHeaders(resp.Header)
+	}
+	return resp, err
+}
+
+// recordRateLimitHeaders stores the X-RateLimit-* headers, if present
+func recordRateLimitHeaders(header http.Header) {
+	gauges := map[string]string{
+		"X-RateLimit-Limit":     metricGitHubRateLimit,
+		"X-RateLimit-Remaining": metricGitHubRateRemaining,
+		"X-RateLimit-Reset":     metricGitHubRateReset,
+	}
+	for headerName, metric := range gauges {
+		value, err := strconv.ParseFloat(header.Get(headerName), 64)
+		if err != nil {
+			continue
+		}
+		metrics.SetGauge(metric, value)
+	}
+}
+
+// RateLimits fetches the current core rate limit and records it like response headers do
+func (gc *GitHubClient) RateLimits(ctx context.Context) (*github.Rate, error) {
+	limits, _, err := gc.client.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	core := limits.GetCore()
+	if core == nil {
+		return &github.Rate{}, nil
+	}
+	metrics.SetGauge(metricGitHubRateLimit, float64(core.Limit))
+	metrics.SetGauge(metricGitHubRateRemaining, float64(core.Remaining))
+	metrics.SetGauge(metricGitHubRateReset, float64(core.Reset.Unix()))
+	return core, nil
+}
+
+// runRateLimitLog logs the latest known GitHub rate-limit budget every interval until ctx is canceled
+func runRateLimitLog(ctx context.Context, gc *GitHubClient, interval time.Duration) {
+	// Start from an authoritative reading rather than waiting for the first API call
+	if _, err := gc.RateLimits(ctx); err != nil {
+		logDebug("Failed to fetch GitHub rate limits: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reset := time.Unix(int64(metrics.Gauge(metricGitHubRateReset)), 0)
+			logDebug("GitHub rate limit: remaining=%.0f limit=%.0f reset_in=%v",
+				metrics.Gauge(metricGitHubRateRemaining),
+				metrics.Gauge(metricGitHubRateLimit),
+				time.Until(reset).Round(time.Second))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// reactionMemory is how long applied reactions are remembered to drop duplicates
+const reactionMemory = 10 * time.Minute
+
+// reactionBatcher coalesces reaction updates on a message. The progress reaction is
+// delayed by the window and dropped if a terminal reaction arrives first, and a reaction
+// already applied to a message is not sent again. With replace, a terminal reaction also
+// removes the interim reactions, such as processing or a queued approval's paused, sent
+// before it. The hourglass of a delayed approval is always replaced.
+type reactionBatcher struct {
+	mu      sync.Mutex
+	window  time.Duration
+	replace bool
+	add     func(channel, timestamp, emoji string)
+	remove  func(channel, timestamp, emoji string)
+	pending map[string]*time.Timer
+	applied map[string]time.Time
+	// interim lists the interim emoji applied per message, for replace
+	interim map[string][]string
+	// queued lists the queued emoji applied per message, replaced whatever replace says
+	queued map[string][]string
+}
+
+// newReactionBatcher creates a batcher; a zero window applies progress reactions immediately
+func newReactionBatcher(window time.Duration, replace bool, add, remove func(channel, timestamp, emoji string)) *reactionBatcher {
+	return &reactionBatcher{
+		window:  window,
+		replace: replace,
+		add:     add,
+		remove:  remove,
+		pending: make(map[string]*time.Timer),
+		applied: make(map[string]time.Time),
+		interim: make(map[string][]string),
+		queued:  make(map[string][]string),
+	}
+}
+
+// progress applies an in-progress reaction once the window passes without a terminal reaction
+func (rb *reactionBatcher) progress(channel, timestamp, emoji string) {
+	if rb.window <= 0 {
+		rb.interimReaction(channel, timestamp, emoji)
+		return
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	message := channel + "/" + timestamp
+	if _, exists := rb.pending[message]; exists {
+		return
+	}
+	rb.pending[message] = time.AfterFunc(rb.window, func() {
+		rb.mu.Lock()
+		delete(rb.pending, message)
+		rb.mu.Unlock()
+		rb.interimReaction(channel, timestamp, emoji)
+	})
+}
+
+// interimReaction applies a reaction that a later terminal reaction replaces
+func (rb *reactionBatcher) interimReaction(channel, timestamp, emoji string) {
+	rb.mu.Lock()
+	if !rb.claim(channel, timestamp, emoji) {
+		rb.mu.Unlock()
+		return
+	}
+	message := channel + "/" + timestamp
+	rb.interim[message] = append(rb.interim[message], emoji)
+	rb.mu.Unlock()
+
+	rb.add(channel, timestamp, emoji)
+}
+
+// queuedReaction applies the reaction of a delayed approval, which the terminal reaction
+// replaces even without replace
+func (rb *reactionBatcher) queuedReaction(channel, timestamp, emoji string) {
+	rb.mu.Lock()
+	if !rb.claim(channel, timestamp, emoji) {
+		rb.mu.Unlock()
+		return
+	}
+	message := channel + "/" + timestamp
+	rb.queued[message] = append(rb.queued[message], emoji)
+	rb.mu.Unlock()
+
+	rb.add(channel, timestamp, emoji)
+}
+
+// final applies a terminal reaction, cancelling a progress reaction that hasn't been sent
+// yet and removing the queued reactions and, with replace, the interim reactions already sent
+func (rb *reactionBatcher) final(channel, timestamp, emoji string) {
+	rb.mu.Lock()
+	message := channel + "/" + timestamp
+	if timer, exists := rb.pending[message]; exists && timer.Stop() {
+		delete(rb.pending, message)
+		logDebug("Skipped progress reaction on message %s", timestamp)
+	}
+
+	if !rb.claim(channel, timestamp, emoji) {
+		rb.mu.Unlock()
+		return
+	}
+
+	// Interim reactions stay claimed, so they are removed once and not sent again
+	var replaced []string
+	if rb.replace {
+		for _, interim := range rb.interim[message] {
+			if interim != emoji {
+				replaced = append(replaced, interim)
+			}
+		}
+		delete(rb.interim, message)
+	}
+	for _, queued := range rb.queued[message] {
+		if queued != emoji {
+			replaced = append(replaced, queued)
+		}
+	}
+	delete(rb.queued, message)
+	rb.mu.Unlock()
+
+	rb.add(channel, timestamp, emoji)
+	for _, interim := range replaced {
+		rb.remove(channel, timestamp, interim)
+	}
+}
+
+// settle removes the queued reactions of a message whose approval ended without a
+// terminal reaction, such as one skipped for a GitHub error
+func (rb *reactionBatcher) settle(channel, timestamp string) {
+	rb.mu.Lock()
+	message := channel + "/" + timestamp
+	queued := rb.queued[message]
+	delete(rb.queued, message)
+	rb.mu.Unlock()
+
+	for _, emoji := range queued {
+		rb.remove(channel, timestamp, emoji)
+	}
+}
+
+// claim records a reaction as applied, reporting false when it already was.
+// Entries older than reactionMemory are swept first. The caller holds mu.
+func (rb *reactionBatcher) claim(channel, timestamp, emoji string) bool {
+	now := time.Now()
+	for key, appliedAt := range rb.applied {
+		if now.Sub(appliedAt) > reactionMemory {
+			delete(rb.applied, key)
+		}
+	}
+	for _, lists := range []map[string][]string{rb.interim, rb.queued} {
+		for message, emojis := range lists {
+			if _, exists := rb.applied[message+":"+emojis[0]]; !exists {
+				delete(lists, message)
+			}
+		}
+	}
+
+	key := channel + "/" + timestamp + ":" + emoji
+	if _, exists := rb.applied[key]; exists {
+		return false
+	}
+	rb.applied[key] = now
+	return true
+}
+
+// forget allows a removed reaction to be applied again
+func (rb *reactionBatcher) forget(channel, timestamp, emoji string) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	delete(rb.applied, channel+"/"+timestamp+":"+emoji)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReactionBatcherSkipsProgressForQuickOutcomes(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(time.Hour, false, recorder.add, recorder.remove)
+
+	rb.progress("C1", "1.1", "eyes")
+	rb.progress("C1", "1.1", "eyes")
+	rb.final("C1", "1.1", "white_check_mark")
+
+	if want := []string{"white_check_mark"}; !reflect.DeepEqual(recorder.added, want) {
+		t.Errorf("added %v, want %v", recorder.added, want)
+	}
+}
+
+func TestReactionBatcherAppliesProgressAfterWindow(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(time.Millisecond, false, recorder.add, recorder.remove)
+
+	rb.progress("C1", "1.1", "eyes")
+	deadline := time.Now().Add(5 * time.Second)
+	for !recorder.has("eyes") {
+		if time.Now().After(deadline) {
+			t.Fatal("progress reaction never applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	rb.final("C1", "1.1", "white_check_mark")
+
+	if want := []string{"eyes", "white_check_mark"}; !reflect.DeepEqual(recorder.added, want) {
+		t.Errorf("added %v, want %v", recorder.added, want)
+	}
+	if len(recorder.removed) != 0 {
+		t.Errorf("removed %v without replace, want nothing", recorder.removed)
+	}
+}
+
+func TestReactionBatcherDropsDuplicates(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(0, false, recorder.add, recorder.remove)
+
+	rb.final("C1", "1.1", "white_check_mark")
+	rb.final("C1", "1.1", "white_check_mark")
+	rb.final("C1", "2.2", "white_check_mark")
+	if len(recorder.added) != 2 {
+		t.Errorf("added %v, want one reaction per message", recorder.added)
+	}
+
+	// A removed reaction may be applied again
+	rb.forget("C1", "1.1", "white_check_mark")
+	rb.final("C1", "1.1", "white_check_mark")
+	if len(recorder.added) != 3 {
+		t.Errorf("added %v after forgetting, want the reaction again", recorder.added)
+	}
+}
+
+func TestReactionCoalesceWindowValidation(t *testing.T) {
+	if err := validateConfiguration(validConfig(t, "--reaction-coalesce-window", "2s")); err != nil {
+		t.Errorf("validateConfiguration: %v", err)
+	}
+	config := validConfig(t)
+	config.ReactionCoalesceWindow = -time.Second
+	var configErr *ConfigError
+	if err := validateConfiguration(config); !errors.As(err, &configErr) || configErr.Field != "ReactionCoalesceWindow" {
+		t.Errorf("validateConfiguration with a negative window = %v, want a ReactionCoalesceWindow error", err)
+	}
+}
+
+func TestReactionBatcherReplacesInterimReactions(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(0, true, recorder.add, recorder.remove)
+
+	// A retried approval asks for the progress reaction on every attempt
+	rb.progress("C1", "1.1", "eyes")
+	rb.progress("C1", "1.1", "eyes")
+	rb.final("C1", "1.1", "white_check_mark")
+	rb.final("C1", "1.1", "white_check_mark")
+	rb.progress("C1", "1.1", "eyes")
+
+	if want := []string{"eyes", "white_check_mark"}; !reflect.DeepEqual(recorder.added, want) {
+		t.Errorf("added %v, want %v", recorder.added, want)
+	}
+	if want := []string{"eyes"}; !reflect.DeepEqual(recorder.removed, want) {
+		t.Errorf("removed %v, want %v", recorder.removed, want)
+	}
+}
+
+func TestReactionBatcherKeepsTerminalInterimReaction(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(0, true, recorder.add, recorder.remove)
+
+	rb.interimReaction("C1", "1.1", "eyes")
+	rb.final("C1", "1.1", "eyes")
+
+	if len(recorder.removed) != 0 {
+		t.Errorf("removed %v, want the interim reaction kept when it is also the outcome", recorder.removed)
+	}
+}
+
+func TestReactionBatcherReplacesQueuedReactions(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace bool
+	}{
+		{name: "with replace", replace: true},
+		{name: "without replace", replace: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &reactionRecorder{}
+			rb := newReactionBatcher(0, tt.replace, recorder.add, recorder.remove)
+
+			rb.queuedReaction("C1", "1.1", "double_vertical_bar")
+			rb.final("C1", "1.1", "white_check_mark")
+
+			if want := []string{"double_vertical_bar"}; !reflect.DeepEqual(recorder.removed, want) {
+				t.Errorf("removed %v, want %v", recorder.removed, want)
+			}
+		})
+	}
+}
+
+func TestReactionBatcherSettlesQueuedReactions(t *testing.T) {
+	recorder := &reactionRecorder{}
+	rb := newReactionBatcher(0, false, recorder.add, recorder.remove)
+
+	rb.queuedReaction("C1", "1.1", "double_vertical_bar")
+	rb.settle("C1", "1.1")
+	rb.settle("C1", "1.1")
+	rb.settle("C1", "2.2")
+
+	if want := []string{"double_vertical_bar"}; !reflect.DeepEqual(recorder.removed, want) {
+		t.Errorf("removed %v, want the queued reaction removed once", recorder.removed)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// reactionMessageData is the data available to trigger reaction review templates
+type reactionMessageData struct {
+	Owner      string
+	Repository string
+	PRNumber   int
+	PR         string
+	Emoji      string
+	SlackUser  string
+	GitHubUser string
+}
+
+// parseReactionMessageFlags parses emoji=template pairs
+func parseReactionMessageFlags(values []string) (map[string]string, error) {
+	pairs, err := parseKeyValues(values, "TriggerReactionMessages", "trigger reaction message", "emoji=template")
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string]string)
+	for _, pair := range pairs {
+		messages[normalizeEmoji(pair.key)] = pair.value
+	}
+	return messages, nil
+}
+
+// parseReactionMessages compiles the review body template of each trigger emoji
+func parseReactionMessages(messages map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for emoji, text := range messages {
+		tmpl, err := template.New("reaction-" + emoji).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("review template for :%s: %v", emoji, err)
+		}
+		templates[normalizeEmoji(emoji)] = tmpl
+	}
+	return templates, nil
+}
+
+// reactionReviewMessage renders the review body for the emoji that triggered an approval.
+// It reports false when the emoji has no template or rendering fails, leaving the default body.
+func (sc *SlackClient) reactionReviewMessage(emoji string, req *ApprovalRequest, githubLogin string) (string, bool) {
+	tmpl, ok := sc.reactionMessages[normalizeEmoji(emoji)]
+	if !ok {
+		return "", false
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, reactionMessageData{
+		Owner:      req.Owner,
+		Repository: req.Repository,
+		PRNumber:   req.PRNumber,
+		PR:         fmt.Sprintf("%s/%s#%d", req.Owner, req.Repository, req.PRNumber),
+		Emoji:      normalizeEmoji(emoji),
+		SlackUser:  req.SourceUser,
+		GitHubUser: githubLogin,
+	}); err != nil {
+		logWarn("Failed to render review message for :%s: on %s/%s#%d: %v", normalizeEmoji(emoji), req.Owner, req.Repository, req.PRNumber, err)
+		return "", false
+	}
+	return text.String(), true
+}
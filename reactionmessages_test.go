@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReactionMessageFlags(t *testing.T) {
+	messages, err := parseReactionMessageFlags([]string{":shipit:=Shipped by {{.SlackUser}}, via Slack", "rocket=a=b"})
+	if err != nil {
+		t.Fatalf("parseReactionMessageFlags: %v", err)
+	}
+	want := map[string]string{"shipit": "Shipped by {{.SlackUser}}, via Slack", "rocket": "a=b"}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages = %v, want %v", messages, want)
+	}
+
+	if _, err := parseReactionMessageFlags([]string{"Shipped, via Slack"}); err == nil {
+		t.Error("parseReactionMessageFlags accepted a message without an emoji")
+	}
+}
+
+func TestTriggerReactionMessageKeepsCommas(t *testing.T) {
+	config := parseRunFlags(t, "--trigger-reaction-message", "shipit=Shipped, reviewed by {{.SlackUser}}")
+	if got := config.TriggerReactionMessages["shipit"]; got != "Shipped, reviewed by {{.SlackUser}}" {
+		t.Errorf("shipit message = %q, want the whole value", got)
+	}
+
+	t.Setenv("TRIGGER_REACTION_MESSAGES", "shipit=Shipped, thanks\nrocket=To the moon, {{.PR}}")
+	config = parseRunFlags(t)
+	want := map[string]string{"shipit": "Shipped, thanks", "rocket": "To the moon, {{.PR}}"}
+	if !reflect.DeepEqual(config.TriggerReactionMessages, want) {
+		t.Errorf("TriggerReactionMessages = %v, want one message per line %v", config.TriggerReactionMessages, want)
+	}
+}
+
+func TestReactionReviewMessage(t *testing.T) {
+	templates, err := parseReactionMessages(map[string]string{"shipit": "Shipped {{.PR}}, thanks {{.GitHubUser}}"})
+	if err != nil {
+		t.Fatalf("parseReactionMessages: %v", err)
+	}
+	sc := &SlackClient{reactionMessages: templates}
+	req := &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, SourceUser: "U1"}
+
+	if got, ok := sc.reactionReviewMessage(":shipit:", req, "octocat"); !ok || got != "Shipped o/r#1, thanks octocat" {
+		t.Errorf("reactionReviewMessage = %q, %v, want the rendered template", got, ok)
+	}
+	if _, ok := sc.reactionReviewMessage("rocket", req, "octocat"); ok {
+		t.Error("reactionReviewMessage rendered a message for an emoji without a template")
+	}
+
+	if _, err := parseReactionMessages(map[string]string{"shipit": "{{.PR"}); err == nil {
+		t.Error("parseReactionMessages accepted an unparseable template")
+	}
+}
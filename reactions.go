@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Outcome classes that the bot reacts to a message with
+const (
+	outcomeProcessing    = "processing"
+	outcomeApproved      = "approved"
+	outcomeSkippedDraft  = "skipped_draft"
+	outcomeSkippedChecks = "skipped_checks"
+	outcomeSkippedBehind = "skipped_behind"
+	outcomeFailed        = "failed"
+	outcomeDenied        = "denied"
+	outcomeNoPR          = "no_pr"
+	outcomeArmed         = "armed"
+	outcomeAmbiguous     = "ambiguous"
+	outcomePaused        = "paused"
+	outcomePartial       = "partial"
+	outcomeThrottled     = "throttled"
+	outcomeDegraded      = "degraded"
+	outcomeFrozen        = "frozen"
+	outcomeOverloaded    = "overloaded"
+	outcomeDraining      = "draining"
+	outcomeWaiting       = "waiting"
+	outcomeQueued        = "queued"
+	outcomeDryRun        = "dry_run"
+	outcomeNotAllowed    = "not_allowed"
+	outcomeReviewed      = "reviewed"
+	outcomeFallback      = "fallback"
+)
+
+// defaultOutcomeReactions is the emoji used for each outcome unless overridden by Reactions
+var defaultOutcomeReactions = map[string]string{
+	outcomeProcessing:    "eyes",
+	outcomeApproved:      "white_check_mark",
+	outcomeSkippedDraft:  "construction",
+	outcomeSkippedChecks: "hourglass",
+	outcomeSkippedBehind: "arrows_counterclockwise",
+	outcomeFailed:        "x",
+	outcomeDenied:        "no_entry",
+	outcomeNoPR:          "x",
+	outcomeArmed:         "raised_hand",
+	outcomeAmbiguous:     "grey_question",
+	outcomePaused:        "double_vertical_bar",
+	outcomePartial:       "warning",
+	outcomeThrottled:     "snail",
+	outcomeDegraded:      "hammer_and_wrench",
+	outcomeFrozen:        "lock",
+	outcomeOverloaded:    "no_entry_sign",
+	outcomeDraining:      "door",
+	outcomeWaiting:       "hourglass_flowing_sand",
+	outcomeQueued:        "hourglass_flowing_sand",
+	outcomeDryRun:        "test_tube",
+	outcomeNotAllowed:    "no_entry",
+	outcomeReviewed:      "speech_balloon",
+	outcomeFallback:      "ballot_box_with_check",
+}
+
+// parseOutcomeReactions parses outcome=emoji pairs into a reaction map
+func parseOutcomeReactions(values []string) (map[string]string, error) {
+	pairs, err := parseKeyValues(values, "Reactions", "reaction", "outcome=emoji")
+	if err != nil {
+		return nil, err
+	}
+	reactions := make(map[string]string)
+	for _, pair := range pairs {
+		reactions[pair.key] = normalizeEmoji(pair.value)
+	}
+	return reactions, nil
+}
+
+// outcomeReaction returns the emoji configured for an outcome, falling back to the default
+func (config *Configuration) outcomeReaction(outcome string) string {
+	if emoji := normalizeEmoji(config.Reactions[outcome]); emoji != "" {
+		return emoji
+	}
+	return defaultOutcomeReactions[outcome]
+}
+
+// skipOutcome classifies a validation error, or returns empty for errors that get no reaction
+func skipOutcome(err error) string {
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return ""
+	}
+	switch policyErr.Policy {
+	case "draft":
+		return outcomeSkippedDraft
+	case "completed-check":
+		return outcomeSkippedChecks
+	case "up-to-date":
+		return outcomeSkippedBehind
+	default:
+		return outcomeDenied
+	}
+}
+
+// react adds the reaction configured for an outcome to a message
+func (sc *SlackClient) react(channel, timestamp, outcome string) {
+	emoji := sc.config.outcomeReaction(outcome)
+	if outcome == outcomeProcessing {
+		sc.reactions.progress(channel, timestamp, emoji)
+		return
+	}
+	sc.reactions.final(channel, timestamp, emoji)
+}
+
+// normalizeEmoji strips surrounding colons so ":rocket:" and "rocket" compare equal
+func normalizeEmoji(emoji string) string {
+	return strings.Trim(strings.TrimSpace(emoji), ":")
+}
+
+// containsEmoji reports whether an emoji is in a list, ignoring surrounding colons
+func containsEmoji(emojis []string, emoji string) bool {
+	emoji = normalizeEmoji(emoji)
+	for _, candidate := range emojis {
+		if normalizeEmoji(candidate) == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// isTriggerReaction reports whether an emoji is in the configured trigger allowlist
+func (sc *SlackClient) isTriggerReaction(emoji string) bool {
+	return containsEmoji(sc.config.TriggerReactions, emoji)
+}
+
+// staleReaction reports whether a reaction event is too old to act on. Events missing
+// either timestamp, or reacting before the message existed, are treated as stale.
+func staleReaction(event *slackevents.ReactionAddedEvent, maxAge time.Duration, now time.Time) (bool, string) {
+	reactedAt, ok := parseSlackTimestamp(event.EventTimestamp)
+	if !ok {
+		return true, "event has no timestamp"
+	}
+	postedAt, ok := parseSlackTimestamp(event.Item.Timestamp)
+	if !ok {
+		return true, "message has no timestamp"
+	}
+	if reactedAt.Before(postedAt) {
+		return true, "reaction predates the message"
+	}
+	if age := now.Sub(reactedAt); age > maxAge {
+		return true, fmt.Sprintf("reaction is %v old", age.Round(time.Second))
+	}
+	return false, ""
+}
+
+// parseSlackTimestamp parses a Slack ts such as "1712345678.123456"
+func parseSlackTimestamp(ts string) (time.Time, bool) {
+	seconds, fraction, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	var usec int64
+	if fraction != "" {
+		fraction = (fraction + "000000")[:6]
+		if usec, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)), true
+}
+
+// handleReactionAdded approves the PRs in a message when a trigger reaction is added to it
+func (sc *SlackClient) handleReactionAdded(ctx context.Context, event *slackevents.ReactionAddedEvent) {
+	if !sc.config.ReactionTrigger {
+		return
+	}
+
+	if event.Item.Type != "message" {
+		return
+	}
+
+	// Skip if channel filtering is enabled and this reaction is from a different channel
+	if sc.config.SlackChannelID != "" && event.Item.Channel != sc.config.SlackChannelID {
+		return
+	}
+
+	// Ignore our own progress reactions
+	if sc.botUserID != "" && event.User == sc.botUserID {
+		return
+	}
+
+	if !sc.isTriggerReaction(event.Reaction) {
+		logDebug("Ignoring non-trigger reaction %s in channel %s", event.Reaction, event.Item.Channel)
+		return
+	}
+
+	// Reactions replayed after a reconnect must not approve again
+	if sc.config.ReactionMaxAge > 0 {
+		if stale, reason := staleReaction(event, sc.config.ReactionMaxAge, time.Now()); stale {
+			logInfo("Ignoring trigger reaction %s in channel %s by user %s: %s", event.Reaction, event.Item.Channel, sc.userLabel(ctx, event.User), reason)
+			return
+		}
+	}
+
+	// With an allowlist, only allowed users' reactions count, also towards a quorum
+	if !sc.config.slackUserAllowed(event.User) {
+		logInfo("Ignoring trigger reaction %s in channel %s by user %s: not an allowed user", event.Reaction, event.Item.Channel, sc.userLabel(ctx, event.User))
+		return
+	}
+
+	logInfo("Trigger reaction %s added in channel %s by user %s", event.Reaction, event.Item.Channel, sc.userLabel(ctx, event.User))
+
+	if sc.config.TriggerQuorum > 1 {
+		reached, count := sc.quorum.add(event.Item.Channel+"/"+event.Item.Timestamp, event.User)
+		if !reached {
+			logInfo("Trigger quorum for message %s: %d of %d", event.Item.Timestamp, count, sc.config.TriggerQuorum)
+			return
+		}
+		logInfo("Trigger quorum of %d reached for message %s", sc.config.TriggerQuorum, event.Item.Timestamp)
+	}
+
+	message, err := sc.fetchMessage(ctx, event.Item.Channel, event.Item.Timestamp)
+	if err != nil {
+		logError("Failed to fetch reacted message %s in channel %s: %v", event.Item.Timestamp, event.Item.Channel, err)
+		return
+	}
+
+	slackMsg := &SlackMessage{
+		Text:      message.Text,
+		Channel:   event.Item.Channel,
+		User:      event.User,
+		Timestamp: event.Item.Timestamp,
+		ThreadTS:  message.ThreadTimestamp,
+	}
+
+	if sc.config.MaxMessageAge > 0 {
+		if stale, reason := sc.config.staleMessage(slackMsg, time.Now()); stale {
+			logInfo("Ignoring trigger reaction %s on message %s in channel %s: %s", event.Reaction, event.Item.Timestamp, event.Item.Channel, reason)
+			return
+		}
+	}
+
+	prRefs, err := sc.matcher.ExtractPRReferences(sc.matchText(slackMsg.Text))
+	if err != nil {
+		logError("Failed to extract PR references: %v", err)
+		return
+	}
+
+	match := &PatternMatch{
+		Pattern:         ":" + normalizeEmoji(event.Reaction) + ":",
+		PRReferences:    prRefs,
+		SourceMessage:   slackMsg,
+		TriggerReaction: event.Reaction,
+	}
+
+	if len(match.PRReferences) == 0 {
+		logInfo("Trigger reaction added but no PR references found in message")
+		sc.react(slackMsg.Channel, slackMsg.Timestamp, outcomeNoPR)
+		return
+	}
+
+	if sc.config.RequireAuthorReaction {
+		match.PRReferences = sc.authoredByReactor(ctx, event.Item.Channel, match.PRReferences, event.User)
+		if len(match.PRReferences) == 0 {
+			logInfo("Ignoring trigger reaction by user %s: not the mapped author of any PR in the message", event.User)
+			return
+		}
+	}
+
+	sc.processPRApprovals(ctx, match)
+}
+
+// authoredByReactor keeps the PRs whose GitHub author is mapped to the reacting Slack user.
+// Only configured mappings count, so no one can claim an author's login for themselves.
+// Users in AuthorReactionOverrides may trigger approval of any PR.
+func (sc *SlackClient) authoredByReactor(ctx context.Context, channel string, prRefs []PRReference, user string) []PRReference {
+	for _, override := range sc.config.AuthorReactionOverrides {
+		if override == user {
+			return prRefs
+		}
+	}
+
+	login, mapped := sc.users.lookupConfigured(user)
+	if !mapped {
+		logDebug("Slack user %s has no configured GitHub mapping, so can't be a PR author", user)
+		return nil
+	}
+
+	var authored []PRReference
+	for _, prRef := range prRefs {
+		if prRef.linked() {
+			resolved, err := sc.resolveLinkedReference(ctx, prRef)
+			if err != nil {
+				logWarn("Skipping %s in %s/%s: %v", prRef.linkDescription(), prRef.Owner, prRef.Repository, err)
+				continue
+			}
+			prRef = resolved
+		}
+
+		owner, repo, ok := sc.resolvePRTarget(channel, prRef)
+		if !ok {
+			continue
+		}
+
+		author, err := sc.githubClient.PRAuthor(ctx, owner, repo, prRef.Number)
+		if err != nil {
+			logWarn("Skipping PR %s/%s#%d: %v", owner, repo, prRef.Number, err)
+			continue
+		}
+		if !strings.EqualFold(author, login) {
+			logDebug("Skipping PR %s/%s#%d: authored by %s, not %s", owner, repo, prRef.Number, author, login)
+			continue
+		}
+		authored = append(authored, prRef)
+	}
+	return authored
+}
+
+// handleReactionRemoved dismisses the bot's approvals of the PRs in a message when
+// the last trigger reaction is removed from it
+func (sc *SlackClient) handleReactionRemoved(ctx context.Context, event *slackevents.ReactionRemovedEvent) {
+	if sc.config.ReactionTrigger && sc.config.TriggerQuorum > 1 && sc.isTriggerReaction(event.Reaction) {
+		sc.quorum.remove(event.Item.Channel+"/"+event.Item.Timestamp, event.User)
+	}
+
+	if !sc.config.ReactionTrigger || !sc.config.DismissOnReactionRemoved {
+		return
+	}
+
+	if event.Item.Type != "message" {
+		return
+	}
+
+	if sc.config.SlackChannelID != "" && event.Item.Channel != sc.config.SlackChannelID {
+		return
+	}
+
+	if sc.botUserID != "" && event.User == sc.botUserID {
+		return
+	}
+
+	if !sc.isTriggerReaction(event.Reaction) {
+		return
+	}
+
+	message, err := sc.fetchMessage(ctx, event.Item.Channel, event.Item.Timestamp)
+	if err != nil {
+		logError("Failed to fetch message %s in channel %s: %v", event.Item.Timestamp, event.Item.Channel, err)
+		return
+	}
+
+	// Someone else's trigger reaction still stands behind the approval
+	for _, reaction := range message.Reactions {
+		if !sc.isTriggerReaction(reaction.Name) {
+			continue
+		}
+		for _, user := range reaction.Users {
+			if user != sc.botUserID {
+				logInfo("Trigger reaction %s removed in channel %s, but another trigger reaction remains", event.Reaction, event.Item.Channel)
+				return
+			}
+		}
+	}
+
+	logInfo("Trigger reaction %s removed in channel %s by user %s", event.Reaction, event.Item.Channel, sc.userLabel(ctx, event.User))
+
+	prRefs, err := sc.matcher.ExtractPRReferences(sc.matchText(message.Text))
+	if err != nil {
+		logError("Failed to extract PR references: %v", err)
+		return
+	}
+
+	slackMsg := &SlackMessage{
+		Text:      message.Text,
+		Channel:   event.Item.Channel,
+		User:      event.User,
+		Timestamp: event.Item.Timestamp,
+		ThreadTS:  message.ThreadTimestamp,
+	}
+
+	reason := fmt.Sprintf("Approval withdrawn: trigger reaction removed in Slack by %s", sc.userLabel(ctx, event.User))
+	for _, prRef := range prRefs {
+		owner, repo, ok := sc.resolvePRTarget(event.Item.Channel, prRef)
+		if !ok {
+			continue
+		}
+
+		// Re-adding the reaction approves again
+		sc.dedupe.release(approvalKey(&ApprovalRequest{
+			Owner:         owner,
+			Repository:    repo,
+			PRNumber:      prRef.Number,
+			SourceChannel: slackMsg.Channel,
+			SourceMessage: slackMsg,
+		}))
+
+		if sc.config.DryRun {
+			logInfo("[DRY_RUN] would dismiss approval of PR %s/%s#%d", owner, repo, prRef.Number)
+			continue
+		}
+		dismissed, err := sc.githubClient.DismissBotApproval(ctx, owner, repo, prRef.Number, reason)
+		if err != nil {
+			logError("Failed to dismiss approval of PR %s/%s#%d: %v", owner, repo, prRef.Number, err)
+			continue
+		}
+		if !dismissed {
+			logInfo("No approval by the bot to dismiss on PR %s/%s#%d", owner, repo, prRef.Number)
+			continue
+		}
+		logInfo("Dismissed approval of PR %s/%s#%d", owner, repo, prRef.Number)
+	}
+
+	sc.removeReaction(slackMsg.Channel, slackMsg.Timestamp, sc.config.outcomeReaction(outcomeApproved))
+}
+
+// fetchMessage loads a single message by channel and timestamp, including thread replies
+func (sc *SlackClient) fetchMessage(ctx context.Context, channel, timestamp string) (*slack.Message, error) {
+	history, err := sc.slackAPI().GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+		ChannelID: channel,
+		Latest:    timestamp,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range history.Messages {
+		if history.Messages[i].Timestamp == timestamp {
+			return &history.Messages[i], nil
+		}
+	}
+
+	// Thread replies don't appear in channel history
+	replies, _, _, err := sc.slackAPI().GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channel,
+		Timestamp: timestamp,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range replies {
+		if replies[i].Timestamp == timestamp {
+			return &replies[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("message %s not found", timestamp)
+}
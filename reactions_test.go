@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// serveSlackMessage answers every Slack API call of sc, including the history lookup
+// of a reacted message, with message
+func serveSlackMessage(t *testing.T, sc *SlackClient, message map[string]interface{}) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channel": "C1", "ts": "1.1", "messages": []interface{}{message}})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+}
+
+// reactionAdded is user's reaction to the message at 1700000000.000100 in channel C1,
+// added a second after it was posted
+func reactionAdded(user, reaction string) *slackevents.ReactionAddedEvent {
+	event := &slackevents.ReactionAddedEvent{User: user, Reaction: reaction, EventTimestamp: "1700000001.000100"}
+	event.Item.Type = "message"
+	event.Item.Channel = "C1"
+	event.Item.Timestamp = "1700000000.000100"
+	return event
+}
+
+func TestReactionTrigger(t *testing.T) {
+	tests := []struct {
+		name         string
+		reaction     string
+		text         string
+		wantReviews  int
+		wantReaction string
+	}{
+		{name: "trigger reaction", reaction: "white_check_mark", text: "please review https://github.com/o/r/pull/1", wantReviews: 1, wantReaction: "white_check_mark"},
+		{name: "other reaction", reaction: "eyes", text: "please review https://github.com/o/r/pull/1"},
+		{name: "no PR in the message", reaction: "white_check_mark", text: "please review", wantReaction: "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, &Configuration{ReactionTrigger: true, TriggerReactions: []string{":white_check_mark:"}}, gh)
+			serveSlackMessage(t, sc, map[string]interface{}{"ts": "1700000000.000100", "text": tt.text})
+
+			sc.handleReactionAdded(context.Background(), reactionAdded("U1", tt.reaction))
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != tt.wantReviews {
+				t.Errorf("submitted %v, want %d review(s)", reviews, tt.wantReviews)
+			}
+			if tt.wantReaction != "" && !reactions.has(tt.wantReaction) {
+				t.Errorf("reactions %v, want %s", reactions.added, tt.wantReaction)
+			}
+		})
+	}
+}
+
+func TestReactionTriggerWithoutMessage(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{ReactionTrigger: true, TriggerReactions: []string{"white_check_mark"}}, gh)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": "message_not_found"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	sc.handleReactionAdded(context.Background(), reactionAdded("U1", "white_check_mark"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 || len(reactions.added) != 0 {
+		t.Errorf("submitted %v and reacted %v for a message that couldn't be fetched", reviews, reactions.added)
+	}
+}
+
+func TestReactionTriggerValidation(t *testing.T) {
+	var configErr *ConfigError
+	if err := validateConfiguration(validConfig(t, "--reaction-trigger")); !errors.As(err, &configErr) || configErr.Field != "TriggerReactions" {
+		t.Errorf("validateConfiguration without trigger reactions = %v, want a TriggerReactions error", err)
+	}
+}
+
+func TestAuthoredByReactor(t *testing.T) {
+	refs := []PRReference{{Owner: "o", Repository: "r", Number: 1}}
+	tests := []struct {
+		name     string
+		config   *Configuration
+		setup    func(sc *SlackClient)
+		wantRefs int
+	}{
+		{
+			name:     "configured author mapping",
+			config:   &Configuration{UserMappings: map[string]string{"U1": "author"}},
+			wantRefs: 1,
+		},
+		{
+			name:     "someone else's PR",
+			config:   &Configuration{UserMappings: map[string]string{"U1": "octocat"}},
+			wantRefs: 0,
+		},
+		{
+			// Anyone can DM "map me as author", so that mapping proves nothing
+			name:     "self-mapped to the author",
+			config:   &Configuration{EnableSelfServiceMapping: true},
+			setup:    func(sc *SlackClient) { sc.users.set("U1", "author") },
+			wantRefs: 0,
+		},
+		{
+			name:     "override",
+			config:   &Configuration{AuthorReactionOverrides: []string{"U1"}},
+			wantRefs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newTestSlackClient(t, tt.config, &fakeGitHub{author: "author"})
+			if tt.setup != nil {
+				tt.setup(sc)
+			}
+			if got := sc.authoredByReactor(context.Background(), "C1", refs, "U1"); len(got) != tt.wantRefs {
+				t.Errorf("authoredByReactor = %v, want %d PR(s)", got, tt.wantRefs)
+			}
+		})
+	}
+}
+
+func TestDismissOnReactionRemoved(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+		// remaining are the users still reacting with the trigger reaction
+		remaining     []string
+		wantDismissed int
+	}{
+		{name: "last trigger reaction removed", wantDismissed: 1},
+		{name: "another trigger reaction remains", remaining: []string{"U2"}, wantDismissed: 0},
+		{name: "dry run", dryRun: true, wantDismissed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{reviews: []string{"APPROVE"}}
+			config := &Configuration{
+				ReactionTrigger:          true,
+				DismissOnReactionRemoved: true,
+				TriggerReactions:         []string{"white_check_mark"},
+				DryRun:                   tt.dryRun,
+			}
+			sc, _ := newTestSlackClient(t, config, gh)
+
+			message := map[string]interface{}{"ts": "1700000000.000100", "text": "https://github.com/o/r/pull/1"}
+			if len(tt.remaining) > 0 {
+				message["reactions"] = []map[string]interface{}{{"name": "white_check_mark", "users": tt.remaining}}
+			}
+			serveSlackMessage(t, sc, message)
+
+			event := &slackevents.ReactionRemovedEvent{User: "U1", Reaction: "white_check_mark"}
+			event.Item.Type = "message"
+			event.Item.Channel = "C1"
+			event.Item.Timestamp = "1700000000.000100"
+			sc.handleReactionRemoved(context.Background(), event)
+
+			if got := len(gh.dismissals()); got != tt.wantDismissed {
+				t.Errorf("dismissed %d review(s), want %d", got, tt.wantDismissed)
+			}
+			if tt.dryRun && gh.requested("GET /repos/o/r/pulls/1/reviews") {
+				t.Error("dry run looked up reviews to dismiss")
+			}
+		})
+	}
+}
+
+func TestStaleReaction(t *testing.T) {
+	now := time.Unix(1700000061, 0)
+	tests := []struct {
+		name       string
+		eventTS    string
+		messageTS  string
+		wantStale  bool
+		wantReason string
+	}{
+		{name: "fresh", eventTS: "1700000031.000100", messageTS: "1700000000.000100"},
+		{name: "stale", eventTS: "1700000001.000100", messageTS: "1700000000.000100", wantStale: true, wantReason: "reaction is 1m0s old"},
+		{name: "no event timestamp", messageTS: "1700000000.000100", wantStale: true, wantReason: "event has no timestamp"},
+		{name: "no message timestamp", eventTS: "1700000031.000100", wantStale: true, wantReason: "message has no timestamp"},
+		{name: "malformed timestamp", eventTS: "soon", messageTS: "1700000000.000100", wantStale: true, wantReason: "event has no timestamp"},
+		{name: "reaction before the message", eventTS: "1700000031.000100", messageTS: "1700000040.000100", wantStale: true, wantReason: "reaction predates the message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &slackevents.ReactionAddedEvent{EventTimestamp: tt.eventTS}
+			event.Item.Timestamp = tt.messageTS
+			stale, reason := staleReaction(event, 45*time.Second, now)
+			if stale != tt.wantStale || reason != tt.wantReason {
+				t.Errorf("staleReaction = %v, %q, want %v, %q", stale, reason, tt.wantStale, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestStaleReactionIsIgnored(t *testing.T) {
+	tests := []struct {
+		name        string
+		reactedAt   time.Time
+		wantReviews int
+	}{
+		{name: "fresh", reactedAt: time.Now(), wantReviews: 1},
+		{name: "replayed", reactedAt: time.Now().Add(-2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{ReactionTrigger: true, TriggerReactions: []string{"white_check_mark"}, ReactionMaxAge: time.Hour}, gh)
+			serveSlackMessage(t, sc, map[string]interface{}{"ts": "1700000000.000100", "text": "please review https://github.com/o/r/pull/1"})
+
+			event := reactionAdded("U1", "white_check_mark")
+			event.EventTimestamp = fmt.Sprintf("%d.000100", tt.reactedAt.Unix())
+			sc.handleReactionAdded(context.Background(), event)
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != tt.wantReviews {
+				t.Errorf("submitted %v, want %d review(s)", reviews, tt.wantReviews)
+			}
+		})
+	}
+}
+
+func TestReactionMaxAgeValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--reaction-max-age", "-1m"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "ReactionMaxAge" {
+		t.Errorf("validateConfiguration = %v, want a ReactionMaxAge error", err)
+	}
+}
+
+func TestParseOutcomeReactions(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", want: map[string]string{}},
+		{name: "overrides", values: []string{"approved=rocket", "denied=:stop_sign:"}, want: map[string]string{"approved": "rocket", "denied": "stop_sign"}},
+		{name: "last one wins", values: []string{"approved=rocket", "approved=tada"}, want: map[string]string{"approved": "tada"}},
+		{name: "unknown outcome is left to validation", values: []string{"shipped=ship"}, want: map[string]string{"shipped": "ship"}},
+		{name: "missing emoji", values: []string{"approved"}, wantErr: true},
+		{name: "missing outcome", values: []string{"=rocket"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOutcomeReactions(tt.values)
+			if tt.wantErr {
+				var configErr *ConfigError
+				if !errors.As(err, &configErr) || configErr.Field != "Reactions" {
+					t.Errorf("parseOutcomeReactions = %v, want a Reactions error", err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOutcomeReactions = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomeReactionOverrides(t *testing.T) {
+	config := &Configuration{Reactions: map[string]string{outcomeApproved: ":rocket:"}}
+	if got := config.outcomeReaction(outcomeApproved); got != "rocket" {
+		t.Errorf("outcomeReaction(approved) = %q, want the override", got)
+	}
+	if got := config.outcomeReaction(outcomeDenied); got != "no_entry" {
+		t.Errorf("outcomeReaction(denied) = %q, want the default", got)
+	}
+
+	sc, reactions := newTestSlackClient(t, config, &fakeGitHub{})
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+	if !reactions.has("rocket") || reactions.has("white_check_mark") {
+		t.Errorf("reactions %v, want the overridden approved reaction", reactions.added)
+	}
+}
+
+func TestReactionsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "known outcome", value: "approved=rocket"},
+		{name: "unknown outcome", value: "shipped=ship", wantErr: `Unknown reaction outcome "shipped"`},
+		{name: "empty emoji", value: "approved=::", wantErr: `Reaction for outcome "approved" cannot be empty`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfiguration(validConfig(t, "--reaction", tt.value))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateConfiguration = %v, want no error", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != "Reactions" || configErr.Message != tt.wantErr {
+				t.Errorf("validateConfiguration = %v, want Reactions error %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// receipt is one line of the receipt file. Each receipt's hash covers its fields and
+// the previous receipt's hash, so editing, reordering or removing a line breaks the chain.
+type receipt struct {
+	Seq        int       `json:"seq"`
+	Time       time.Time `json:"time"`
+	Instance   string    `json:"instance,omitempty"`
+	Owner      string    `json:"owner"`
+	Repository string    `json:"repo"`
+	PRNumber   int       `json:"pr"`
+	User       string    `json:"user,omitempty"`
+	Channel    string    `json:"channel,omitempty"`
+	Decision   string    `json:"decision"`
+	Outcome    string    `json:"outcome,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	// Signature is the HMAC-SHA256 of Hash under the signing key, empty without one
+	Signature string `json:"signature,omitempty"`
+}
+
+// digest is the SHA-256 of the receipt with its hash and signature left out
+func (r receipt) digest() string {
+	r.Hash, r.Signature = "", ""
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signReceipt returns the HMAC-SHA256 of a receipt hash
+func signReceipt(hash, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// receiptLog appends a receipt per attempted approval to a local file for tamper-evident
+// audit without a central log store. A nil log records nothing.
+type receiptLog struct {
+	mu       sync.Mutex
+	path     string
+	key      string
+	instance string
+	seq      int
+	lastHash string
+}
+
+// newReceiptLog opens the receipt file, continuing the chain of any receipts already in
+// it; it returns nil when no file is configured
+func newReceiptLog(config *Configuration) (*receiptLog, error) {
+	if config.ReceiptFile == "" {
+		return nil, nil
+	}
+	rl := &receiptLog{path: config.ReceiptFile, key: config.ReceiptSigningKey, instance: config.InstanceName}
+
+	file, err := os.Open(rl.path)
+	if os.IsNotExist(err) {
+		return rl, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open receipt file: %v", err)
+	}
+	defer file.Close()
+
+	receipts, err := readReceipts(file)
+	if err != nil {
+		return nil, fmt.Errorf("receipt file %s is unreadable, check it with `lgtm verify-receipts`: %v", rl.path, err)
+	}
+	if len(receipts) > 0 {
+		last := receipts[len(receipts)-1]
+		rl.seq, rl.lastHash = last.Seq, last.Hash
+	}
+	return rl, nil
+}
+
+// record appends a receipt for every decision that wrote to GitHub or tried to, such as
+// an approval, a comment review or a failure. Skips and dry runs wrote nothing, so they
+// are not receipted.
+func (rl *receiptLog) record(d *approvalDecision) {
+	if rl == nil || d.Decision == decisionSkipped || d.Decision == decisionDryRun {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	r := receipt{
+		Seq:        rl.seq + 1,
+		Time:       time.Now().UTC(),
+		Instance:   rl.instance,
+		Owner:      d.Owner,
+		Repository: d.Repository,
+		PRNumber:   d.PRNumber,
+		User:       d.User,
+		Channel:    d.Channel,
+		Decision:   d.Decision,
+		Outcome:    d.Outcome,
+		Reason:     d.Reason,
+		PrevHash:   rl.lastHash,
+	}
+	r.Hash = r.digest()
+	if rl.key != "" {
+		r.Signature = signReceipt(r.Hash, rl.key)
+	}
+
+	if err := rl.append(r); err != nil {
+		logError("Failed to write approval receipt for %s/%s#%d: %v", d.Owner, d.Repository, d.PRNumber, err)
+		return
+	}
+	rl.seq, rl.lastHash = r.Seq, r.Hash
+}
+
+// append writes one receipt line and syncs it to disk
+func (rl *receiptLog) append(r receipt) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(rl.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readReceipts parses a receipt file, one JSON receipt per non-empty line
+func readReceipts(input io.Reader) ([]receipt, error) {
+	var receipts []receipt
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r receipt
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, scanner.Err()
+}
+
+// verifyReceipts checks the sequence numbers, hash chain and, with a key, the signatures
+// of a receipt file's receipts, reporting the first one that doesn't check out
+func verifyReceipts(receipts []receipt, key string) error {
+	prevHash := ""
+	for i, r := range receipts {
+		switch {
+		case r.Seq != i+1:
+			return fmt.Errorf("receipt %d has sequence number %d: a receipt was removed or reordered", i+1, r.Seq)
+		case r.PrevHash != prevHash:
+			return fmt.Errorf("receipt %d doesn't follow receipt %d: the chain is broken", r.Seq, i)
+		case r.digest() != r.Hash:
+			return fmt.Errorf("receipt %d doesn't match its hash: it was modified", r.Seq)
+		case key != "" && !hmac.Equal([]byte(r.Signature), []byte(signReceipt(r.Hash, key))):
+			return fmt.Errorf("receipt %d has an invalid signature", r.Seq)
+		}
+		prevHash = r.Hash
+	}
+	return nil
+}
+
+// verifyReceiptsCommand checks a receipt file's hash chain and signatures
+func verifyReceiptsCommand(c *cli.Context) error {
+	file, err := os.Open(c.String("receipt-file"))
+	if err != nil {
+		return fmt.Errorf("failed to open receipt file: %v", err)
+	}
+	defer file.Close()
+
+	receipts, err := readReceipts(file)
+	if err != nil {
+		return fmt.Errorf("%s %v", failMark(), err)
+	}
+	if err := verifyReceipts(receipts, c.String("receipt-signing-key")); err != nil {
+		return fmt.Errorf("%s %v", failMark(), err)
+	}
+
+	if len(receipts) == 0 {
+		fmt.Printf("%s No receipts in %s\n", okMark(), c.String("receipt-file"))
+		return nil
+	}
+	last := receipts[len(receipts)-1]
+	signatures := "signatures not checked, pass --receipt-signing-key"
+	if c.String("receipt-signing-key") != "" {
+		signatures = "signatures valid"
+	}
+	fmt.Printf("%s %d receipt(s) intact, %s\n", okMark(), len(receipts), signatures)
+	fmt.Printf("Last receipt: #%d at %s, hash %s\n", last.Seq, last.Time.Format(time.RFC3339), last.Hash)
+	return nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readReceiptFile reads every receipt in a receipt file
+func readReceiptFile(t *testing.T, path string) []receipt {
+	t.Helper()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	receipts, err := readReceipts(file)
+	if err != nil {
+		t.Fatalf("readReceipts: %v", err)
+	}
+	return receipts
+}
+
+func TestReceiptDecisions(t *testing.T) {
+	tests := []struct {
+		decision    string
+		wantReceipt bool
+	}{
+		{decision: decisionApproved, wantReceipt: true},
+		{decision: decisionFailed, wantReceipt: true},
+		// A COMMENT or REQUEST_CHANGES review was written to GitHub too
+		{decision: decisionReviewed, wantReceipt: true},
+		// So was a fallback comment review or commit status
+		{decision: decisionFallback, wantReceipt: true},
+		{decision: decisionSkipped},
+		{decision: decisionDryRun},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decision, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "receipts.jsonl")
+			rl, err := newReceiptLog(&Configuration{ReceiptFile: path})
+			if err != nil {
+				t.Fatalf("newReceiptLog: %v", err)
+			}
+			rl.record(&approvalDecision{Owner: "o", Repository: "r", PRNumber: 1, Decision: tt.decision})
+
+			receipts := readReceiptFile(t, path)
+			if got := len(receipts) == 1; got != tt.wantReceipt {
+				t.Fatalf("receipted = %v, want %v (%+v)", got, tt.wantReceipt, receipts)
+			}
+			if tt.wantReceipt && receipts[0].Decision != tt.decision {
+				t.Errorf("receipt decision = %q, want %q", receipts[0].Decision, tt.decision)
+			}
+		})
+	}
+}
+
+func TestApprovalFallbackIsReceipted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	config := &Configuration{AppApprovalFallback: AppFallbackStatus, ReceiptFile: path}
+	sc, _ := newTestSlackClient(t, config, &fakeGitHub{restrictApprovals: true})
+	receipts, err := newReceiptLog(config)
+	if err != nil {
+		t.Fatalf("newReceiptLog: %v", err)
+	}
+	sc.receipts = receipts
+
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	got := readReceiptFile(t, path)
+	if len(got) != 1 || got[0].Decision != decisionFallback {
+		t.Errorf("receipts = %+v, want one fallback receipt", got)
+	}
+}
+
+// writeReceipts records a receipt per decision in a new receipt file and returns its path
+func writeReceipts(t *testing.T, key string, decisions ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	rl, err := newReceiptLog(&Configuration{ReceiptFile: path, ReceiptSigningKey: key})
+	if err != nil {
+		t.Fatalf("newReceiptLog: %v", err)
+	}
+	for i, decision := range decisions {
+		rl.record(&approvalDecision{Owner: "o", Repository: "r", PRNumber: i + 1, User: "U1", Channel: "C1", Decision: decision})
+	}
+	return path
+}
+
+// writeReceiptFile replaces the receipts in a receipt file
+func writeReceiptFile(t *testing.T, path string, receipts []receipt) {
+	t.Helper()
+	var data []byte
+	for _, r := range receipts {
+		line, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReceiptChain(t *testing.T) {
+	path := writeReceipts(t, "secret", decisionApproved, decisionFailed)
+	rl, err := newReceiptLog(&Configuration{ReceiptFile: path, ReceiptSigningKey: "secret", InstanceName: "blue"})
+	if err != nil {
+		t.Fatalf("newReceiptLog: %v", err)
+	}
+	rl.record(&approvalDecision{Owner: "o", Repository: "r", PRNumber: 3, Decision: decisionReviewed})
+
+	receipts := readReceiptFile(t, path)
+	if len(receipts) != 3 {
+		t.Fatalf("receipt file has %d receipts, want 3", len(receipts))
+	}
+	for i, r := range receipts {
+		if r.Seq != i+1 || r.PRNumber != i+1 {
+			t.Errorf("receipt %d = %+v, want sequence number and PR %d", i+1, r, i+1)
+		}
+	}
+	if receipts[2].PrevHash != receipts[1].Hash || receipts[2].Instance != "blue" {
+		t.Errorf("reopened log wrote %+v, want it to continue the chain after %s", receipts[2], receipts[1].Hash)
+	}
+	if err := verifyReceipts(receipts, "secret"); err != nil {
+		t.Errorf("verifyReceipts: %v", err)
+	}
+}
+
+func TestVerifyReceipts(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		tamper  func([]receipt) []receipt
+		wantErr string
+	}{
+		{name: "intact", tamper: func(r []receipt) []receipt { return r }},
+		{name: "intact and signed", key: "secret", tamper: func(r []receipt) []receipt { return r }},
+		{name: "modified", tamper: func(r []receipt) []receipt { r[1].Decision = decisionApproved; return r }, wantErr: "receipt 2 doesn't match its hash: it was modified"},
+		{name: "removed", tamper: func(r []receipt) []receipt { return append(r[:1], r[2:]...) }, wantErr: "receipt 2 has sequence number 3: a receipt was removed or reordered"},
+		{name: "renumbered", tamper: func(r []receipt) []receipt { r = r[1:]; r[0].Seq, r[1].Seq = 1, 2; return r }, wantErr: "receipt 1 doesn't follow receipt 0: the chain is broken"},
+		{name: "wrong key", key: "other", tamper: func(r []receipt) []receipt { return r }, wantErr: "receipt 1 has an invalid signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipts := tt.tamper(readReceiptFile(t, writeReceipts(t, "secret", decisionApproved, decisionFailed, decisionReviewed)))
+			err := verifyReceipts(receipts, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verifyReceipts: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("verifyReceipts = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnreadableReceiptFile(t *testing.T) {
+	path := writeReceipts(t, "", decisionApproved)
+	if err := os.WriteFile(path, []byte("{not json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newReceiptLog(&Configuration{ReceiptFile: path}); err == nil || !strings.Contains(err.Error(), "check it with `lgtm verify-receipts`") {
+		t.Errorf("newReceiptLog = %v, want the receipt file to be reported unreadable", err)
+	}
+}
+
+// runVerifyReceipts runs `lgtm verify-receipts` with args and returns what it printed
+func runVerifyReceipts(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, plain := os.Stdout, plainOutput
+	t.Cleanup(func() { os.Stdout, plainOutput = stdout, plain })
+	os.Stdout = writer
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	err = newApp().Run(append([]string{"lgtm", "verify-receipts"}, args...))
+	writer.Close()
+	os.Stdout = stdout
+	return <-output, err
+}
+
+func TestVerifyReceiptsCommand(t *testing.T) {
+	path := writeReceipts(t, "secret", decisionApproved, decisionFallback)
+	last := readReceiptFile(t, path)[1]
+	lastLine := fmt.Sprintf("Last receipt: #2 at %s, hash %s\n", last.Time.Format(time.RFC3339), last.Hash)
+
+	tampered := writeReceipts(t, "", decisionApproved, decisionFailed)
+	receipts := readReceiptFile(t, tampered)
+	receipts[1].Reason = "edited"
+	writeReceiptFile(t, tampered, receipts)
+
+	malformed := writeReceipts(t, "", decisionApproved)
+	file, err := os.OpenFile(malformed, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{not json\n")
+	file.Close()
+
+	empty := filepath.Join(t.TempDir(), "empty.jsonl")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "unsigned check", args: []string{"--receipt-file", path}, want: "[OK] 2 receipt(s) intact, signatures not checked, pass --receipt-signing-key\n" + lastLine},
+		{name: "signed check", args: []string{"--receipt-file", path, "--receipt-signing-key", "secret"}, want: "[OK] 2 receipt(s) intact, signatures valid\n" + lastLine},
+		{name: "wrong key", args: []string{"--receipt-file", path, "--receipt-signing-key", "other"}, wantErr: "[FAIL] receipt 1 has an invalid signature"},
+		{name: "empty file", args: []string{"--receipt-file", empty}, want: "[OK] No receipts in " + empty + "\n"},
+		{name: "tampered", args: []string{"--receipt-file", tampered}, wantErr: "[FAIL] receipt 2 doesn't match its hash: it was modified"},
+		{name: "malformed", args: []string{"--receipt-file", malformed}, wantErr: "[FAIL] line 2: "},
+		{name: "missing file", args: []string{"--receipt-file", filepath.Join(t.TempDir(), "missing.jsonl")}, wantErr: "failed to open receipt file: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runVerifyReceipts(t, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("verify-receipts = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify-receipts: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("verify-receipts printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalDecisionsAreReceipted(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Configuration
+		gh           *fakeGitHub
+		wantDecision string
+	}{
+		{name: "approved", gh: &fakeGitHub{}, wantDecision: decisionApproved},
+		{name: "comment review", config: Configuration{ReviewEvent: "COMMENT"}, gh: &fakeGitHub{}, wantDecision: decisionReviewed},
+		{name: "review refused", gh: &fakeGitHub{reviewStatus: http.StatusUnprocessableEntity, reviewError: "Validation Failed"}, wantDecision: decisionFailed},
+		{name: "policy failed", config: Configuration{SkipDrafts: true}, gh: &fakeGitHub{draft: true}},
+		{name: "dry run", config: Configuration{DryRun: true}, gh: &fakeGitHub{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.ReceiptFile = filepath.Join(t.TempDir(), "receipts.jsonl")
+			sc, _ := newTestSlackClient(t, &config, tt.gh)
+			receipts, err := newReceiptLog(&config)
+			if err != nil {
+				t.Fatalf("newReceiptLog: %v", err)
+			}
+			sc.receipts = receipts
+
+			sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			got := readReceiptFile(t, config.ReceiptFile)
+			if tt.wantDecision == "" {
+				if len(got) != 0 {
+					t.Errorf("receipts = %+v, want none for a decision that wrote nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].Decision != tt.wantDecision || got[0].Owner != "o" || got[0].PRNumber != 1 || got[0].Channel != "C1" || got[0].User != "U1" {
+				t.Errorf("receipts = %+v, want one %s receipt for o/r#1", got, tt.wantDecision)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultRepoCacheTTL is how long repository metadata is reused when no TTL is configured
+const defaultRepoCacheTTL = 5 * time.Minute
+
+// maxGitHubPerPage is the largest page size the GitHub API accepts
+const maxGitHubPerPage = 100
+
+// repoMetadata is the subset of repository details the gates need
+type repoMetadata struct {
+	Archived      bool
+	DefaultBranch string
+	fetchedAt     time.Time
+}
+
+// repoCache holds repository metadata for a short TTL, so gating many PRs in the
+// same repository doesn't fetch the repository every time
+type repoCache struct {
+	mu      sync.Mutex
+	entries map[string]repoMetadata
+}
+
+// repository returns a repository's metadata, fetching it when not cached or stale
+func (gc *GitHubClient) repository(ctx context.Context, owner, repo string) (repoMetadata, error) {
+	ttl := gc.config.RepoCacheTTL
+	if ttl <= 0 {
+		ttl = defaultRepoCacheTTL
+	}
+	key := owner + "/" + repo
+
+	gc.repos.mu.Lock()
+	defer gc.repos.mu.Unlock()
+
+	if cached, ok := gc.repos.entries[key]; ok && time.Since(cached.fetchedAt) < ttl {
+		return cached, nil
+	}
+
+	repository, _, err := gc.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return repoMetadata{}, fmt.Errorf("failed to get repository %s: %v", key, err)
+	}
+
+	metadata := repoMetadata{
+		Archived:      repository.GetArchived(),
+		DefaultBranch: repository.GetDefaultBranch(),
+		fetchedAt:     time.Now(),
+	}
+	if gc.repos.entries == nil {
+		gc.repos.entries = make(map[string]repoMetadata)
+	}
+	gc.repos.entries[key] = metadata
+	return metadata, nil
+}
+
+// perPage returns the page size for paginated list calls
+func (gc *GitHubClient) perPage() int {
+	if gc.config.GitHubPerPage <= 0 || gc.config.GitHubPerPage > maxGitHubPerPage {
+		return maxGitHubPerPage
+	}
+	return gc.config.GitHubPerPage
+}
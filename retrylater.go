@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultRetryLaterPolicies are the gates whose failures usually clear up on their own
+var defaultRetryLaterPolicies = []string{"completed-check", "up-to-date"}
+
+// retriesLater reports whether an approval that failed with err is re-checked later:
+// RetryLaterAttempts is set and err is a failure of one of RetryLaterPolicies
+func (config *Configuration) retriesLater(err error) bool {
+	if config.RetryLaterAttempts <= 0 {
+		return false
+	}
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	return containsFold(config.RetryLaterPolicies, policyErr.Policy)
+}
+
+// runApprovalRetryingLater runs an approval and, while it fails a transient gate, reacts
+// with the waiting emoji and runs it again after RetryLaterDelay, up to RetryLaterAttempts
+// times. The last attempt's result is returned; cancelling ctx stops the waiting.
+func (sc *SlackClient) runApprovalRetryingLater(ctx context.Context, req *ApprovalRequest) (*ApprovalResult, error) {
+	result, err := sc.runApproval(ctx, req)
+	for attempt := 1; attempt <= sc.config.RetryLaterAttempts && sc.config.retriesLater(err); attempt++ {
+		logInfo("PR %s/%s#%d not ready yet, re-checking in %v (%d/%d): %v", req.Owner, req.Repository, req.PRNumber, sc.config.RetryLaterDelay, attempt, sc.config.RetryLaterAttempts, err)
+		metrics.Inc(metricApprovalsRetriedLater)
+		sc.reactQueued(req, outcomeWaiting)
+
+		timer := time.NewTimer(sc.config.RetryLaterDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		result, err = sc.runApproval(ctx, req)
+	}
+	return result, err
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// SkipReason says why a message that matched a pattern did not lead to an approval
+type SkipReason string
+
+// Skip reasons recorded in the skip audit
+const (
+	SkipNoPRReference  SkipReason = "no_pr_reference"
+	SkipUnmappedUser   SkipReason = "unmapped_user"
+	SkipNotAllowedUser SkipReason = "not_allowed_user"
+	SkipUnresolvedLink SkipReason = "unresolved_link"
+	SkipMissingRepo    SkipReason = "missing_repo"
+	SkipPaused         SkipReason = "paused"
+	SkipFrozen         SkipReason = "frozen"
+	SkipDegraded       SkipReason = "degraded"
+	SkipThrottled      SkipReason = "throttled"
+	SkipDraining       SkipReason = "draining"
+	SkipOverloaded     SkipReason = "overloaded"
+	SkipPolicy         SkipReason = "policy"
+	SkipCancelled      SkipReason = "cancelled"
+	SkipError          SkipReason = "error"
+)
+
+// approvalSkipReason classifies an error returned before an approval was submitted
+func approvalSkipReason(err error) SkipReason {
+	var policyErr *PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		return SkipPolicy
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return SkipCancelled
+	default:
+		return SkipError
+	}
+}
+
+// skipAuditEntry is one line of the skip audit file
+type skipAuditEntry struct {
+	Time       time.Time  `json:"time"`
+	Instance   string     `json:"instance,omitempty"`
+	Channel    string     `json:"channel"`
+	User       string     `json:"user,omitempty"`
+	Timestamp  string     `json:"ts,omitempty"`
+	ThreadTS   string     `json:"thread_ts,omitempty"`
+	Owner      string     `json:"owner,omitempty"`
+	Repository string     `json:"repo,omitempty"`
+	PRNumber   int        `json:"pr,omitempty"`
+	SkipReason SkipReason `json:"skip_reason"`
+	Outcome    string     `json:"outcome,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// skipAudit appends a JSON line per skipped decision on a matched message. Matched
+// messages can be frequent, so it is opt-in; a nil audit records nothing.
+type skipAudit struct {
+	mu       sync.Mutex
+	path     string
+	instance string
+}
+
+// newSkipAudit returns the skip audit for the configured file, or nil when none is set
+func newSkipAudit(config *Configuration) *skipAudit {
+	if config.SkipAuditFile == "" {
+		return nil
+	}
+	return &skipAudit{path: config.SkipAuditFile, instance: config.InstanceName}
+}
+
+// record appends an entry for a skipped decision; other decisions are ignored
+func (sa *skipAudit) record(msg *SlackMessage, d *approvalDecision) {
+	if sa == nil || d.Decision != decisionSkipped {
+		return
+	}
+
+	entry := skipAuditEntry{
+		Time:       time.Now().UTC(),
+		Instance:   sa.instance,
+		Channel:    d.Channel,
+		User:       d.User,
+		Owner:      d.Owner,
+		Repository: d.Repository,
+		PRNumber:   d.PRNumber,
+		SkipReason: d.SkipReason,
+		Outcome:    d.Outcome,
+		Reason:     d.Reason,
+	}
+	if entry.SkipReason == "" {
+		entry.SkipReason = SkipError
+	}
+	if msg != nil {
+		entry.Timestamp, entry.ThreadTS = msg.Timestamp, msg.ThreadTS
+	}
+
+	if err := sa.append(entry); err != nil {
+		logError("Failed to write skip audit entry for channel %s: %v", d.Channel, err)
+	}
+}
+
+// logSkip logs a skipped decision and records it in the skip audit
+func (sc *SlackClient) logSkip(msg *SlackMessage, d *approvalDecision) {
+	logDecision(d)
+	sc.skipAudit.record(msg, d)
+}
+
+// append writes one entry line
+func (sa *skipAudit) append(entry skipAuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	file, err := os.OpenFile(sa.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
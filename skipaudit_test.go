@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readSkipAudit returns the entries in a skip audit file
+func readSkipAudit(t *testing.T, path string) []skipAuditEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("open skip audit: %v", err)
+	}
+	defer file.Close()
+
+	var entries []skipAuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry skipAuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("skip audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestApprovalSkipReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want SkipReason
+	}{
+		{err: &PolicyError{Policy: "draft", Message: "PR #1 is a draft"}, want: SkipPolicy},
+		{err: fmt.Errorf("validate: %w", &PolicyError{Policy: "draft"}), want: SkipPolicy},
+		{err: context.Canceled, want: SkipCancelled},
+		{err: fmt.Errorf("lookup: %w", context.DeadlineExceeded), want: SkipCancelled},
+		{err: errors.New("connection refused"), want: SkipError},
+	}
+
+	for _, tt := range tests {
+		if got := approvalSkipReason(tt.err); got != tt.want {
+			t.Errorf("approvalSkipReason(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSkipAuditRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skips.jsonl")
+	audit := newSkipAudit(&Configuration{SkipAuditFile: path, InstanceName: "blue"})
+	msg := &SlackMessage{Channel: "C1", User: "U1", Timestamp: "1.1", ThreadTS: "1.0"}
+
+	audit.record(msg, &approvalDecision{Owner: "o", Repository: "r", PRNumber: 1, Channel: "C1", Decision: decisionApproved})
+	audit.record(msg, &approvalDecision{Owner: "o", Repository: "r", PRNumber: 2, User: "U1", Channel: "C1", Decision: decisionSkipped, Outcome: outcomeDenied, Reason: "draft", SkipReason: SkipPolicy})
+	audit.record(nil, &approvalDecision{Channel: "C1", Decision: decisionSkipped})
+
+	entries := readSkipAudit(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("skip audit has %d entries, want the 2 skipped decisions", len(entries))
+	}
+	got := entries[0]
+	if got.Instance != "blue" || got.PRNumber != 2 || got.User != "U1" || got.Timestamp != "1.1" || got.ThreadTS != "1.0" || got.SkipReason != SkipPolicy || got.Outcome != outcomeDenied || got.Reason != "draft" {
+		t.Errorf("entry = %+v, want the policy skip of PR 2", got)
+	}
+	if entries[1].SkipReason != SkipError || entries[1].Timestamp != "" {
+		t.Errorf("entry = %+v, want a skip without a message defaulting to %s", entries[1], SkipError)
+	}
+}
+
+func TestSkipAuditDisabled(t *testing.T) {
+	audit := newSkipAudit(&Configuration{})
+	if audit != nil {
+		t.Fatalf("newSkipAudit = %+v, want nil without a skip audit file", audit)
+	}
+	audit.record(&SlackMessage{}, &approvalDecision{Decision: decisionSkipped})
+}
+
+func TestSkipAuditWriteFailure(t *testing.T) {
+	buffer := captureLog(t)
+	audit := newSkipAudit(&Configuration{SkipAuditFile: filepath.Join(t.TempDir(), "missing", "skips.jsonl")})
+	audit.record(nil, &approvalDecision{Channel: "C1", Decision: decisionSkipped})
+
+	if !strings.Contains(buffer.String(), "[ERROR] Failed to write skip audit entry for channel C1") {
+		t.Errorf("log = %q, want the write failure", buffer.String())
+	}
+}
+
+func TestSkippedMessagesAreAudited(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Configuration
+		gh     *fakeGitHub
+		text   string
+		want   SkipReason
+	}{
+		{name: "no PR reference", gh: &fakeGitHub{}, text: "lgtm", want: SkipNoPRReference},
+		{name: "unmapped user", config: Configuration{RequireMappedUser: true}, gh: &fakeGitHub{}, text: "lgtm https://github.com/o/r/pull/1", want: SkipUnmappedUser},
+		{name: "missing repo", gh: &fakeGitHub{}, text: "lgtm #1", want: SkipMissingRepo},
+		{name: "policy", config: Configuration{SkipDrafts: true}, gh: &fakeGitHub{draft: true}, text: "lgtm https://github.com/o/r/pull/1", want: SkipPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.SkipAuditFile = filepath.Join(t.TempDir(), "skips.jsonl")
+			sc, _ := newTestSlackClient(t, &config, tt.gh)
+
+			sc.processMessage(context.Background(), testMessage(tt.text))
+			waitForApprovals(t, sc)
+
+			entries := readSkipAudit(t, config.SkipAuditFile)
+			if len(entries) != 1 || entries[0].SkipReason != tt.want || entries[0].Channel != "C1" || entries[0].Timestamp != "1700000000.000100" {
+				t.Errorf("skip audit = %+v, want one %s entry for the message", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalsAreNotAudited(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skips.jsonl")
+	sc, _ := newTestSlackClient(t, &Configuration{SkipAuditFile: path}, &fakeGitHub{})
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if entries := readSkipAudit(t, path); len(entries) != 0 {
+		t.Errorf("skip audit = %+v, want nothing for an approval", entries)
+	}
+}
+
+func TestSkipAuditFileValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--skip-audit-file", "audit.jsonl", "--receipt-file", "audit.jsonl"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "SkipAuditFile" {
+		t.Errorf("validateConfiguration = %v, want a SkipAuditFile error", err)
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -10,48 +14,199 @@ import (
 	"github.com/slack-go/slack/socketmode"
 )
 
+// interactiveApproveActionID is the action_id of block buttons that trigger an approval
+const interactiveApproveActionID = "lgtm_approve"
+
 // SlackClient handles Slack Socket Mode connection
 type SlackClient struct {
+	apiMu        sync.RWMutex
 	api          *slack.Client
 	socketClient *socketmode.Client
 	config       *Configuration
 	matcher      *PatternMatcher
 	githubClient *GitHubClient
+	
+	// botUserID and botID identify the bot's own Slack user and bot, resolved at startup
+	botUserID string
+	botID     string
+	
+	// refreshToken is the latest Slack refresh token when token rotation is enabled
+	refreshToken string
+	
+	// store keeps state that outlives a restart, such as resolved channel names
+	store Store
+	
+	// dedupe prevents approving the same PR twice from one root message
+	dedupe *dedupeCache
+	
+	// denialTemplates explain policy denials per policy when ExplainDenials is on
+	denialTemplates map[string]*template.Template
+	
+	// reactionMessages are the review bodies per trigger emoji in reaction-trigger mode
+	reactionMessages map[string]*template.Template
+	
+	// reactions coalesces reaction updates to save Slack API calls
+	reactions *reactionBatcher
+	// queuedReactions remembers the interim reactions of queued approvals across a restart
+	queuedReactions *queuedReactions
+	
+	// confirmations holds approvals awaiting the confirmation keyword, nil when not required
+	confirmations *confirmationTracker
+	
+	// quorum counts distinct trigger reactors per message in reaction-trigger mode
+	quorum *quorumTracker
+	
+	// users maps Slack users to GitHub logins
+	users *userDirectory
+	
+	// userNames resolves user IDs to display names for logs, nil when not resolving
+	userNames *userNames
+	
+	// channelMatchers replace the global matcher in channels with their own patterns
+	channelMatchers map[string]*PatternMatcher
+	
+	// pause is the global kill switch toggled by admins
+	pause *pauseSwitch
+	
+	// directives are the default targets read from channel topics
+	directives *channelDirectives
+	
+	// throttle caps approvals per minute across all channels, nil when unlimited
+	throttle *approvalThrottle
+	
+	// inFlight counts running approval goroutines and caps them at MaxInFlightApprovals
+	inFlight *approvalSlots
+	
+	// drain stops new approvals from starting ahead of a rolling restart
+	drain *drainSwitch
+	
+	// merges polls approved PRs and reports when they land, nil when not watching
+	merges *mergeWatcher
+	
+	// receipts appends a hash-chained receipt per approval, nil without a receipt file
+	receipts *receiptLog
+	
+	// messageApprovals remembers the PRs each message approved, nil unless DismissOnMessageDeleted
+	messageApprovals *messageApprovals
+	
+	// skipAudit records every skipped decision on a matched message, nil without a skip audit file
+	skipAudit *skipAudit
 }
 
 // NewSlackClient creates a new Slack client with Socket Mode
-func NewSlackClient(config *Configuration, matcher *PatternMatcher, githubClient *GitHubClient) (*SlackClient, error) {
+func NewSlackClient(config *Configuration, matcher *PatternMatcher, githubClient *GitHubClient, store Store) (*SlackClient, error) {
 	// Create Slack API client with bot token
-	api := slack.New(
-		config.SlackBotToken,
-		slack.OptionDebug(config.LogLevel == "debug"),
-		slack.OptionAppLevelToken(config.SlackAppToken),
-	)
+	api := newSlackAPI(config.SlackBotToken, config)
+	
+	denialTemplates, err := parseDenialTemplates(config.DenialTemplates)
+	if err != nil {
+		return nil, err
+	}
+	
+	reactionMessages, err := parseReactionMessages(config.TriggerReactionMessages)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Compile each channel's patterns once, sharing the global matcher's settings
+	channelMatchers := make(map[string]*PatternMatcher)
+	for channel, patterns := range config.ChannelPatterns {
+		channelMatcher, err := NewMultiPatternMatcher(patterns, config.MatchMode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for channel %s: %v", channel, err)
+		}
+		channelMatcher.SetRepoAliases(config.RepoAliases)
+		channelMatcher.SetResolveCommits(config.ResolveCommits)
+		channelMatcher.SetResolveBranchLinks(config.ResolveBranchLinks)
+		channelMatcher.SetPerReferenceActions(config.PerReferenceActions)
+		channelMatcher.SetRepoCase(config.RepoCase)
+		channelMatcher.SetBareNumberConflict(config.BareNumberConflict)
+		channelMatchers[channel] = channelMatcher
+	}
 	
 	// Create Socket Mode client
 	socketClient := socketmode.New(
 		api,
 		socketmode.OptionDebug(config.LogLevel == "debug"),
+		socketmode.OptionDialer(newWebsocketDialer(config)),
 	)
 	
-	return &SlackClient{
+	sc := &SlackClient{
 		api:          api,
 		socketClient: socketClient,
 		config:       config,
 		matcher:      matcher,
 		githubClient: githubClient,
-	}, nil
+		refreshToken: config.SlackRefreshToken,
+		store:        store,
+		dedupe:       newDedupeCache(config.DedupeWindow, store),
+		
+		denialTemplates:  denialTemplates,
+		reactionMessages: reactionMessages,
+		confirmations:    newConfirmationTracker(config.RequireConfirmationKeyword, config.ConfirmationWindow),
+		quorum:           newQuorumTracker(config.TriggerQuorum),
+		users:            newUserDirectory(config.UserMappings, store),
+		channelMatchers:  channelMatchers,
+		pause:            newPauseSwitch(store),
+		directives:       newChannelDirectives(),
+		throttle:         newApprovalThrottle(config.MaxApprovalsPerMinute, store),
+		inFlight:         newApprovalSlots(config.MaxInFlightApprovals),
+		drain:            newDrainSwitch(),
+		messageApprovals: newMessageApprovals(config.DismissOnMessageDeleted, store),
+		queuedReactions:  newQueuedReactions(store),
+		skipAudit:        newSkipAudit(config),
+	}
+	sc.reactions = newReactionBatcher(config.ReactionCoalesceWindow, config.ReplaceInterimReactions, sc.addReaction, sc.deleteReaction)
+	sc.userNames = newUserNames(config.ResolveUserNames, config.UserNameCacheTTL, func(ctx context.Context, userID string) (*slack.User, error) {
+		return sc.slackAPI().GetUserInfoContext(ctx, userID)
+	})
+	sc.merges = newMergeWatcher(config.WatchUntilMerged, config.MergeWatchTimeout, config.MergeWatchInterval, githubClient.MergeState, sc.reportMergeState)
+	
+	return sc, nil
+}
+
+// newSlackAPI creates a Slack API client for a bot token
+func newSlackAPI(botToken string, config *Configuration) *slack.Client {
+	return slack.New(
+		botToken,
+		slack.OptionDebug(config.LogLevel == "debug"),
+		slack.OptionAppLevelToken(config.SlackAppToken),
+		slack.OptionHTTPClient(newHTTPClient(config)),
+	)
 }
 
 // Start begins the Slack Socket Mode connection
 func (sc *SlackClient) Start(ctx context.Context) error {
 	logInfo("Connecting to Slack workspace...")
 	
+	// With token rotation, start from a fresh bot token and keep refreshing it
+	if sc.config.tokenRotationEnabled() {
+		expiresIn, err := sc.refreshBotToken(ctx)
+		if err != nil {
+			return err
+		}
+		go sc.rotateTokens(ctx, expiresIn)
+	}
+	
 	// Test authentication first
 	if err := sc.validateTokens(ctx); err != nil {
 		return fmt.Errorf("Slack token validation failed: %v", err)
 	}
 	
+	// Turn a channel name into the ID events are filtered on
+	if err := sc.resolveChannelName(ctx); err != nil {
+		return err
+	}
+	metrics.SetGauge(metricSlackReady, 1)
+	
+	// Approvals queued when the bot last stopped are gone, so take their reactions back off
+	sc.reconcileQueuedReactions(ctx)
+	
+	// Read standing instructions from the topics of channels the bot is already in
+	if sc.config.ChannelTopicDirectives {
+		go sc.scanChannelDirectives(ctx)
+	}
+	
 	// Start event handling in background
 	go sc.handleEvents(ctx)
 	
@@ -62,12 +217,21 @@ func (sc *SlackClient) Start(ctx context.Context) error {
 // validateTokens validates Slack bot and app tokens
 func (sc *SlackClient) validateTokens(ctx context.Context) error {
 	// Test bot token by calling auth.test
-	authResponse, err := sc.api.AuthTestContext(ctx)
+	authResponse, err := sc.slackAPI().AuthTestContext(ctx)
 	if err != nil {
 		return &AuthenticationError{Service: "Slack", Message: fmt.Sprintf("bot token validation failed: %v", err)}
 	}
 	
 	logInfo("Authenticated as Slack user: %s (team: %s)", authResponse.User, authResponse.Team)
+	sc.botUserID = authResponse.UserID
+	sc.botID = authResponse.BotID
+	
+	// Org-wide installs on Enterprise Grid report the enterprise the token belongs to
+	if authResponse.EnterpriseID != "" {
+		logInfo("Slack Enterprise Grid context: enterprise=%s team_id=%s", authResponse.EnterpriseID, authResponse.TeamID)
+	} else {
+		logDebug("Slack workspace context: team_id=%s", authResponse.TeamID)
+	}
 	
 	// App token validation is implicit - if Socket Mode connection succeeds, the app token is valid
 	logDebug("App token validated successfully")
@@ -100,14 +264,32 @@ func (sc *SlackClient) handleEvents(ctx context.Context) {
 			// Handle the inner event
 			sc.handleEventsAPIEvent(ctx, eventsAPIEvent)
 			
+		case socketmode.EventTypeInteractive:
+			callback, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				logDebug("Unexpected interactive event type: %T", evt.Data)
+				sc.socketClient.Ack(*evt.Request)
+				continue
+			}
+			
+			// Acknowledge within Slack's 3 second window, then process
+			sc.socketClient.Ack(*evt.Request)
+			
+			if sc.config.EnableInteractive {
+				go sc.handleInteraction(ctx, callback)
+			}
+			
 		case socketmode.EventTypeConnecting:
 			logDebug("Connecting to Slack with Socket Mode...")
+			metrics.SetGauge(metricSlackConnected, 0)
 			
 		case socketmode.EventTypeConnectionError:
 			logWarn("Connection failed. Retrying later...")
+			metrics.SetGauge(metricSlackConnected, 0)
 			
 		case socketmode.EventTypeConnected:
 			logInfo("Connected to Slack workspace")
+			metrics.SetGauge(metricSlackConnected, 1)
 			
 		default:
 			logDebug("Unexpected event type received: %s", evt.Type)
@@ -124,36 +306,158 @@ func (sc *SlackClient) handleEventsAPIEvent(ctx context.Context, event slackeven
 	innerEvent := event.InnerEvent
 	switch ev := innerEvent.Data.(type) {
 	case *slackevents.MessageEvent:
-		sc.handleMessageEvent(ctx, ev)
+		sc.handleMessageEvent(ctx, ev, event.TeamID, event.EnterpriseID)
+	case *slackevents.ReactionAddedEvent:
+		sc.handleReactionAdded(ctx, ev)
+	case *slackevents.ReactionRemovedEvent:
+		sc.handleReactionRemoved(ctx, ev)
+	case *slackevents.MemberJoinedChannelEvent:
+		if ev.User == sc.botUserID {
+			sc.handleChannelRestored(ev.Channel)
+		}
+		if sc.config.ChannelTopicDirectives && ev.User == sc.botUserID {
+			sc.loadChannelDirectives(ctx, ev.Channel)
+		}
+	case *slackevents.ChannelLeftEvent:
+		sc.handleChannelRemoved(ev.Channel, "was left by the bot")
+	case *slackevents.GroupLeftEvent:
+		sc.handleChannelRemoved(ev.Channel, "was left by the bot")
+	case *slackevents.ChannelArchiveEvent:
+		sc.handleChannelRemoved(ev.Channel, "was archived")
+	case *slackevents.GroupArchiveEvent:
+		sc.handleChannelRemoved(ev.Channel, "was archived")
+	case *slackevents.ChannelUnarchiveEvent:
+		sc.handleChannelRestored(ev.Channel)
+	case *slackevents.GroupUnarchiveEvent:
+		sc.handleChannelRestored(ev.Channel)
 	default:
 		// Ignore other event types
 	}
 }
 
+// isSelf reports whether a message was posted by this bot. Some payloads for the
+// bot's own messages omit bot_id, so the user ID is checked as well.
+func (sc *SlackClient) isSelf(user, botID string) bool {
+	if sc.botUserID != "" && user == sc.botUserID {
+		return true
+	}
+	return sc.botID != "" && botID == sc.botID
+}
+
 // handleMessageEvent processes message events
-func (sc *SlackClient) handleMessageEvent(ctx context.Context, event *slackevents.MessageEvent) {
+func (sc *SlackClient) handleMessageEvent(ctx context.Context, event *slackevents.MessageEvent, teamID, enterpriseID string) {
+	// Direct messages carry admin and self-service mapping commands, never approvals
+	if event.ChannelType == "im" {
+		if event.SubType == "" && event.BotID == "" && !sc.isSelf(event.User, event.BotID) {
+			msg := &SlackMessage{Text: event.Text, Channel: event.Channel, User: event.User, Timestamp: event.TimeStamp}
+			if sc.handlePauseCommand(ctx, msg) {
+				return
+			}
+			if sc.config.EnableSelfServiceMapping && sc.handleMappingReview(ctx, msg) {
+				return
+			}
+		}
+		if sc.config.EnableSelfServiceMapping {
+			sc.handleMappingCommand(event)
+		}
+		return
+	}
+	
 	// Skip if channel filtering is enabled and this message is from a different channel
 	if sc.config.SlackChannelID != "" && event.Channel != sc.config.SlackChannelID {
 		return
 	}
 	
+	// Events from org-wide installs may omit the outer team_id; fall back to the message's own team fields
+	if teamID == "" {
+		teamID = event.SourceTeam
+	}
+	if teamID == "" {
+		teamID = event.UserTeam
+	}
+	
+	// Skip if workspace filtering is enabled and this message is from a different workspace
+	if sc.config.SlackTeamID != "" {
+		// A message with no team context can't be told apart from another workspace's
+		if teamID == "" {
+			logDebug("Dropping message %s in channel %s: no team context to match against the workspace filter", event.TimeStamp, event.Channel)
+			return
+		}
+		if teamID != sc.config.SlackTeamID {
+			return
+		}
+	}
+	
 	// Skip bot messages to avoid processing our own messages
-	if event.BotID != "" {
+	if event.BotID != "" || sc.isSelf(event.User, event.BotID) {
 		return
 	}
 	
 	// Create SlackMessage struct
 	slackMsg := &SlackMessage{
-		Text:      event.Text,
-		Channel:   event.Channel,
-		User:      event.User,
-		Timestamp: event.TimeStamp,
-		ThreadTS:  event.ThreadTimeStamp,
+		Text:       event.Text,
+		Channel:    event.Channel,
+		User:       event.User,
+		Timestamp:  event.TimeStamp,
+		ThreadTS:   event.ThreadTimeStamp,
+		Team:       teamID,
+		Enterprise: enterpriseID,
+	}
+	
+	switch event.SubType {
+	case "":
+	case "channel_topic", "channel_purpose":
+		if sc.config.ChannelTopicDirectives {
+			sc.loadChannelDirectives(ctx, event.Channel)
+		}
+		return
+	case "message_deleted":
+		sc.handleMessageDeleted(ctx, event.Channel, event.DeletedTimeStamp)
+		return
+	case "message_changed":
+		// Edits carry the updated message, keyed by the original message ts
+		if !sc.config.HandleEdits || event.Message == nil {
+			return
+		}
+		if event.Message.BotID != "" || sc.isSelf(event.Message.User, event.Message.BotID) {
+			return
+		}
+		// Link unfurls also arrive as edits; only re-process when the text changed
+		if event.PreviousMessage != nil && event.PreviousMessage.Text == event.Message.Text {
+			return
+		}
+		slackMsg.Text = event.Message.Text
+		slackMsg.User = event.Message.User
+		slackMsg.Timestamp = event.Message.Timestamp
+		slackMsg.ThreadTS = event.Message.ThreadTimestamp
+		logDebug("Message edited: channel=%s ts=%s", event.Channel, slackMsg.Timestamp)
+	case "channel_join", "channel_leave", "group_join", "group_leave", "channel_name", "channel_archive", "channel_unarchive", "message_replied", "pinned_item", "unpinned_item":
+		// Joins, leaves and other notices carry no text to match. The rest, such as
+		// thread_broadcast, file_share and me_message, carry the user's own text.
+		return
 	}
 	
 	// Use structured logging for message events
-	logDebug("Message received: channel=%s user=%s text=%q", event.Channel, event.User, event.Text)
+	logDebug("Message received: channel=%s user=%s team=%s enterprise=%s text=%q", event.Channel, slackMsg.User, teamID, enterpriseID, slackMsg.Text)
 	logInfo("Message received from channel %s", event.Channel)
+	metrics.Inc(metricMessagesReceived)
+	
+	// Admins can pause and resume approvals from any monitored channel
+	if sc.handlePauseCommand(ctx, slackMsg) {
+		return
+	}
+	
+	// In reaction-trigger mode approvals start from reactions, not new messages
+	if sc.config.ReactionTrigger {
+		return
+	}
+	
+	// Only allowed users can ask for approvals; an edit is checked against the edited
+	// message's author, and relayed messages without a user never pass
+	if !sc.config.slackUserAllowed(slackMsg.User) {
+		sc.ignoreDisallowedUser(ctx, slackMsg)
+		return
+	}
 	
 	// Process the message for pattern matching
 	sc.processMessage(ctx, slackMsg)
@@ -161,8 +465,33 @@ func (sc *SlackClient) handleMessageEvent(ctx context.Context, event *slackevent
 
 // processMessage handles pattern matching for incoming messages
 func (sc *SlackClient) processMessage(ctx context.Context, msg *SlackMessage) {
+	// Skip oversized messages so a huge paste can't tie up the matcher
+	if sc.config.MaxMessageLength > 0 && len(msg.Text) > sc.config.MaxMessageLength {
+		logWarn("Skipping message in channel %s from user %s: length %d exceeds limit %d", msg.Channel, sc.userLabel(ctx, msg.User), len(msg.Text), sc.config.MaxMessageLength)
+		return
+	}
+	
+	// Old messages, or replies in old threads, no longer ask for a review
+	if sc.config.MaxMessageAge > 0 {
+		if stale, reason := sc.config.staleMessage(msg, time.Now()); stale {
+			logInfo("Skipping message %s in channel %s from user %s: %s", msg.Timestamp, msg.Channel, sc.userLabel(ctx, msg.User), reason)
+			return
+		}
+	}
+	
+	// A confirmation executes the approvals armed by the same user's earlier message
+	if sc.confirmations != nil && sc.confirmations.isConfirmation(sc.matchText(msg.Text)) {
+		if armed := sc.confirmations.confirm(msg.Channel, msg.User); len(armed) > 0 {
+			logInfo("Confirmation received in channel %s from user %s for %d PR(s)", msg.Channel, sc.userLabel(ctx, msg.User), len(armed))
+			for _, match := range armed {
+				sc.processPRApprovals(ctx, match)
+			}
+			return
+		}
+	}
+	
 	// Attempt to match the message against the configured pattern
-	match, err := sc.matcher.Match(msg.Text)
+	match, err := sc.matcherFor(msg.Channel).Match(sc.matchText(msg.Text))
 	if err != nil {
 		logError("Pattern matching error: %v", err)
 		return
@@ -175,39 +504,194 @@ func (sc *SlackClient) processMessage(ctx context.Context, msg *SlackMessage) {
 	
 	// Pattern matched!
 	match.SourceMessage = msg
-	logInfo("Pattern matched in channel %s from user %s", msg.Channel, msg.User)
-	logDebug("Pattern details: pattern=%q matched_text=%q", match.Pattern, match.MatchedText)
+	metrics.Inc(metricPatternMatches)
+	logInfo("Pattern matched in channel %s from user %s", msg.Channel, sc.userLabel(ctx, msg.User))
+	logDebug("Pattern details: pattern=%q matched_text=%q matched_patterns=%q", match.Pattern, match.MatchedText, match.MatchedPatterns)
+	
+	// A reply without a link approves the PRs of the message it answers
+	if len(match.PRReferences) == 0 && sc.config.ResolveThreadParent {
+		match.PRReferences = sc.threadParentReferences(ctx, msg)
+		match.Intent.References = match.PRReferences
+	}
 	
 	// Process GitHub PR approvals if any PR references found
-	if len(match.PRReferences) > 0 {
+	if len(match.PRReferences) > 0 && sc.confirmations != nil {
+		sc.armPRApprovals(match)
+	} else if len(match.PRReferences) > 0 {
 		sc.processPRApprovals(ctx, match)
 	} else {
 		logInfo("Pattern matched but no PR references found in message")
 		// React with X emoji - no PR references found
-		sc.addReaction(msg.Channel, msg.Timestamp, "x")
+		sc.react(msg.Channel, msg.Timestamp, outcomeNoPR)
+		sc.skipAudit.record(msg, &approvalDecision{
+			User:       msg.User,
+			Channel:    msg.Channel,
+			Decision:   decisionSkipped,
+			Outcome:    outcomeNoPR,
+			Reason:     "no PR references found",
+			SkipReason: SkipNoPRReference,
+		})
 	}
 }
 
+// armPRApprovals holds the PRs in a match until the sender confirms them
+func (sc *SlackClient) armPRApprovals(match *PatternMatch) {
+	armed := 0
+	for _, prRef := range match.PRReferences {
+		owner, repo, ok := sc.resolvePRTarget(match.SourceMessage.Channel, prRef)
+		if !ok {
+			logWarn("Skipping PR %d: missing owner or repo", prRef.Number)
+			continue
+		}
+		sc.confirmations.arm(owner, repo, prRef, match)
+		armed++
+	}
+	
+	if armed == 0 {
+		return
+	}
+	
+	logInfo("Armed %d PR(s) in channel %s; waiting for %q from user %s", armed, match.SourceMessage.Channel, sc.config.RequireConfirmationKeyword, match.SourceMessage.User)
+	sc.react(match.SourceMessage.Channel, match.SourceMessage.Timestamp, outcomeArmed)
+}
+
+// resolveLinkedReference replaces a commit or file link reference with the open PR
+// containing the commit or having the link's branch as its head
+func (sc *SlackClient) resolveLinkedReference(ctx context.Context, prRef PRReference) (PRReference, error) {
+	var number int
+	var err error
+	if prRef.CommitSHA != "" {
+		number, err = sc.githubClient.ResolveCommitPR(ctx, prRef.Owner, prRef.Repository, prRef.CommitSHA)
+	} else {
+		number, err = sc.githubClient.ResolveBranchPR(ctx, prRef.Owner, prRef.Repository, prRef.Branch)
+	}
+	if err != nil {
+		return prRef, err
+	}
+	
+	logInfo("Resolved %s to PR %s/%s#%d", prRef.linkDescription(), prRef.Owner, prRef.Repository, number)
+	return PRReference{
+		Owner:      prRef.Owner,
+		Repository: prRef.Repository,
+		Number:     number,
+		URL:        fmt.Sprintf("https://github.com/%s/%s/pull/%d", prRef.Owner, prRef.Repository, number),
+		Action:     prRef.Action,
+	}, nil
+}
+
+// matcherFor returns the channel's own matcher, falling back to the global one
+func (sc *SlackClient) matcherFor(channel string) *PatternMatcher {
+	if channelMatcher, ok := sc.channelMatchers[channel]; ok {
+		return channelMatcher
+	}
+	return sc.matcher
+}
+
+// duplicateApprovalRequest reports whether reqs already approve a PR
+func duplicateApprovalRequest(reqs []*ApprovalRequest, owner, repo string, number int) bool {
+	for _, req := range reqs {
+		if req.PRNumber == number && strings.EqualFold(req.Owner, owner) && strings.EqualFold(req.Repository, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePRTarget fills in a reference's missing owner/repo from the channel's topic
+// directives, then from configuration
+func (sc *SlackClient) resolvePRTarget(channel string, prRef PRReference) (string, string, bool) {
+	owner := prRef.Owner
+	repo := prRef.Repository
+	
+	if directive := sc.directives.target(channel); owner == "" {
+		owner = directive.Owner
+		if repo == "" {
+			repo = directive.Repo
+		}
+	}
+	if owner == "" {
+		owner = sc.config.DefaultOwner
+	}
+	if repo == "" {
+		repo = sc.config.DefaultRepo
+	}
+	
+	// Defaults, aliases and directives may be written in any case
+	owner = canonicalRepoName(owner, sc.config.RepoCase)
+	repo = canonicalRepoName(repo, sc.config.RepoCase)
+	
+	return owner, repo, owner != "" && repo != ""
+}
+
 // processPRApprovals handles GitHub PR approvals for matched messages
 func (sc *SlackClient) processPRApprovals(ctx context.Context, match *PatternMatch) {
-	// Add eyes reaction - processing started
-	sc.addReaction(match.SourceMessage.Channel, match.SourceMessage.Timestamp, "eyes")
+	// Channel policy falls back to the global policy
+	policy := sc.config.PolicyFor(match.SourceMessage.Channel)
 	
-	for _, prRef := range match.PRReferences {
-		// Fill in missing owner/repo from configuration if needed
-		owner := prRef.Owner
-		repo := prRef.Repository
-		
-		if owner == "" {
-			owner = sc.config.DefaultOwner
+	// Attribute the review to the triggering user's GitHub login when known
+	githubLogin, mapped := sc.users.lookup(match.SourceMessage.User)
+	if !mapped && sc.config.RequireMappedUser {
+		sc.handleUnmappedUser(match.SourceMessage)
+		for _, prRef := range match.PRReferences {
+			sc.logSkip(match.SourceMessage, &approvalDecision{
+				Owner:      prRef.Owner,
+				Repository: prRef.Repository,
+				PRNumber:   prRef.Number,
+				User:       match.SourceMessage.User,
+				Channel:    match.SourceMessage.Channel,
+				Decision:   decisionSkipped,
+				Outcome:    outcomeNone,
+				Reason:     "Slack user has no GitHub mapping",
+				SkipReason: SkipUnmappedUser,
+			})
 		}
-		if repo == "" {
-			repo = sc.config.DefaultRepo
+		return
+	}
+	
+	var approvalReqs []*ApprovalRequest
+	for _, prRef := range match.PRReferences {
+		if prRef.linked() {
+			resolved, err := sc.resolveLinkedReference(ctx, prRef)
+			if err != nil {
+				logWarn("Skipping %s in %s/%s: %v", prRef.linkDescription(), prRef.Owner, prRef.Repository, err)
+				outcome := outcomeAmbiguous
+				if errors.Is(err, errCommitHasNoPR) || errors.Is(err, errBranchHasNoPR) {
+					outcome = outcomeNoPR
+				}
+				sc.react(match.SourceMessage.Channel, match.SourceMessage.Timestamp, outcome)
+				sc.logSkip(match.SourceMessage, &approvalDecision{
+					Owner:      prRef.Owner,
+					Repository: prRef.Repository,
+					User:       match.SourceMessage.User,
+					Channel:    match.SourceMessage.Channel,
+					Decision:   decisionSkipped,
+					Outcome:    outcome,
+					Reason:     err.Error(),
+					SkipReason: SkipUnresolvedLink,
+				})
+				continue
+			}
+			prRef = resolved
 		}
 		
-		// Skip if we still don't have owner/repo
-		if owner == "" || repo == "" {
+		owner, repo, ok := sc.resolvePRTarget(match.SourceMessage.Channel, prRef)
+		if !ok {
 			logWarn("Skipping PR %d: missing owner or repo", prRef.Number)
+			sc.logSkip(match.SourceMessage, &approvalDecision{
+				PRNumber:   prRef.Number,
+				User:       match.SourceMessage.User,
+				Channel:    match.SourceMessage.Channel,
+				Decision:   decisionSkipped,
+				Outcome:    outcomeNone,
+				Reason:     "missing owner or repo",
+				SkipReason: SkipMissingRepo,
+			})
+			continue
+		}
+		
+		// A bare number resolved to a PR the message also links is the same PR
+		if duplicateApprovalRequest(approvalReqs, owner, repo, prRef.Number) {
+			logDebug("Skipping PR %s/%s#%d: referenced more than once in the message", owner, repo, prRef.Number)
 			continue
 		}
 		
@@ -220,40 +704,414 @@ func (sc *SlackClient) processPRApprovals(ctx context.Context, match *PatternMat
 			SourceUser:    match.SourceMessage.User,
 			SourceMessage: match.SourceMessage,
 			Timestamp:     time.Now(),
+			Policy:        policy,
+		}
+		if mapped {
+			approvalReq.Message = fmt.Sprintf("Approved via Slack on behalf of @%s.", githubLogin)
+		}
+		
+		// A per-reference action overrides the repository's and policy's review event for this PR only
+		if prRef.Action != "" {
+			approvalReq.RequestedEvent = prRef.Action
+			approvalReq.Message = actionReviewMessage(prRef.Action, githubLogin, mapped)
+		}
+		
+		// A negated trigger such as "not lgtm" only gets a comment flagging it, unless an
+		// action keyword already asked for something other than an approval
+		if match.Intent != nil && match.Intent.Negated && sc.config.DeescalateNegated && (prRef.Action == "" || prRef.Action == "APPROVE") {
+			logInfo("Trigger for PR %s/%s#%d is negated, commenting instead of approving", owner, repo, prRef.Number)
+			approvalReq.RequestedEvent = "COMMENT"
+			approvalReq.Message = reviewBody(negatedTriggerNote, actionReviewMessage("COMMENT", githubLogin, mapped))
+		}
+		
+		// The trigger emoji may carry its own review body
+		if message, ok := sc.reactionReviewMessage(match.TriggerReaction, approvalReq, githubLogin); ok {
+			approvalReq.Message = message
+		}
+		
+		// Each PR is approved at most once per root message, across edits and redeliveries
+		if !sc.dedupe.claim(approvalKey(approvalReq)) {
+			logDebug("Skipping PR %s/%s#%d: already processed for this message", owner, repo, prRef.Number)
+			continue
 		}
 		
-		// Process the approval (this will be async in a real implementation)
-		go sc.processApproval(ctx, approvalReq)
+		approvalReqs = append(approvalReqs, approvalReq)
+	}
+	
+	if len(approvalReqs) == 0 {
+		return
+	}
+	
+	// Add eyes reaction - processing started
+	sc.react(match.SourceMessage.Channel, match.SourceMessage.Timestamp, outcomeProcessing)
+	
+	// Several PRs from one message share a single composite reaction
+	if sc.config.CompositeReaction && len(approvalReqs) > 1 {
+		batch := newApprovalBatch(len(approvalReqs))
+		for _, approvalReq := range approvalReqs {
+			approvalReq.batch = batch
+		}
+	}
+	
+	for _, approvalReq := range approvalReqs {
+		// Process the approval in the background, unless too many are already running
+		sc.spawnApproval(ctx, approvalReq)
 	}
 }
 
 // processApproval processes a single PR approval request
 func (sc *SlackClient) processApproval(ctx context.Context, req *ApprovalRequest) {
-	logDebug("Starting PR approval: %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
-	
-	// Validate PR exists and is in valid state first
-	if err := sc.githubClient.ValidatePRReference(ctx, req.Owner, req.Repository, req.PRNumber); err != nil {
-		logError("PR validation failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+	// The kill switch holds every approval. A queued one is decided once approvals resume
+	// and it runs again, so nothing is recorded for it now.
+	if paused, queued := sc.pause.hold(req, sc.config.QueueWhilePaused); paused {
+		if queued {
+			logInfo("Approvals paused, queueing PR %s/%s#%d until they resume", req.Owner, req.Repository, req.PRNumber)
+			sc.reactQueued(req, outcomePaused)
+			return
+		}
+		if sc.config.QueueWhilePaused {
+			logWarn("Approvals paused and %d already queued, dropping PR %s/%s#%d", maxPausedApprovals, req.Owner, req.Repository, req.PRNumber)
+		} else {
+			logInfo("Approvals paused, skipping PR %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+		}
+		sc.rejectApproval(req, outcomePaused, "approvals paused", SkipPaused)
 		return
 	}
 	
-	// Approve PR with retry logic
-	result, err := sc.githubClient.ApprovePRWithRetry(ctx, req)
+	// Exactly one decision line is logged per PR, whatever the outcome
+	decision := newApprovalDecision(req)
+	defer sc.finishQueued(ctx, req)
+	defer logDecision(decision)
+	defer sc.receipts.record(decision)
+	defer sc.skipAudit.record(req.SourceMessage, decision)
+	defer sc.finishBatchDecision(req, decision)
+	
+	// A deploy freeze holds approvals until it ends, except for the override allowlist
+	if hold := sc.freezeHold(req.SourceUser); hold != nil {
+		if !sc.config.QueueDuringFreeze {
+			sc.skipHeld(req, decision, hold)
+			return
+		}
+		
+		logInfo("%s, queueing PR %s/%s#%d", hold.reason, req.Owner, req.Repository, req.PRNumber)
+		sc.reactQueued(req, outcomeFrozen)
+		if err := waitForFreeze(ctx, sc.config.FreezeWindows); err != nil {
+			sc.dedupe.release(approvalKey(req))
+			decision.Decision = decisionSkipped
+			decision.Outcome = outcomeNone
+			decision.Reason = err.Error()
+			decision.SkipReason = SkipCancelled
+			return
+		}
+	}
+	
+	// During a GitHub outage approvals would only fail, so hold them until it recovers
+	if hold := sc.degradedHold(); hold != nil {
+		if !sc.config.QueueWhileDegraded {
+			sc.skipHeld(req, decision, hold)
+			return
+		}
+		
+		logInfo("GitHub degraded, queueing PR %s/%s#%d until it recovers", req.Owner, req.Repository, req.PRNumber)
+		sc.reactDelayed(req)
+		if err := sc.githubClient.degraded.wait(ctx); err != nil {
+			sc.dedupe.release(approvalKey(req))
+			decision.Decision = decisionSkipped
+			decision.Outcome = outcomeNone
+			decision.Reason = err.Error()
+			decision.SkipReason = SkipCancelled
+			return
+		}
+	}
+	
+	// The global throttle protects the GitHub token's budget; queued approvals wait their turn
+	if hold := sc.throttleHold(); hold != nil {
+		if !sc.config.QueueWhenThrottled {
+			sc.skipHeld(req, decision, hold)
+			return
+		}
+		
+		logInfo("Approval throttle exceeded, queueing PR %s/%s#%d for about %v", req.Owner, req.Repository, req.PRNumber, hold.retryAfter.Round(time.Second))
+		sc.reactDelayed(req)
+		if err := sc.throttle.wait(ctx); err != nil {
+			sc.dedupe.release(approvalKey(req))
+			decision.Decision = decisionSkipped
+			decision.Outcome = outcomeNone
+			decision.Reason = err.Error()
+			decision.SkipReason = SkipCancelled
+			return
+		}
+	}
+	
+	result, err := sc.runApprovalRetryingLater(ctx, req)
+	
+	// The review was submitted but errored, e.g. cancelled between retries: a failure, not a skip
+	var approvalErr *ProcessingError
+	if errors.As(err, &approvalErr) {
+		sc.dedupe.release(approvalKey(req))
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision = decisionFailed
+		decision.Outcome = outcomeFailed
+		decision.Reason = err.Error()
+		sc.reactOutcome(req, outcomeFailed)
+		return
+	}
 	if err != nil {
-		logError("PR approval failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		sc.dedupe.release(approvalKey(req))
+		metrics.Inc(metricApprovalsSkipped)
+		decision.Decision = decisionSkipped
+		decision.Outcome = outcomeNone
+		decision.Reason = err.Error()
+		decision.SkipReason = approvalSkipReason(err)
+		
+		// Policy failures won't resolve on their own, so flag them on the message
+		if outcome := skipOutcome(err); outcome != "" {
+			decision.Outcome = outcome
+			sc.reactOutcome(req, outcome)
+		}
+		if sc.config.ExplainDenials {
+			sc.explainDenial(req, err)
+		}
+		return
+	}
+	decision.Retries = result.RetryAttempts
+	if result.DryRun {
+		// Nothing was approved, so the message can be tried again for real later
+		sc.dedupe.release(approvalKey(req))
+		decision.Decision = decisionDryRun
+		decision.Outcome = outcomeDryRun
+		decision.Reason = "dry run"
+		sc.reactOutcome(req, outcomeDryRun)
 		return
 	}
+	if !result.Success {
+		sc.dedupe.release(approvalKey(req))
+		metrics.Inc(metricApprovalFailures)
+		decision.Decision = decisionFailed
+		decision.Outcome = outcomeFailed
+		decision.Reason = result.Error
+	} else {
+		reviewDecision(decision, result)
+	}
 	
 	// Log the result
-	if result.Success {
+	if result.Success && !result.approved() {
+		// A comment, other review or fallback is not an approval, so none of the approval's follow-ups run
+		logInfo("PR %s/%s#%d not approved: %s (review ID: %d)", req.Owner, req.Repository, req.PRNumber, decision.Reason, result.ReviewID)
+		sc.reactOutcome(req, decision.Outcome)
+	} else if result.Success && result.AlreadyApproved {
+		logInfo("PR %s/%s#%d was already approved", req.Owner, req.Repository, req.PRNumber)
+		sc.reactOutcome(req, outcomeApproved)
+	} else if result.Success {
 		logInfo("Approved PR %s/%s#%d (review ID: %d)", req.Owner, req.Repository, req.PRNumber, result.ReviewID)
 		logDebug("PR approval details: retries=%d", result.RetryAttempts)
 		// React with checkmark on success
-		sc.addReaction(req.SourceChannel, req.SourceMessage.Timestamp, "white_check_mark")
+		sc.reactOutcome(req, outcomeApproved)
+		sc.messageApprovals.record(ctx, req.SourceChannel, req.SourceMessage.Timestamp, req)
+		
+		// Record who triggered the approval; a comment failure does not undo the approval
+		if sc.config.CommentOnApprove && !result.AlreadyApproved {
+			if err := sc.githubClient.CommentOnApproval(withRequestSource(ctx, req.SourceChannel), req); err != nil {
+				logWarn("Approval succeeded but comment failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+			}
+		}
+		
+		// Clean up open conversations; a failure here does not undo the approval
+		if sc.config.ResolveThreadsOnApprove && !result.AlreadyApproved {
+			resolved, err := sc.githubClient.ResolveReviewThreads(withRequestSource(ctx, req.SourceChannel), req.Owner, req.Repository, req.PRNumber)
+			if err != nil {
+				logWarn("Approval succeeded but resolving review threads failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+			} else if resolved > 0 {
+				logInfo("Resolved %d review thread(s) on %s/%s#%d", resolved, req.Owner, req.Repository, req.PRNumber)
+			}
+		}
+		
+		// Trigger downstream automation; a dispatch failure does not undo the approval
+		if sc.config.OnApproveDispatch {
+			if err := sc.githubClient.DispatchApprovalEvent(withRequestSource(ctx, req.SourceChannel), req, result); err != nil {
+				logWarn("Approval succeeded but dispatch failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+			}
+		}
 	} else {
 		logError("Failed to approve PR %s/%s#%d: %s (retries: %d)", req.Owner, req.Repository, req.PRNumber, result.Error, result.RetryAttempts)
 		// React with X on failure
-		sc.addReaction(req.SourceChannel, req.SourceMessage.Timestamp, "x")
+		sc.reactOutcome(req, outcomeFailed)
+	}
+	
+	// Report in the thread once the approved PR lands or is closed
+	if result.approved() && sc.merges != nil && !sc.merges.watch(ctx, req) {
+		logDebug("Not watching PR %s/%s#%d for a merge: already watched or too many watches", req.Owner, req.Repository, req.PRNumber)
+	}
+}
+
+// runApproval validates and approves a PR, logging failures.
+// A non-nil error means the PR was not submitted for approval at all, except for a
+// *ProcessingError from submitting the review, which is a failed approval.
+func (sc *SlackClient) runApproval(ctx context.Context, req *ApprovalRequest) (*ApprovalResult, error) {
+	logDebug("Starting PR approval: %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+	ctx = withRequestSource(ctx, req.SourceChannel)
+	
+	// Validate PR exists and is in valid state first
+	if err := sc.githubClient.ValidatePRReference(ctx, req.Owner, req.Repository, req.PRNumber, req.Policy); err != nil {
+		logError("PR validation failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		return nil, err
+	}
+	
+	// The external policy endpoint has the last word before approving
+	if err := sc.githubClient.checkExternalPolicy(ctx, req); err != nil {
+		logError("External policy blocked %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		return nil, err
+	}
+	
+	// A dry run stops short of the only write, so matching can be checked end to end
+	if sc.config.DryRun {
+		logInfo("[DRY_RUN] would approve %s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+		return &ApprovalResult{Request: req, Success: true, DryRun: true, ProcessedAt: time.Now()}, nil
+	}
+	
+	// Approve PR with retry logic
+	result, err := sc.githubClient.ApprovePRWithRetry(ctx, req)
+	if err != nil {
+		logError("PR approval failed for %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		return nil, &ProcessingError{Operation: "approve", Cause: err}
+	}
+	
+	return result, nil
+}
+
+// handleInteraction approves the PRs referenced by an "Approve" button click the way
+// a message's are, and replaces the original message with the outcome
+func (sc *SlackClient) handleInteraction(ctx context.Context, callback slack.InteractionCallback) {
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	
+	if sc.config.SlackChannelID != "" && callback.Channel.ID != sc.config.SlackChannelID {
+		return
+	}
+	
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != interactiveApproveActionID {
+			continue
+		}
+		
+		logInfo("Approve button clicked in channel %s by user %s", callback.Channel.ID, callback.User.ID)
+		
+		// The button stays in place for allowed users to click
+		if !sc.config.slackUserAllowed(callback.User.ID) {
+			logInfo("Ignoring approve button click by user %s: not an allowed user", callback.User.ID)
+			continue
+		}
+		
+		prRefs, err := sc.matcher.ExtractPRReferences(action.Value)
+		if err != nil || len(prRefs) == 0 {
+			logWarn("Approve button value has no PR reference: %q", action.Value)
+			sc.updateInteractiveMessage(callback, fmt.Sprintf(":x: No pull request found in %q", action.Value))
+			continue
+		}
+		
+		sourceMsg := &SlackMessage{
+			Text:      action.Value,
+			Channel:   callback.Channel.ID,
+			User:      callback.User.ID,
+			Timestamp: callback.Container.MessageTs,
+			Team:      callback.Team.ID,
+		}
+		
+		// Attribute the review to the clicking user's GitHub login when known
+		githubLogin, mapped := sc.users.lookup(callback.User.ID)
+		if !mapped && sc.config.RequireMappedUser {
+			logInfo("Ignoring approve button click in channel %s: Slack user %s has no GitHub mapping", callback.Channel.ID, callback.User.ID)
+			for _, prRef := range prRefs {
+				sc.logSkip(sourceMsg, &approvalDecision{
+					Owner:      prRef.Owner,
+					Repository: prRef.Repository,
+					PRNumber:   prRef.Number,
+					User:       callback.User.ID,
+					Channel:    callback.Channel.ID,
+					Decision:   decisionSkipped,
+					Outcome:    outcomeNone,
+					Reason:     "Slack user has no GitHub mapping",
+					SkipReason: SkipUnmappedUser,
+				})
+			}
+			sc.updateInteractiveMessage(callback, fmt.Sprintf(":x: <@%s> has no GitHub mapping, so I can't approve on their behalf", callback.User.ID))
+			continue
+		}
+		
+		var notes []string
+		var reqs []*ApprovalRequest
+		for _, prRef := range prRefs {
+			if prRef.linked() {
+				resolved, err := sc.resolveLinkedReference(ctx, prRef)
+				if err != nil {
+					notes = append(notes, fmt.Sprintf(":x: %s: %v", prRef.linkDescription(), err))
+					continue
+				}
+				prRef = resolved
+			}
+			
+			owner, repo, ok := sc.resolvePRTarget(callback.Channel.ID, prRef)
+			if !ok {
+				notes = append(notes, fmt.Sprintf(":x: PR #%d: missing owner or repo", prRef.Number))
+				continue
+			}
+			if duplicateApprovalRequest(reqs, owner, repo, prRef.Number) {
+				continue
+			}
+			
+			req := &ApprovalRequest{
+				Owner:         owner,
+				Repository:    repo,
+				PRNumber:      prRef.Number,
+				SourceChannel: callback.Channel.ID,
+				SourceUser:    callback.User.ID,
+				SourceMessage: sourceMsg,
+				Timestamp:     time.Now(),
+				Policy:        sc.config.PolicyFor(callback.Channel.ID),
+			}
+			if mapped {
+				req.Message = fmt.Sprintf("Approved via Slack on behalf of @%s.", githubLogin)
+			}
+			
+			// A button is approved at most once, however often it is clicked
+			if !sc.dedupe.claim(approvalKey(req)) {
+				notes = append(notes, fmt.Sprintf(":information_source: %s/%s#%d: already processed for this button", owner, repo, prRef.Number))
+				continue
+			}
+			reqs = append(reqs, req)
+		}
+		
+		if len(reqs) == 0 {
+			sc.updateInteractiveMessage(callback, strings.Join(notes, "\n"))
+			continue
+		}
+		
+		// The clicked PRs go through the same holds, limits and records as a message's,
+		// and the button's message is replaced with their outcomes once all are decided
+		batch := newApprovalBatch(len(reqs))
+		batch.interaction = &callback
+		batch.notes = notes
+		for _, req := range reqs {
+			req.batch = batch
+			sc.spawnApproval(ctx, req)
+		}
+	}
+}
+
+// updateInteractiveMessage replaces the message holding the clicked button
+func (sc *SlackClient) updateInteractiveMessage(callback slack.InteractionCallback, text string) {
+	if callback.ResponseURL == "" {
+		return
+	}
+	
+	_, _, err := sc.slackAPI().PostMessage(
+		callback.Channel.ID,
+		slack.MsgOptionReplaceOriginal(callback.ResponseURL),
+		slack.MsgOptionText(text, false),
+	)
+	if err != nil {
+		logDebug("Failed to update interactive message: %v", err)
 	}
 }
 
@@ -264,9 +1122,29 @@ func (sc *SlackClient) addReaction(channel, timestamp, emoji string) {
 		Timestamp: timestamp,
 	}
 	
-	if err := sc.api.AddReaction(emoji, msgRef); err != nil {
+	if err := sc.slackAPI().AddReaction(emoji, msgRef); err != nil {
 		logDebug("Failed to add reaction %s: %v", emoji, err)
 	} else {
 		logDebug("Added reaction %s to message %s", emoji, timestamp)
 	}
-}
\ No newline at end of file
+}
+
+// removeReaction removes one of the bot's reactions from a message, allowing it to be added again
+func (sc *SlackClient) removeReaction(channel, timestamp, emoji string) {
+	sc.reactions.forget(channel, timestamp, emoji)
+	sc.deleteReaction(channel, timestamp, emoji)
+}
+
+// deleteReaction removes one of the bot's reactions from a message in Slack
+func (sc *SlackClient) deleteReaction(channel, timestamp, emoji string) {
+	msgRef := slack.ItemRef{
+		Channel:   channel,
+		Timestamp: timestamp,
+	}
+	
+	if err := sc.slackAPI().RemoveReaction(emoji, msgRef); err != nil {
+		logDebug("Failed to remove reaction %s: %v", emoji, err)
+	} else {
+		logDebug("Removed reaction %s from message %s", emoji, timestamp)
+	}
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// reactionRecorder records the reactions the bot adds and removes, in place of Slack
+type reactionRecorder struct {
+	mu      sync.Mutex
+	added   []string
+	removed []string
+}
+
+// add records an added reaction
+func (rr *reactionRecorder) add(channel, timestamp, emoji string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.added = append(rr.added, emoji)
+}
+
+// remove records a removed reaction
+func (rr *reactionRecorder) remove(channel, timestamp, emoji string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.removed = append(rr.removed, emoji)
+}
+
+// has reports whether emoji was added
+func (rr *reactionRecorder) has(emoji string) bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for _, added := range rr.added {
+		if added == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestSlackClient creates a Slack client matching "lgtm" whose GitHub requests are
+// served by gh, whose Slack API calls all succeed, and whose reactions are recorded
+func newTestSlackClient(t *testing.T, config *Configuration, gh *fakeGitHub) (*SlackClient, *reactionRecorder) {
+	t.Helper()
+	matcher, err := NewPatternMatcher(`(?i)\blgtm\b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := NewSlackClient(config, matcher, newTestGitHubClient(t, config, gh.ServeHTTP), newMemoryStore())
+	if err != nil {
+		t.Fatalf("NewSlackClient: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channel": "C1", "ts": "1.1"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	recorder := &reactionRecorder{}
+	sc.reactions = newReactionBatcher(0, config.ReplaceInterimReactions, recorder.add, recorder.remove)
+	return sc, recorder
+}
+
+// waitForApprovals waits until no approval is in flight
+func waitForApprovals(t *testing.T, sc *SlackClient) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.inFlight.count() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d approval(s) still in flight", sc.inFlight.count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// testMessage is a message from user U1 in channel C1
+func testMessage(text string) *SlackMessage {
+	return &SlackMessage{Text: text, Channel: "C1", User: "U1", Timestamp: "1700000000.000100"}
+}
+
+func TestNegatedTriggerIsNotApproved(t *testing.T) {
+	tests := []struct {
+		message      string
+		wantEvent    string
+		wantReaction string
+	}{
+		{"lgtm https://github.com/o/r/pull/1", "APPROVE", "white_check_mark"},
+		{"not lgtm https://github.com/o/r/pull/1", "COMMENT", "speech_balloon"},
+		{"don't lgtm this https://github.com/o/r/pull/1", "COMMENT", "speech_balloon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, &Configuration{DeescalateNegated: true}, gh)
+
+			sc.processMessage(context.Background(), testMessage(tt.message))
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != 1 || reviews[0] != tt.wantEvent {
+				t.Fatalf("submitted reviews %v, want [%s]", reviews, tt.wantEvent)
+			}
+			if !reactions.has(tt.wantReaction) {
+				t.Errorf("reactions %v, want %s", reactions.added, tt.wantReaction)
+			}
+			if tt.wantEvent != "APPROVE" && reactions.has("white_check_mark") {
+				t.Errorf("a %s review got the approved reaction", tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestApprovalErrorsCountAsFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Configuration
+		gh           *fakeGitHub
+		timeout      time.Duration
+		wantFailures int64
+		wantSkips    int64
+	}{
+		{
+			// The first attempt fails with a 500 and the retry outlives the context
+			name:         "approval error",
+			config:       &Configuration{GitHubRetryDelay: time.Minute},
+			gh:           &fakeGitHub{reviewStatus: http.StatusInternalServerError, reviewError: "boom"},
+			timeout:      200 * time.Millisecond,
+			wantFailures: 1,
+		},
+		{
+			name:         "rejected review",
+			config:       &Configuration{},
+			gh:           &fakeGitHub{reviewStatus: http.StatusForbidden, reviewError: "forbidden"},
+			wantFailures: 1,
+		},
+		{
+			name:      "policy skip",
+			config:    &Configuration{Repositories: []string{"o/other"}},
+			gh:        &fakeGitHub{},
+			wantSkips: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, reactions := newTestSlackClient(t, tt.config, tt.gh)
+			failures, skips := metrics.Counter(metricApprovalFailures), metrics.Counter(metricApprovalsSkipped)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			sc.processMessage(ctx, testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			gotFailures := metrics.Counter(metricApprovalFailures) - failures
+			gotSkips := metrics.Counter(metricApprovalsSkipped) - skips
+			if gotFailures != tt.wantFailures || gotSkips != tt.wantSkips {
+				t.Errorf("counted %d failure(s) and %d skip(s), want %d and %d", gotFailures, gotSkips, tt.wantFailures, tt.wantSkips)
+			}
+			if tt.wantFailures > 0 && !reactions.has("x") {
+				t.Errorf("reactions %v, want x", reactions.added)
+			}
+
+			err := approvalFailuresError(&Configuration{FailOnApprovalError: true}, gotFailures)
+			if (err != nil) != (tt.wantFailures > 0) {
+				t.Errorf("exit error %v with %d failure(s)", err, gotFailures)
+			}
+		})
+	}
+}
+
+// clickApprove clicks an approve button for value and returns the text replacing the
+// button's message, empty when it was left alone
+func clickApprove(t *testing.T, sc *SlackClient, value string) string {
+	t.Helper()
+	var mu sync.Mutex
+	var replaced string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		replaced = body.Text
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	}))
+	defer server.Close()
+
+	callback := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, ResponseURL: server.URL}
+	callback.Channel.ID = "C1"
+	callback.User.ID = "U1"
+	callback.Container.MessageTs = "1700000000.000100"
+	callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: interactiveApproveActionID, Value: value}}
+	sc.handleInteraction(context.Background(), callback)
+	waitForApprovals(t, sc)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return replaced
+}
+
+func TestApproveButtonUsesApprovalPipeline(t *testing.T) {
+	const pr = "https://github.com/o/r/pull/1"
+
+	t.Run("approves", func(t *testing.T) {
+		gh := &fakeGitHub{}
+		sc, _ := newTestSlackClient(t, &Configuration{EnableInteractive: true, DedupeWindow: time.Hour}, gh)
+		if text := clickApprove(t, sc, pr); !strings.Contains(text, "o/r#1 approved by <@U1>") {
+			t.Errorf("message replaced with %q, want the approval", text)
+		}
+		if text := clickApprove(t, sc, pr); !strings.Contains(text, "already processed") {
+			t.Errorf("second click replaced the message with %q, want it deduplicated", text)
+		}
+		if reviews := gh.submitted(); len(reviews) != 1 || reviews[0] != "APPROVE" {
+			t.Errorf("submitted %v, want one approval", reviews)
+		}
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		gh := &fakeGitHub{}
+		sc, _ := newTestSlackClient(t, &Configuration{EnableInteractive: true}, gh)
+		sc.drain.start()
+		skipped := metrics.Counter(metricApprovalsSkipped)
+		if text := clickApprove(t, sc, pr); !strings.Contains(text, "instance is draining") {
+			t.Errorf("message replaced with %q, want the drain rejection", text)
+		}
+		if got := metrics.Counter(metricApprovalsSkipped) - skipped; got != 1 {
+			t.Errorf("skipped approvals grew by %d, want 1", got)
+		}
+		if reviews := gh.submitted(); len(reviews) != 0 {
+			t.Errorf("submitted %v while draining, want nothing", reviews)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		gh := &fakeGitHub{}
+		sc, _ := newTestSlackClient(t, &Configuration{EnableInteractive: true}, gh)
+		sc.pause.pause()
+		if text := clickApprove(t, sc, pr); !strings.Contains(text, "approvals paused") {
+			t.Errorf("message replaced with %q, want the pause", text)
+		}
+		if reviews := gh.submitted(); len(reviews) != 0 {
+			t.Errorf("submitted %v while paused, want nothing", reviews)
+		}
+	})
+
+	t.Run("unmapped user", func(t *testing.T) {
+		gh := &fakeGitHub{}
+		sc, _ := newTestSlackClient(t, &Configuration{EnableInteractive: true, RequireMappedUser: true}, gh)
+		if text := clickApprove(t, sc, pr); !strings.Contains(text, "no GitHub mapping") {
+			t.Errorf("message replaced with %q, want the missing mapping", text)
+		}
+		if reviews := gh.submitted(); len(reviews) != 0 {
+			t.Errorf("submitted %v for an unmapped user, want nothing", reviews)
+		}
+	})
+}
+
+func TestWorkspaceFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		teamID     string
+		sourceTeam string
+		wantReview bool
+	}{
+		{name: "monitored workspace", teamID: "T1", wantReview: true},
+		{name: "team from the message", sourceTeam: "T1", wantReview: true},
+		{name: "other workspace", teamID: "T2"},
+		{name: "no team context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{SlackTeamID: "T1"}, gh)
+			event := &slackevents.MessageEvent{
+				Channel:    "C1",
+				User:       "U1",
+				Text:       "lgtm https://github.com/o/r/pull/1",
+				TimeStamp:  "1700000000.000100",
+				SourceTeam: tt.sourceTeam,
+			}
+			sc.handleMessageEvent(context.Background(), event, tt.teamID, "")
+			waitForApprovals(t, sc)
+
+			if reviewed := len(gh.submitted()) > 0; reviewed != tt.wantReview {
+				t.Errorf("reviewed = %v, want %v", reviewed, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestMessageSubtypes(t *testing.T) {
+	tests := []struct {
+		subType    string
+		wantReview bool
+	}{
+		{subType: "", wantReview: true},
+		{subType: "thread_broadcast", wantReview: true},
+		{subType: "file_share", wantReview: true},
+		{subType: "me_message", wantReview: true},
+		{subType: "channel_join"},
+		{subType: "message_replied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subType, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{}, gh)
+			event := &slackevents.MessageEvent{
+				SubType:         tt.subType,
+				Channel:         "C1",
+				User:            "U1",
+				Text:            "lgtm https://github.com/o/r/pull/1",
+				TimeStamp:       "1700000000.000200",
+				ThreadTimeStamp: "1700000000.000100",
+			}
+			sc.handleMessageEvent(context.Background(), event, "", "")
+			waitForApprovals(t, sc)
+
+			if reviewed := len(gh.submitted()) > 0; reviewed != tt.wantReview {
+				t.Errorf("reviewed = %v, want %v", reviewed, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestOwnMessagesAreIgnored(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      *slackevents.MessageEvent
+		wantReview bool
+	}{
+		{name: "someone else", event: &slackevents.MessageEvent{User: "U1"}, wantReview: true},
+		{name: "bot user without bot_id", event: &slackevents.MessageEvent{User: "UBOT"}},
+		{name: "bot_id without user", event: &slackevents.MessageEvent{BotID: "BBOT"}},
+		{
+			name: "edited by the bot without bot_id",
+			event: &slackevents.MessageEvent{SubType: "message_changed", Message: &slack.Msg{
+				User: "UBOT", Text: "lgtm https://github.com/o/r/pull/1", Timestamp: "1700000000.000100",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{HandleEdits: true}, gh)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "user_id": "UBOT", "bot_id": "BBOT"})
+			}))
+			t.Cleanup(server.Close)
+			sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+			if err := sc.validateTokens(context.Background()); err != nil {
+				t.Fatalf("validateTokens: %v", err)
+			}
+
+			tt.event.Channel = "C1"
+			tt.event.Text = "lgtm https://github.com/o/r/pull/1"
+			tt.event.TimeStamp = "1700000000.000100"
+			sc.handleMessageEvent(context.Background(), tt.event, "", "")
+			waitForApprovals(t, sc)
+
+			if reviewed := len(gh.submitted()) > 0; reviewed != tt.wantReview {
+				t.Errorf("reviewed = %v, want %v", reviewed, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestValidateTokensFailure(t *testing.T) {
+	sc, _ := newTestSlackClient(t, &Configuration{}, &fakeGitHub{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": "invalid_auth"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	var authErr *AuthenticationError
+	if err := sc.validateTokens(context.Background()); !errors.As(err, &authErr) {
+		t.Errorf("validateTokens = %v, want an authentication error", err)
+	}
+	if sc.botUserID != "" || sc.botID != "" {
+		t.Errorf("bot identified as %q/%q from a failed auth.test", sc.botUserID, sc.botID)
+	}
+}
+
+func TestDraftIsSkipped(t *testing.T) {
+	gh := &fakeGitHub{draft: true}
+	sc, reactions := newTestSlackClient(t, &Configuration{SkipDrafts: true}, gh)
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 {
+		t.Errorf("submitted %v for a draft, want nothing", reviews)
+	}
+	if !reactions.has("construction") {
+		t.Errorf("reactions %v, want the skipped_draft reaction", reactions.added)
+	}
+}
+
+func TestDraftIsApprovedWithoutSkipDrafts(t *testing.T) {
+	gh := &fakeGitHub{draft: true}
+	sc, reactions := newTestSlackClient(t, &Configuration{}, gh)
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 1 || reviews[0] != "APPROVE" {
+		t.Errorf("submitted %v for a draft, want an approval", reviews)
+	}
+	if reactions.has("construction") {
+		t.Errorf("reactions %v, want no skipped_draft reaction", reactions.added)
+	}
+}
+
+func TestOversizedMessageIsSkipped(t *testing.T) {
+	trigger := "lgtm https://github.com/o/r/pull/1 "
+	tests := []struct {
+		name       string
+		limit      int
+		text       string
+		wantReview bool
+	}{
+		{name: "within the limit", limit: 100, text: trigger, wantReview: true},
+		{name: "over the limit", limit: 100, text: trigger + strings.Repeat("a", 1<<20)},
+		{name: "no limit", text: trigger + strings.Repeat("a", 1000), wantReview: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, _ := newTestSlackClient(t, &Configuration{MaxMessageLength: tt.limit}, gh)
+			sc.processMessage(context.Background(), testMessage(tt.text))
+			waitForApprovals(t, sc)
+
+			if reviewed := len(gh.submitted()) > 0; reviewed != tt.wantReview {
+				t.Errorf("reviewed = %v, want %v", reviewed, tt.wantReview)
+			}
+		})
+	}
+}
+
+func TestRetryLaterUntilChecksComplete(t *testing.T) {
+	tests := []struct {
+		name         string
+		pendingRuns  int
+		wantApproved bool
+		wantChecks   int
+		wantReaction string
+	}{
+		{name: "pending then green", pendingRuns: 2, wantApproved: true, wantChecks: 3, wantReaction: "white_check_mark"},
+		{name: "still pending after every attempt", pendingRuns: 10, wantChecks: 4, wantReaction: "hourglass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			var mu sync.Mutex
+			checks := 0
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/commits/abc123/check-runs" {
+					mu.Lock()
+					checks++
+					status := "in_progress"
+					if checks > tt.pendingRuns {
+						status = "completed"
+					}
+					mu.Unlock()
+					writeJSON(w, http.StatusOK, map[string]interface{}{
+						"total_count": 1,
+						"check_runs":  []map[string]string{{"status": status}},
+					})
+					return
+				}
+				gh.ServeHTTP(w, r)
+			}
+
+			config := &Configuration{
+				RequireAnyCompletedCheck: true,
+				RetryLaterAttempts:       3,
+				RetryLaterDelay:          time.Millisecond,
+				RetryLaterPolicies:       defaultRetryLaterPolicies,
+				ReplaceInterimReactions:  true,
+			}
+			sc, reactions := newTestSlackClient(t, config, gh)
+			// The check runs go through the handler, everything else to gh
+			sc.githubClient = newTestGitHubClient(t, config, handler)
+			retried := metrics.Counter(metricApprovalsRetriedLater)
+			sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+			waitForApprovals(t, sc)
+
+			if approved := len(gh.submitted()) == 1; approved != tt.wantApproved {
+				t.Errorf("approved = %v, want %v (submitted %v)", approved, tt.wantApproved, gh.submitted())
+			}
+			if checks != tt.wantChecks {
+				t.Errorf("checked the PR %d time(s), want %d", checks, tt.wantChecks)
+			}
+			if got := metrics.Counter(metricApprovalsRetriedLater) - retried; got != int64(tt.wantChecks-1) {
+				t.Errorf("retried later %d time(s), want %d", got, tt.wantChecks-1)
+			}
+			if !reactions.has("hourglass_flowing_sand") || !reactions.has(tt.wantReaction) {
+				t.Errorf("reactions %v, want the waiting reaction and then %s", reactions.added, tt.wantReaction)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// stateExportVersion is the version of the state export format. Bump it on any change
+// an older binary would misread; import refuses exports newer than it understands.
+const stateExportVersion = 1
+
+// stateNamespaces are the store namespaces carried over by a state export: the dedupe
+// cache and approval history, so the new instance doesn't approve the same messages
+// again, the approval throttle's bucket, the pause state, user mappings and channel
+// names, and the reactions of approvals still queued, which the new instance removes
+var stateNamespaces = []string{
+	storeNamespaceDedupe,
+	storeNamespaceMessageApprovals,
+	storeNamespaceThrottle,
+	storeNamespacePause,
+	storeNamespaceUserMappings,
+	storeNamespaceChannelNames,
+	storeNamespaceQueuedReactions,
+}
+
+// stateExport is the file written by `lgtm state export` and read by `lgtm state import`
+type stateExport struct {
+	Version    int                     `json:"version"`
+	ExportedAt time.Time               `json:"exported_at"`
+	Instance   string                  `json:"instance,omitempty"`
+	Namespaces map[string][]StoreEntry `json:"namespaces"`
+}
+
+// openStateStore opens the persistent store a state command works on. The memory store
+// only lives inside a running bot, so it has no state to export or import.
+func openStateStore(ctx context.Context, c *cli.Context) (Store, func() error, error) {
+	config := &Configuration{Store: c.String("store"), StorePath: c.String("store-path")}
+	if config.Store != StoreSQLite {
+		return nil, nil, fmt.Errorf("the %s store keeps no state outside the running bot; state export and import need --store sqlite", config.Store)
+	}
+	store, err := openStore(ctx, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s store: %v", config.Store, err)
+	}
+	return newInstanceStore(store, c.String("instance-name")), store.Close, nil
+}
+
+// exportState collects every live entry of the exported namespaces
+func exportState(ctx context.Context, store Store, instance string) (*stateExport, error) {
+	export := &stateExport{
+		Version:    stateExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Instance:   instance,
+		Namespaces: make(map[string][]StoreEntry),
+	}
+	for _, namespace := range stateNamespaces {
+		entries, err := store.Entries(ctx, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", namespace, err)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+		export.Namespaces[namespace] = entries
+	}
+	return export, nil
+}
+
+// importState writes an export's entries into the store, keeping what is left of each
+// entry's expiry. Lapsed entries and unknown namespaces are skipped. It returns the
+// number of entries imported per namespace.
+func importState(ctx context.Context, store Store, export *stateExport) (map[string]int, error) {
+	switch {
+	case export.Version == 0:
+		return nil, fmt.Errorf("not an lgtm state export: no format version")
+	case export.Version > stateExportVersion:
+		return nil, fmt.Errorf("state export format version %d is newer than this binary supports (%d); import it with a newer lgtm", export.Version, stateExportVersion)
+	}
+
+	imported := make(map[string]int)
+	now := time.Now()
+	for namespace, entries := range export.Namespaces {
+		if !containsFold(stateNamespaces, namespace) {
+			logWarn("Skipping %d entries in unknown namespace %q", len(entries), namespace)
+			continue
+		}
+		for _, entry := range entries {
+			ttl := entry.ttl(now)
+			if ttl < 0 {
+				continue
+			}
+			if err := store.Put(ctx, namespace, entry.Key, entry.Value, ttl); err != nil {
+				return imported, fmt.Errorf("failed to import %s entry %q: %v", namespace, entry.Key, err)
+			}
+			imported[namespace]++
+		}
+	}
+	return imported, nil
+}
+
+// stateExportCommand writes the bot state in the store to a file
+func stateExportCommand(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("usage: lgtm state export <file>")
+	}
+	logLevel = strings.ToLower(c.String("log-level"))
+
+	ctx := context.Background()
+	store, closeStore, err := openStateStore(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	export, err := exportState(ctx, store, c.String("instance-name"))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	// User mappings are personal data, so the file is private like the database
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	for _, namespace := range stateNamespaces {
+		fmt.Printf("%s Exported %d %s entries\n", okMark(), len(export.Namespaces[namespace]), namespace)
+	}
+	fmt.Printf("State written to %s (format version %d)\n", path, export.Version)
+	return nil
+}
+
+// stateImportCommand loads a state export into the store
+func stateImportCommand(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("usage: lgtm state import <file>")
+	}
+	logLevel = strings.ToLower(c.String("log-level"))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var export stateExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	ctx := context.Background()
+	store, closeStore, err := openStateStore(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	imported, err := importState(ctx, store, &export)
+	if err != nil {
+		return err
+	}
+	for _, namespace := range stateNamespaces {
+		fmt.Printf("%s Imported %d %s entries\n", okMark(), imported[namespace], namespace)
+	}
+	return nil
+}
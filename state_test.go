@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestStateExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	from := storeBackends[StoreSQLite](t)
+	defer from.Close()
+
+	from.Put(ctx, storeNamespaceDedupe, "o/r#1@C1/1.1", "2026-10-14T00:00:00Z", time.Hour)
+	from.Put(ctx, storeNamespaceMessageApprovals, "C1/1.1", "o/r#1", 24*time.Hour)
+	from.Put(ctx, storeNamespaceUserMappings, "U1", "octocat", 0)
+	from.Put(ctx, "unrelated", "k", "v", 0)
+	throttle := newApprovalThrottle(2, from)
+	throttle.take()
+
+	export, err := exportState(ctx, from, "")
+	if err != nil {
+		t.Fatalf("exportState: %v", err)
+	}
+	if export.Version != stateExportVersion {
+		t.Errorf("export version %d, want %d", export.Version, stateExportVersion)
+	}
+	if _, ok := export.Namespaces["unrelated"]; ok {
+		t.Error("exported a namespace the bot doesn't use")
+	}
+
+	to := storeBackends[StoreSQLite](t)
+	defer to.Close()
+	imported, err := importState(ctx, to, export)
+	if err != nil {
+		t.Fatalf("importState: %v", err)
+	}
+	for _, namespace := range []string{storeNamespaceDedupe, storeNamespaceMessageApprovals, storeNamespaceUserMappings, storeNamespaceThrottle} {
+		if imported[namespace] != 1 {
+			t.Errorf("imported %d %s entries, want 1", imported[namespace], namespace)
+		}
+	}
+
+	// The dedupe claim carries over, so the new instance doesn't approve the message again
+	dedupe := newDedupeCache(time.Hour, to)
+	if dedupe.claim("o/r#1@C1/1.1") {
+		t.Error("imported dedupe entry claimed again")
+	}
+	if login, ok, _ := to.Get(ctx, storeNamespaceUserMappings, "U1"); !ok || login != "octocat" {
+		t.Errorf("user mapping = %q, %v, want octocat", login, ok)
+	}
+	if restored := newApprovalThrottle(2, to); restored.tokens >= 2 {
+		t.Errorf("imported throttle has %.2f tokens, want the spent token still missing", restored.tokens)
+	}
+
+	// Expiries carry over rather than restarting or becoming permanent
+	entries, _ := to.Entries(ctx, storeNamespaceDedupe)
+	if len(entries) != 1 || entries[0].ExpiresAt.IsZero() || time.Until(entries[0].ExpiresAt) > time.Hour {
+		t.Errorf("imported dedupe entries %+v, want the original expiry", entries)
+	}
+}
+
+func TestStateImportSkipsLapsedAndUnknown(t *testing.T) {
+	store := newMemoryStore()
+	export := &stateExport{
+		Version: stateExportVersion,
+		Namespaces: map[string][]StoreEntry{
+			storeNamespaceDedupe: {
+				{Key: "lapsed", Value: "v", ExpiresAt: time.Now().Add(-time.Minute)},
+				{Key: "live", Value: "v", ExpiresAt: time.Now().Add(time.Minute)},
+			},
+			"from-the-future": {{Key: "k", Value: "v"}},
+		},
+	}
+
+	imported, err := importState(context.Background(), store, export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported[storeNamespaceDedupe] != 1 || len(imported) != 1 {
+		t.Errorf("imported %v, want only the live dedupe entry", imported)
+	}
+	if _, ok, _ := store.Get(context.Background(), storeNamespaceDedupe, "lapsed"); ok {
+		t.Error("lapsed entry imported")
+	}
+}
+
+func TestStateImportVersionMismatch(t *testing.T) {
+	for _, version := range []int{0, stateExportVersion + 1} {
+		_, err := importState(context.Background(), newMemoryStore(), &stateExport{Version: version})
+		if err == nil {
+			t.Errorf("importing format version %d succeeded, want an error", version)
+		}
+	}
+}
+
+func TestStateCommandsNeedPersistentStore(t *testing.T) {
+	app := &cli.App{
+		Commands: []*cli.Command{{
+			Name:   "export",
+			Action: stateExportCommand,
+			Flags:  append(storeFlags(), stateInstanceFlag(), &cli.StringFlag{Name: "log-level"}),
+		}},
+	}
+	err := app.Run([]string{"lgtm", "export", "--store", StoreMemory, filepath.Join(t.TempDir(), "state.json")})
+	if err == nil || !strings.Contains(err.Error(), "--store sqlite") {
+		t.Errorf("exporting the memory store = %v, want an error asking for the sqlite store", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statsdSink sends every counter increment and gauge update of the registry to a
+// StatsD server over UDP. The registry's constant labels become DogStatsD tags.
+type statsdSink struct {
+	conn net.Conn
+}
+
+// newStatsdSink connects a UDP socket to addr; an unreachable server is not an error,
+// since UDP only resolves the address
+func newStatsdSink(addr string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn}, nil
+}
+
+// count sends a counter increment
+func (s *statsdSink) count(name string, delta int64, tags string) {
+	s.send(name + ":" + strconv.FormatInt(delta, 10) + "|c" + tags)
+}
+
+// gauge sends a gauge value
+func (s *statsdSink) gauge(name string, value float64, tags string) {
+	s.send(name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|g" + tags)
+}
+
+// send writes one datagram. Metrics must never hold up approvals, so send errors,
+// e.g. nothing listening on the port, are dropped.
+func (s *statsdSink) send(line string) {
+	s.conn.Write([]byte(line))
+}
+
+// close closes the socket
+func (s *statsdSink) close() {
+	s.conn.Close()
+}
+
+// statsdTags formats labels as a DogStatsD tag suffix, sorted by name, e.g.
+// "|#instance:team-a"; it is empty when there are no labels
+func statsdTags(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	tags := make([]string, 0, len(labels))
+	for name, value := range labels {
+		tags = append(tags, name+":"+value)
+	}
+	sort.Strings(tags)
+	return "|#" + strings.Join(tags, ",")
+}
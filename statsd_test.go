@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenStatsD starts a fake StatsD server and returns its address and a function
+// reading the next n datagrams
+func listenStatsD(t *testing.T) (string, func(n int) []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening for StatsD: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func(n int) []string {
+		t.Helper()
+		var lines []string
+		buf := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for len(lines) < n {
+			size, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("reading StatsD datagram %d of %d: %v", len(lines)+1, n, err)
+			}
+			lines = append(lines, string(buf[:size]))
+		}
+		return lines
+	}
+}
+
+func TestStatsDMirrorsMetrics(t *testing.T) {
+	addr, read := listenStatsD(t)
+	sink, err := newStatsdSink(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.close()
+
+	registry := newMetricsRegistry()
+	registry.SetLabel("instance", "team-a")
+	registry.SetGauge(metricApprovalsInFlight, 2)
+	registry.SetStatsD(sink)
+	registry.Inc(metricApprovals)
+	registry.Add(metricApprovalFailures, 3)
+	registry.SetGauge(metricApprovalsInFlight, 0.5)
+
+	want := []string{
+		metricApprovalsInFlight + ":2|g|#instance:team-a",
+		metricApprovals + ":1|c|#instance:team-a",
+		metricApprovalFailures + ":3|c|#instance:team-a",
+		metricApprovalsInFlight + ":0.5|g|#instance:team-a",
+	}
+	got := read(len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("datagram %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+	if registry.Counter(metricApprovals) != 1 {
+		t.Errorf("%s = %d in the registry, want 1 alongside StatsD", metricApprovals, registry.Counter(metricApprovals))
+	}
+}
+
+func TestStatsDSendFailuresAreDropped(t *testing.T) {
+	// Nothing listens once the fake server is closed, so sends fail with connection refused
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	sink, err := newStatsdSink(addr)
+	if err != nil {
+		t.Fatalf("newStatsdSink for an unreachable server: %v", err)
+	}
+	defer sink.close()
+
+	registry := newMetricsRegistry()
+	registry.SetStatsD(sink)
+	for i := 0; i < 3; i++ {
+		registry.Inc(metricApprovals)
+	}
+	if got := registry.Counter(metricApprovals); got != 3 {
+		t.Errorf("%s = %d, want 3 despite the failed sends", metricApprovals, got)
+	}
+}
+
+func TestStatsdTags(t *testing.T) {
+	if got := statsdTags(nil); got != "" {
+		t.Errorf("statsdTags(nil) = %q, want empty", got)
+	}
+	if got := statsdTags(map[string]string{"team": "a", "instance": "b"}); got != "|#instance:b,team:a" {
+		t.Errorf("statsdTags = %q, want sorted DogStatsD tags", got)
+	}
+}
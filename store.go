@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store backends selectable with the Store option
+const (
+	StoreMemory = "memory"
+	StoreSQLite = "sqlite"
+)
+
+// Store namespaces used by the bot's stateful features
+const (
+	storeNamespaceDedupe           = "dedupe"
+	storeNamespaceUserMappings     = "user_mappings"
+	storeNamespacePause            = "pause"
+	storeNamespaceChannelNames     = "channel_names"
+	storeNamespaceMessageApprovals = "message_approvals"
+	storeNamespaceThrottle         = "throttle"
+	storeNamespaceQueuedReactions  = "queued_reactions"
+)
+
+// StoreEntry is a live key with its value and expiry, as copied between stores
+type StoreEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// ExpiresAt is when the entry lapses, zero when it is kept until deleted
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// ttl returns the time the entry has left, zero for an entry without an expiry and
+// negative once it has lapsed
+func (e StoreEntry) ttl(now time.Time) time.Duration {
+	if e.ExpiresAt.IsZero() {
+		return 0
+	}
+	if left := e.ExpiresAt.Sub(now); left > 0 {
+		return left
+	}
+	return -1
+}
+
+// Store is the key-value backend shared by every stateful feature. Keys live in a
+// namespace per feature; a ttl of zero keeps an entry until it is deleted.
+type Store interface {
+	// Get returns the value of a key, reporting false when it is missing or expired
+	Get(ctx context.Context, namespace, key string) (string, bool, error)
+	// Put sets a key, replacing any existing value
+	Put(ctx context.Context, namespace, key, value string, ttl time.Duration) error
+	// PutIfAbsent sets a key only if it is missing or expired, reporting whether it did
+	PutIfAbsent(ctx context.Context, namespace, key, value string, ttl time.Duration) (bool, error)
+	// Delete removes a key; deleting a missing key is not an error
+	Delete(ctx context.Context, namespace, key string) error
+	// List returns every live key and value in a namespace
+	List(ctx context.Context, namespace string) (map[string]string, error)
+	// Entries returns every live entry in a namespace with its expiry
+	Entries(ctx context.Context, namespace string) ([]StoreEntry, error)
+	// Close releases the backend
+	Close() error
+}
+
+// openStore opens the backend selected in the configuration
+func openStore(ctx context.Context, config *Configuration) (Store, error) {
+	switch config.Store {
+	case "", StoreMemory:
+		return newMemoryStore(), nil
+	case StoreSQLite:
+		return openSQLiteStore(ctx, config.StorePath)
+	default:
+		return nil, fmt.Errorf("unknown store %q", config.Store)
+	}
+}
+
+// memoryEntry is one value held by the memory store
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// expired reports whether the entry has passed its expiry
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// memoryStore keeps state in process memory, so it is lost on restart
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]memoryEntry
+}
+
+// newMemoryStore creates an empty memory store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]map[string]memoryEntry)}
+}
+
+// expiresAt converts a ttl to an absolute expiry, zero meaning never
+func expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// Get returns the value of a key
+func (ms *memoryStore) Get(ctx context.Context, namespace, key string) (string, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry, ok := ms.entries[namespace][key]
+	if !ok || entry.expired(time.Now()) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Put sets a key
+func (ms *memoryStore) Put(ctx context.Context, namespace, key, value string, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.put(namespace, key, value, ttl, time.Now())
+	return nil
+}
+
+// PutIfAbsent sets a key only if it is missing or expired
+func (ms *memoryStore) PutIfAbsent(ctx context.Context, namespace, key, value string, ttl time.Duration) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	ms.prune(namespace, now)
+	if _, exists := ms.entries[namespace][key]; exists {
+		return false, nil
+	}
+	ms.put(namespace, key, value, ttl, now)
+	return true, nil
+}
+
+// put stores an entry; callers hold the lock
+func (ms *memoryStore) put(namespace, key, value string, ttl time.Duration, now time.Time) {
+	if ms.entries[namespace] == nil {
+		ms.entries[namespace] = make(map[string]memoryEntry)
+	}
+	ms.entries[namespace][key] = memoryEntry{value: value, expiresAt: expiresAt(now, ttl)}
+}
+
+// prune drops expired entries from a namespace; callers hold the lock
+func (ms *memoryStore) prune(namespace string, now time.Time) {
+	for key, entry := range ms.entries[namespace] {
+		if entry.expired(now) {
+			delete(ms.entries[namespace], key)
+		}
+	}
+}
+
+// Delete removes a key
+func (ms *memoryStore) Delete(ctx context.Context, namespace, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.entries[namespace], key)
+	return nil
+}
+
+// List returns every live key and value in a namespace
+func (ms *memoryStore) List(ctx context.Context, namespace string) (map[string]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.prune(namespace, time.Now())
+	values := make(map[string]string, len(ms.entries[namespace]))
+	for key, entry := range ms.entries[namespace] {
+		values[key] = entry.value
+	}
+	return values, nil
+}
+
+// Entries returns every live entry in a namespace
+func (ms *memoryStore) Entries(ctx context.Context, namespace string) ([]StoreEntry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.prune(namespace, time.Now())
+	entries := make([]StoreEntry, 0, len(ms.entries[namespace]))
+	for key, entry := range ms.entries[namespace] {
+		entries = append(entries, StoreEntry{Key: key, Value: entry.value, ExpiresAt: entry.expiresAt})
+	}
+	return entries, nil
+}
+
+// Close is a no-op for the memory store
+func (ms *memoryStore) Close() error {
+	return nil
+}
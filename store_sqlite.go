@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order; each index is a schema version.
+// Never edit an existing entry, only append.
+var sqliteMigrations = []string{
+	`CREATE TABLE kv (
+		namespace  TEXT    NOT NULL,
+		key        TEXT    NOT NULL,
+		value      TEXT    NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (namespace, key)
+	)`,
+	`CREATE INDEX kv_expires_at ON kv (expires_at) WHERE expires_at > 0`,
+}
+
+// sqliteStore keeps state in a SQLite database file, so it survives restarts
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// openSQLiteStore opens the database at path and applies pending migrations
+func openSQLiteStore(ctx context.Context, path string) (*sqliteStore, error) {
+	db, err := openSQLiteDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// openSQLiteDB opens a database file without migrating it
+func openSQLiteDB(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("store path is required for the sqlite store")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %v", path, err)
+	}
+	// SQLite allows one writer at a time
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// migrateSQLite applies pending migrations and returns the versions it applied
+func migrateSQLite(ctx context.Context, db *sql.DB) ([]int, error) {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %v", err)
+	}
+
+	current, err := sqliteSchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if current > len(sqliteMigrations) {
+		return nil, fmt.Errorf("store schema version %d is newer than this binary supports (%d)", current, len(sqliteMigrations))
+	}
+
+	var applied []int
+	for version := current + 1; version <= len(sqliteMigrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[version-1]); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %d failed: %v", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("failed to record migration %d: %v", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, fmt.Errorf("failed to commit migration %d: %v", version, err)
+		}
+		applied = append(applied, version)
+	}
+	return applied, nil
+}
+
+// sqliteSchemaVersion returns the latest applied migration, 0 for a new database
+func sqliteSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// unixExpiry converts a ttl to a unix expiry, 0 meaning never
+func unixExpiry(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).Unix()
+}
+
+// Get returns the value of a key
+func (ss *sqliteStore) Get(ctx context.Context, namespace, key string) (string, bool, error) {
+	var value string
+	err := ss.db.QueryRowContext(ctx,
+		`SELECT value FROM kv WHERE namespace = ? AND key = ? AND (expires_at = 0 OR expires_at > ?)`,
+		namespace, key, time.Now().Unix()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Put sets a key
+func (ss *sqliteStore) Put(ctx context.Context, namespace, key, value string, ttl time.Duration) error {
+	_, err := ss.db.ExecContext(ctx,
+		`INSERT INTO kv (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (namespace, key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		namespace, key, value, unixExpiry(ttl))
+	return err
+}
+
+// PutIfAbsent sets a key only if it is missing or expired
+func (ss *sqliteStore) PutIfAbsent(ctx context.Context, namespace, key, value string, ttl time.Duration) (bool, error) {
+	result, err := ss.db.ExecContext(ctx,
+		`INSERT INTO kv (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (namespace, key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+		 WHERE kv.expires_at > 0 AND kv.expires_at <= ?`,
+		namespace, key, value, unixExpiry(ttl), time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// Delete removes a key
+func (ss *sqliteStore) Delete(ctx context.Context, namespace, key string) error {
+	_, err := ss.db.ExecContext(ctx, `DELETE FROM kv WHERE namespace = ? AND key = ?`, namespace, key)
+	return err
+}
+
+// List returns every live key and value in a namespace, pruning expired ones
+func (ss *sqliteStore) List(ctx context.Context, namespace string) (map[string]string, error) {
+	now := time.Now().Unix()
+	if _, err := ss.db.ExecContext(ctx, `DELETE FROM kv WHERE namespace = ? AND expires_at > 0 AND expires_at <= ?`, namespace, now); err != nil {
+		return nil, err
+	}
+
+	rows, err := ss.db.QueryContext(ctx, `SELECT key, value FROM kv WHERE namespace = ?`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, rows.Err()
+}
+
+// Entries returns every live entry in a namespace, pruning expired ones
+func (ss *sqliteStore) Entries(ctx context.Context, namespace string) ([]StoreEntry, error) {
+	now := time.Now().Unix()
+	if _, err := ss.db.ExecContext(ctx, `DELETE FROM kv WHERE namespace = ? AND expires_at > 0 AND expires_at <= ?`, namespace, now); err != nil {
+		return nil, err
+	}
+
+	rows, err := ss.db.QueryContext(ctx, `SELECT key, value, expires_at FROM kv WHERE namespace = ?`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []StoreEntry
+	for rows.Next() {
+		var entry StoreEntry
+		var expiresAt int64
+		if err := rows.Scan(&entry.Key, &entry.Value, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt > 0 {
+			entry.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Close closes the database
+func (ss *sqliteStore) Close() error {
+	return ss.db.Close()
+}
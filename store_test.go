@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// storeBackends opens a fresh store of every backend, for tests every backend must pass
+var storeBackends = map[string]func(t *testing.T) Store{
+	StoreMemory: func(t *testing.T) Store { return newMemoryStore() },
+	StoreSQLite: func(t *testing.T) Store {
+		store, err := openSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "lgtm.db"))
+		if err != nil {
+			t.Fatalf("openSQLiteStore: %v", err)
+		}
+		return store
+	},
+}
+
+func TestStoreBackends(t *testing.T) {
+	for name, open := range storeBackends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("put and get", func(t *testing.T) { testStorePutGet(t, open(t)) })
+			t.Run("put if absent", func(t *testing.T) { testStorePutIfAbsent(t, open(t)) })
+			t.Run("namespaces", func(t *testing.T) { testStoreNamespaces(t, open(t)) })
+			t.Run("expiry", func(t *testing.T) { testStoreExpiry(t, open(t)) })
+		})
+	}
+}
+
+func testStorePutGet(t *testing.T, store Store) {
+	defer store.Close()
+	ctx := context.Background()
+
+	if _, ok, err := store.Get(ctx, "ns", "k"); err != nil || ok {
+		t.Fatalf("Get of a missing key = %v, %v, want not found", ok, err)
+	}
+	if err := store.Put(ctx, "ns", "k", "v1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "ns", "k", "v2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := store.Get(ctx, "ns", "k"); err != nil || !ok || value != "v2" {
+		t.Errorf("Get = %q, %v, %v, want the replaced value v2", value, ok, err)
+	}
+
+	if err := store.Delete(ctx, "ns", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "ns", "k"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "ns", "k"); ok {
+		t.Error("key still found after Delete")
+	}
+}
+
+func testStorePutIfAbsent(t *testing.T, store Store) {
+	defer store.Close()
+	ctx := context.Background()
+
+	if claimed, err := store.PutIfAbsent(ctx, "ns", "k", "first", time.Hour); err != nil || !claimed {
+		t.Fatalf("first PutIfAbsent = %v, %v, want claimed", claimed, err)
+	}
+	if claimed, err := store.PutIfAbsent(ctx, "ns", "k", "second", time.Hour); err != nil || claimed {
+		t.Errorf("second PutIfAbsent = %v, %v, want not claimed", claimed, err)
+	}
+	if value, _, _ := store.Get(ctx, "ns", "k"); value != "first" {
+		t.Errorf("value = %q, want the first claim kept", value)
+	}
+}
+
+func testStoreNamespaces(t *testing.T, store Store) {
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Put(ctx, "a", "k1", "v1", 0)
+	store.Put(ctx, "a", "k2", "v2", 0)
+	store.Put(ctx, "b", "k1", "other", 0)
+
+	values, err := store.List(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["k1"] != "v1" || values["k2"] != "v2" {
+		t.Errorf("List(a) = %v, want k1 and k2 only", values)
+	}
+	if entries, err := store.Entries(ctx, "a"); err != nil || len(entries) != 2 {
+		t.Errorf("Entries(a) = %+v, %v, want k1 and k2", entries, err)
+	}
+	if value, _, _ := store.Get(ctx, "b", "k1"); value != "other" {
+		t.Errorf("Get(b, k1) = %q, want the value from its own namespace", value)
+	}
+}
+
+func testStoreExpiry(t *testing.T, store Store) {
+	defer store.Close()
+	ctx := context.Background()
+
+	store.Put(ctx, "ns", "short", "v", 10*time.Millisecond)
+	store.Put(ctx, "ns", "forever", "v", 0)
+	// SQLite keeps expiries in whole seconds
+	time.Sleep(1100 * time.Millisecond)
+
+	if _, ok, _ := store.Get(ctx, "ns", "short"); ok {
+		t.Error("expired key still found")
+	}
+	if values, _ := store.List(ctx, "ns"); len(values) != 1 || values["forever"] != "v" {
+		t.Errorf("List = %v, want only the key without a ttl", values)
+	}
+	if claimed, err := store.PutIfAbsent(ctx, "ns", "short", "again", time.Hour); err != nil || !claimed {
+		t.Errorf("PutIfAbsent over an expired key = %v, %v, want claimed", claimed, err)
+	}
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lgtm.db")
+
+	db, err := openSQLiteDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	applied, err := migrateSQLite(ctx, db)
+	if err != nil {
+		t.Fatalf("migrating a new database: %v", err)
+	}
+	if len(applied) != len(sqliteMigrations) {
+		t.Errorf("applied %v to a new database, want all %d migrations", applied, len(sqliteMigrations))
+	}
+	if applied, err := migrateSQLite(ctx, db); err != nil || len(applied) != 0 {
+		t.Errorf("migrating again applied %v, %v, want nothing", applied, err)
+	}
+
+	// A database migrated by a newer binary is refused rather than misread
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, 0)`, len(sqliteMigrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateSQLite(ctx, db); err == nil {
+		t.Error("migrating a database with a newer schema succeeded, want an error")
+	}
+}
+
+func TestSQLiteStoreSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lgtm.db")
+
+	store, err := openSQLiteStore(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put(ctx, storeNamespaceDedupe, "k", "v", time.Hour)
+	store.Close()
+
+	store, err = openSQLiteStore(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if value, ok, _ := store.Get(ctx, storeNamespaceDedupe, "k"); !ok || value != "v" {
+		t.Errorf("Get after reopening = %q, %v, want the stored value", value, ok)
+	}
+}
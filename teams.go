@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v75/github"
+)
+
+// teamReviewer tracks whether the bot can review on behalf of the configured team.
+// GitHub has no API to submit a review as a team: a review counts toward a team's
+// CODEOWNERS entry when its author is an active member of that team.
+type teamReviewer struct {
+	mu      sync.Mutex
+	checked bool
+	member  bool
+}
+
+// parseReviewTeam splits an org/team-slug reference
+func parseReviewTeam(value string) (string, string, error) {
+	org, slug, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(value), "@"), "/")
+	if !ok || org == "" || slug == "" || strings.Contains(slug, "/") {
+		return "", "", &ConfigError{Field: "ReviewTeam", Message: fmt.Sprintf("team %q must be in org/team-slug form", value)}
+	}
+	return org, slug, nil
+}
+
+// reviewTeamNote returns the line naming the team a review is submitted for, or empty
+// when no team is configured or the bot isn't an active member of it, in which case
+// the review is an ordinary user review.
+func (gc *GitHubClient) reviewTeamNote(ctx context.Context) string {
+	if gc.config.ReviewTeam == "" {
+		return ""
+	}
+	org, slug, err := parseReviewTeam(gc.config.ReviewTeam)
+	if err != nil {
+		return ""
+	}
+
+	gc.teams.mu.Lock()
+	defer gc.teams.mu.Unlock()
+
+	if !gc.teams.checked {
+		member, err := gc.isActiveTeamMember(ctx, org, slug)
+		if err != nil {
+			// Transient failures are retried on the next review
+			logWarn("Couldn't check membership of team %s/%s, submitting a user review: %v", org, slug, err)
+			return ""
+		}
+		gc.teams.checked, gc.teams.member = true, member
+		if !member {
+			logWarn("Bot is not an active member of team %s/%s, submitting user reviews instead", org, slug)
+		}
+	}
+
+	if !gc.teams.member {
+		return ""
+	}
+	return fmt.Sprintf("Reviewed on behalf of @%s/%s.", org, slug)
+}
+
+// isActiveTeamMember reports whether the bot is an active member of a team. Reading
+// team membership needs the read:org scope, or Members read access for GitHub Apps.
+func (gc *GitHubClient) isActiveTeamMember(ctx context.Context, org, slug string) (bool, error) {
+	login, err := gc.botLogin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	membership, response, err := gc.client.Teams.GetTeamMembershipBySlug(ctx, org, slug, login)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return membership.GetState() == "active", nil
+}
+
+// What to do with a PR that still has review requested from a team
+const (
+	// PendingTeamsProceed approves regardless of pending team review requests
+	PendingTeamsProceed = "proceed"
+	// PendingTeamsSkip denies the approval until the teams have reviewed
+	PendingTeamsSkip = "skip"
+	// PendingTeamsComment submits a comment review naming the pending teams instead of an approval
+	PendingTeamsComment = "comment"
+)
+
+// pendingReviewTeams lists the teams, as @org/slug, a PR still has a review requested from.
+// GitHub drops a team from the requested teams once one of its members reviews. The team
+// the bot reviews on behalf of is left out, as the bot's own review satisfies it.
+func (gc *GitHubClient) pendingReviewTeams(ctx context.Context, pr *github.PullRequest) []string {
+	org := pr.GetBase().GetRepo().GetOwner().GetLogin()
+
+	var teams []string
+	for _, team := range pr.RequestedTeams {
+		name := org + "/" + team.GetSlug()
+		if strings.EqualFold(name, strings.TrimPrefix(gc.config.ReviewTeam, "@")) && gc.reviewTeamNote(ctx) != "" {
+			continue
+		}
+		teams = append(teams, "@"+name)
+	}
+	return teams
+}
+
+// checkPendingTeamReviews fails while the PR has review requested from a team other than the bot's
+func (gc *GitHubClient) checkPendingTeamReviews(ctx context.Context, pr *github.PullRequest) error {
+	teams := gc.pendingReviewTeams(ctx, pr)
+	if len(teams) == 0 {
+		return nil
+	}
+	return &PolicyError{Policy: "team-reviews", Message: fmt.Sprintf("PR #%d still has review requested from %s", pr.GetNumber(), strings.Join(teams, ", "))}
+}
+
+// pendingTeamsNote returns the line explaining why a comment review was submitted instead
+// of an approval in PendingTeamsComment mode, or empty when the approval should go ahead
+func (gc *GitHubClient) pendingTeamsNote(ctx context.Context, req *ApprovalRequest) string {
+	if gc.config.PendingTeamReviews != PendingTeamsComment {
+		return ""
+	}
+	pr, err := gc.getPR(ctx, req.Owner, req.Repository, req.PRNumber)
+	if err != nil {
+		// The review itself will fail the same way if GitHub is unreachable
+		logWarn("Couldn't check pending team reviews of %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+		return ""
+	}
+	teams := gc.pendingReviewTeams(ctx, pr)
+	if len(teams) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Not approving yet: review is still requested from %s.", strings.Join(teams, ", "))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// teamMembership is the membership lookup of the bot in team o/platform
+const teamMembership = "GET /orgs/o/teams/platform/memberships/" + fakeBotLogin
+
+func TestParseReviewTeam(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantOrg  string
+		wantSlug string
+		wantErr  bool
+	}{
+		{value: "o/platform", wantOrg: "o", wantSlug: "platform"},
+		{value: "@o/platform", wantOrg: "o", wantSlug: "platform"},
+		{value: " o/platform ", wantOrg: "o", wantSlug: "platform"},
+		{value: "platform", wantErr: true},
+		{value: "o/", wantErr: true},
+		{value: "/platform", wantErr: true},
+		{value: "o/platform/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			org, slug, err := parseReviewTeam(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReviewTeam error = %v, want error %v", err, tt.wantErr)
+			}
+			if org != tt.wantOrg || slug != tt.wantSlug {
+				t.Errorf("parseReviewTeam = %q, %q, want %q, %q", org, slug, tt.wantOrg, tt.wantSlug)
+			}
+		})
+	}
+}
+
+func TestReviewOnBehalfOfTeam(t *testing.T) {
+	tests := []struct {
+		name       string
+		membership interface{}
+		failure    int
+		want       string
+	}{
+		{name: "active member", membership: map[string]string{"state": "active"}, want: "Reviewed on behalf of @o/platform."},
+		{name: "pending invitation", membership: map[string]string{"state": "pending"}},
+		{name: "not a member", failure: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{}, failures: map[string]int{}}
+			if tt.failure != 0 {
+				gh.failures[teamMembership] = tt.failure
+			} else {
+				gh.routes[teamMembership] = tt.membership
+			}
+			gc := newTestGitHubClient(t, &Configuration{ReviewTeam: "o/platform"}, gh.ServeHTTP)
+
+			for i := 0; i < 2; i++ {
+				if _, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1}); err != nil {
+					t.Fatalf("ApprovePR: %v", err)
+				}
+			}
+			for _, body := range gh.bodies() {
+				if body != tt.want {
+					t.Errorf("review body %q, want %q", body, tt.want)
+				}
+			}
+			lookups := 0
+			for _, request := range gh.requests {
+				if request == teamMembership {
+					lookups++
+				}
+			}
+			if lookups != 1 {
+				t.Errorf("looked up the team membership %d times, want once", lookups)
+			}
+		})
+	}
+}
+
+func TestReviewTeamLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{teamMembership: http.StatusForbidden}}
+	gc := newTestGitHubClient(t, &Configuration{ReviewTeam: "o/platform"}, gh.ServeHTTP)
+
+	for i := 0; i < 2; i++ {
+		result, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+		if err != nil || !result.Success {
+			t.Fatalf("ApprovePR = %+v, %v, want a user review", result, err)
+		}
+	}
+	if got := gh.bodies(); len(got) != 2 || got[0] != "" || got[1] != "" {
+		t.Errorf("review bodies %q, want user reviews without a team note", got)
+	}
+	if gc.teams.checked {
+		t.Error("a failed membership lookup was cached, want it retried on the next review")
+	}
+}
+
+// pendingTeamsPR is PR o/r#1 with review requested from the teams with slugs
+func pendingTeamsPR(slugs ...string) map[string]interface{} {
+	teams := make([]map[string]string, 0, len(slugs))
+	for _, slug := range slugs {
+		teams = append(teams, map[string]string{"slug": slug})
+	}
+	return map[string]interface{}{
+		"number":          1,
+		"state":           "open",
+		"head":            map[string]string{"sha": "abc123"},
+		"base":            map[string]interface{}{"ref": "main", "repo": map[string]interface{}{"name": "r", "full_name": "o/r", "owner": map[string]string{"login": "o"}}},
+		"requested_teams": teams,
+	}
+}
+
+func TestPendingTeamReviewsGate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		reviewTeam string
+		membership string
+		teams      []string
+		wantErr    string
+	}{
+		{name: "no pending teams", mode: PendingTeamsSkip},
+		{name: "pending teams", mode: PendingTeamsSkip, teams: []string{"security", "docs"}, wantErr: "PR #1 still has review requested from @o/security, @o/docs"},
+		{name: "own team", mode: PendingTeamsSkip, reviewTeam: "@o/platform", membership: "active", teams: []string{"platform"}},
+		{name: "own team without membership", mode: PendingTeamsSkip, reviewTeam: "o/platform", membership: "pending", teams: []string{"platform"}, wantErr: "PR #1 still has review requested from @o/platform"},
+		{name: "proceed", mode: PendingTeamsProceed, teams: []string{"security"}},
+		{name: "comment", mode: PendingTeamsComment, teams: []string{"security"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/pulls/1": pendingTeamsPR(tt.teams...),
+				teamMembership:           map[string]string{"state": tt.membership},
+			}}
+			err := validatePR(t, &Configuration{PendingTeamReviews: tt.mode, ReviewTeam: tt.reviewTeam}, gh)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePRReference: %v, want the PR to pass", err)
+				}
+				return
+			}
+			if failedPolicy(err) != "team-reviews" || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePRReference = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPendingTeamsComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		teams     []string
+		wantEvent string
+		wantBody  string
+	}{
+		{name: "pending teams", teams: []string{"security"}, wantEvent: "COMMENT", wantBody: "Not approving yet: review is still requested from @o/security.\n\nLooks good"},
+		{name: "no pending teams", wantEvent: "APPROVE", wantBody: "Looks good"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1": pendingTeamsPR(tt.teams...)}}
+			gc := newTestGitHubClient(t, &Configuration{PendingTeamReviews: PendingTeamsComment}, gh.ServeHTTP)
+
+			result, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, Message: "Looks good"})
+			if err != nil || !result.Success {
+				t.Fatalf("ApprovePR = %+v, %v, want a review", result, err)
+			}
+			if result.Event != tt.wantEvent {
+				t.Errorf("result event %s, want %s", result.Event, tt.wantEvent)
+			}
+			if got := gh.submitted(); len(got) != 1 || got[0] != tt.wantEvent {
+				t.Errorf("submitted %v, want one %s review", got, tt.wantEvent)
+			}
+			if got := gh.bodies(); len(got) != 1 || got[0] != tt.wantBody {
+				t.Errorf("review bodies %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPendingTeamsCommentLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/pulls/1": http.StatusBadGateway}}
+	gc := newTestGitHubClient(t, &Configuration{PendingTeamReviews: PendingTeamsComment}, gh.ServeHTTP)
+
+	if _, err := gc.ApprovePR(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1}); err != nil {
+		t.Fatalf("ApprovePR: %v", err)
+	}
+	if got := gh.submitted(); len(got) != 1 || got[0] != "APPROVE" {
+		t.Errorf("submitted %v, want the approval to go ahead", got)
+	}
+}
+
+func TestPendingTeamReviewsValidation(t *testing.T) {
+	for _, mode := range []string{PendingTeamsProceed, PendingTeamsSkip, PendingTeamsComment} {
+		if err := validateConfiguration(validConfig(t, "--pending-team-reviews", mode)); err != nil {
+			t.Errorf("validateConfiguration(%s) = %v, want no error", mode, err)
+		}
+	}
+	err := validateConfiguration(validConfig(t, "--pending-team-reviews", "wait"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "PendingTeamReviews" {
+		t.Errorf("validateConfiguration = %v, want a PendingTeamReviews error", err)
+	}
+}
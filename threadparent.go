@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// messageTextWithAttachments joins a message's text with the text of its attachments,
+// where apps forwarding GitHub notifications usually put the PR link
+func messageTextWithAttachments(message *slack.Message) string {
+	parts := []string{message.Text}
+	for _, attachment := range message.Attachments {
+		parts = append(parts, attachment.Pretext, attachment.Title, attachment.TitleLink, attachment.Text, attachment.Fallback)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// threadParentReferences returns the PR references of the message a reply is threaded
+// under, e.g. a forwarded GitHub notification answered with "lgtm". It returns nothing
+// for top-level messages, on lookup errors, or when the parent has no PR link.
+func (sc *SlackClient) threadParentReferences(ctx context.Context, msg *SlackMessage) []PRReference {
+	if msg.ThreadTS == "" || msg.ThreadTS == msg.Timestamp {
+		return nil
+	}
+
+	parent, err := sc.fetchMessage(ctx, msg.Channel, msg.ThreadTS)
+	if err != nil {
+		logWarn("Failed to fetch the thread parent %s in channel %s: %v", msg.ThreadTS, msg.Channel, err)
+		return nil
+	}
+
+	refs, err := sc.matcherFor(msg.Channel).ExtractPRReferences(sc.matchText(messageTextWithAttachments(parent)))
+	if err != nil || len(refs) == 0 {
+		logDebug("Thread parent %s in channel %s has no PR references", msg.ThreadTS, msg.Channel)
+		return nil
+	}
+	logDebug("Resolved %d PR reference(s) from thread parent %s in channel %s", len(refs), msg.ThreadTS, msg.Channel)
+	return refs
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// threadReply is "lgtm" answered in the thread of the message at 1700000000.000100
+func threadReply() *SlackMessage {
+	msg := testMessage("lgtm")
+	msg.Timestamp, msg.ThreadTS = "1700000005.000100", "1700000000.000100"
+	return msg
+}
+
+func TestMessageTextWithAttachments(t *testing.T) {
+	message := &slack.Message{Msg: slack.Msg{
+		Text: "New pull request",
+		Attachments: []slack.Attachment{{
+			Title:     "Add a button",
+			TitleLink: "https://github.com/o/r/pull/1",
+		}},
+	}}
+	refs, err := (&IntentParser{}).ExtractPRReferences(messageTextWithAttachments(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0].Number != 1 {
+		t.Errorf("references %+v, want the attachment's PR", refs)
+	}
+}
+
+func TestReplyResolvesThreadParent(t *testing.T) {
+	tests := []struct {
+		name         string
+		resolve      bool
+		parent       map[string]interface{}
+		wantReviews  int
+		wantReaction string
+	}{
+		{
+			name:        "parent in an attachment",
+			resolve:     true,
+			parent:      map[string]interface{}{"ts": "1700000000.000100", "text": "", "attachments": []map[string]string{{"title_link": "https://github.com/o/r/pull/1"}}},
+			wantReviews: 1,
+		},
+		{
+			name:         "parent without a PR link",
+			resolve:      true,
+			parent:       map[string]interface{}{"ts": "1700000000.000100", "text": "deploy went out"},
+			wantReaction: "x",
+		},
+		{
+			name:         "disabled",
+			parent:       map[string]interface{}{"ts": "1700000000.000100", "text": "https://github.com/o/r/pull/1"},
+			wantReaction: "x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{}
+			sc, reactions := newTestSlackClient(t, &Configuration{ResolveThreadParent: tt.resolve}, gh)
+			serveSlackMessage(t, sc, tt.parent)
+
+			sc.processMessage(context.Background(), threadReply())
+			waitForApprovals(t, sc)
+
+			if reviews := gh.submitted(); len(reviews) != tt.wantReviews {
+				t.Errorf("submitted %v, want %d review(s)", reviews, tt.wantReviews)
+			}
+			if tt.wantReaction != "" && !reactions.has(tt.wantReaction) {
+				t.Errorf("reactions %v, want %s", reactions.added, tt.wantReaction)
+			}
+		})
+	}
+}
+
+func TestThreadParentLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{}
+	sc, reactions := newTestSlackClient(t, &Configuration{ResolveThreadParent: true}, gh)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": "missing_scope"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	sc.processMessage(context.Background(), threadReply())
+	waitForApprovals(t, sc)
+
+	if reviews := gh.submitted(); len(reviews) != 0 {
+		t.Errorf("submitted %v, want nothing when the parent can't be read", reviews)
+	}
+	if !reactions.has("x") {
+		t.Errorf("reactions %v, want x", reactions.added)
+	}
+}
+
+func TestTopLevelMessageSkipsThreadParent(t *testing.T) {
+	sc, _ := newTestSlackClient(t, &Configuration{ResolveThreadParent: true}, &fakeGitHub{})
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	msg := testMessage("lgtm")
+	msg.ThreadTS = msg.Timestamp
+	if refs := sc.threadParentReferences(context.Background(), msg); refs != nil || lookups != 0 {
+		t.Errorf("threadParentReferences = %+v after %d lookup(s), want nothing looked up for a thread's own parent", refs, lookups)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reviewThreadsQuery lists a PR's review threads a page at a time
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $cursor) {
+        nodes { id isResolved }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+// resolveReviewThreadMutation marks one review thread as resolved
+const resolveReviewThreadMutation = `mutation($id: ID!) {
+  resolveReviewThread(input: {threadId: $id}) { thread { id } }
+}`
+
+// graphqlURL returns the GraphQL endpoint matching the REST base URL,
+// e.g. https://api.github.com/graphql or https://ghe.example.com/api/graphql
+func (gc *GitHubClient) graphqlURL() string {
+	base := gc.client.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
+
+// graphql runs a GraphQL request with the REST client's credentials and decodes its data into out
+func (gc *GitHubClient) graphql(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gc.graphqlURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", gc.client.UserAgent)
+
+	resp, err := gc.client.Client().Do(req)
+	if err != nil {
+		return fmt.Errorf("GraphQL request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GraphQL request failed with status %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response: %v", err)
+	}
+	if len(envelope.Errors) > 0 {
+		messages := make([]string, len(envelope.Errors))
+		for i, graphqlErr := range envelope.Errors {
+			messages[i] = graphqlErr.Message
+		}
+		return fmt.Errorf("GraphQL error: %s", strings.Join(messages, "; "))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// ResolveReviewThreads resolves every open review thread on a PR and returns how many were resolved.
+// Threads resolved before a failure stay resolved.
+func (gc *GitHubClient) ResolveReviewThreads(ctx context.Context, owner, repo string, prNumber int) (int, error) {
+	var open []string
+	var cursor *string
+
+	for {
+		var page struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		err := gc.graphql(ctx, reviewThreadsQuery, map[string]interface{}{
+			"owner":  owner,
+			"repo":   repo,
+			"number": prNumber,
+			"cursor": cursor,
+		}, &page)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list review threads on PR #%d: %v", prNumber, err)
+		}
+
+		threads := page.Repository.PullRequest.ReviewThreads
+		for _, thread := range threads.Nodes {
+			if !thread.IsResolved {
+				open = append(open, thread.ID)
+			}
+		}
+		if !threads.PageInfo.HasNextPage {
+			break
+		}
+		endCursor := threads.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+
+	resolved := 0
+	for _, id := range open {
+		if err := gc.graphql(ctx, resolveReviewThreadMutation, map[string]interface{}{"id": id}, nil); err != nil {
+			return resolved, fmt.Errorf("failed to resolve review thread %s on PR #%d: %v", id, prNumber, err)
+		}
+		resolved++
+	}
+	return resolved, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGraphQL serves review thread pages and resolve mutations, passing other requests
+// on to the REST fake
+type fakeGraphQL struct {
+	mu sync.Mutex
+	// pages lists the thread IDs of each page, with resolved IDs prefixed by "!"
+	pages [][]string
+	// listError is the GraphQL error of every thread listing
+	listError string
+	// status, when set, is the status of every GraphQL request
+	status int
+	// failResolve is the thread ID whose resolve mutation fails
+	failResolve string
+	resolved    []string
+	rest        *fakeGitHub
+}
+
+// ServeHTTP answers POST /graphql and hands everything else to the REST fake
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/graphql" {
+		f.rest.ServeHTTP(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		writeJSON(w, f.status, map[string]string{"message": http.StatusText(f.status)})
+		return
+	}
+	var body struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if strings.Contains(body.Query, "resolveReviewThread") {
+		id, _ := body.Variables["id"].(string)
+		if id == f.failResolve {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"errors": []map[string]string{{"message": "Resource not accessible by integration"}}})
+			return
+		}
+		f.resolved = append(f.resolved, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{}})
+		return
+	}
+
+	if f.listError != "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"errors": []map[string]string{{"message": f.listError}}})
+		return
+	}
+	page := 0
+	if cursor, ok := body.Variables["cursor"].(string); ok {
+		page = len(cursor)
+	}
+	nodes := []map[string]interface{}{}
+	if page < len(f.pages) {
+		for _, id := range f.pages[page] {
+			nodes = append(nodes, map[string]interface{}{"id": strings.TrimPrefix(id, "!"), "isResolved": strings.HasPrefix(id, "!")})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+		"repository": map[string]interface{}{"pullRequest": map[string]interface{}{"reviewThreads": map[string]interface{}{
+			"nodes": nodes,
+			// Each page's cursor is as long as the number of the next page
+			"pageInfo": map[string]interface{}{"hasNextPage": page+1 < len(f.pages), "endCursor": strings.Repeat("c", page+1)},
+		}}},
+	}})
+}
+
+// resolvedThreads returns the IDs of the threads resolved
+func (f *fakeGraphQL) resolvedThreads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.resolved...)
+}
+
+func TestResolveReviewThreads(t *testing.T) {
+	tests := []struct {
+		name         string
+		graphql      *fakeGraphQL
+		wantResolved []string
+		wantErr      bool
+	}{
+		{name: "open threads across pages", graphql: &fakeGraphQL{pages: [][]string{{"T1", "!T2"}, {"T3"}}}, wantResolved: []string{"T1", "T3"}},
+		{name: "no threads", graphql: &fakeGraphQL{}},
+		{name: "all resolved already", graphql: &fakeGraphQL{pages: [][]string{{"!T1"}}}},
+		{name: "listing error", graphql: &fakeGraphQL{listError: "Could not resolve to a PullRequest"}, wantErr: true},
+		{name: "listing status", graphql: &fakeGraphQL{status: http.StatusBadGateway}, wantErr: true},
+		{name: "resolve error keeps earlier threads", graphql: &fakeGraphQL{pages: [][]string{{"T1", "T2", "T3"}}, failResolve: "T2"}, wantResolved: []string{"T1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.graphql.rest = &fakeGitHub{}
+			gc := newTestGitHubClient(t, &Configuration{}, tt.graphql.ServeHTTP)
+
+			resolved, err := gc.ResolveReviewThreads(context.Background(), "o", "r", 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveReviewThreads error = %v, want error %v", err, tt.wantErr)
+			}
+			if resolved != len(tt.wantResolved) {
+				t.Errorf("ResolveReviewThreads = %d, want %d", resolved, len(tt.wantResolved))
+			}
+			if got := tt.graphql.resolvedThreads(); !reflect.DeepEqual(got, tt.wantResolved) {
+				t.Errorf("resolved threads %v, want %v", got, tt.wantResolved)
+			}
+		})
+	}
+}
+
+func TestResolveThreadsFailureKeepsApproval(t *testing.T) {
+	gh := &fakeGitHub{}
+	graphql := &fakeGraphQL{status: http.StatusInternalServerError, rest: gh}
+	config := &Configuration{ResolveThreadsOnApprove: true}
+	gc := newTestGitHubClient(t, config, graphql.ServeHTTP)
+	sc, recorder := newTestSlackClient(t, config, gh)
+	sc.githubClient = gc
+
+	sc.processMessage(context.Background(), testMessage("lgtm https://github.com/o/r/pull/1"))
+	waitForApprovals(t, sc)
+
+	if got := gh.submitted(); !reflect.DeepEqual(got, []string{"APPROVE"}) {
+		t.Errorf("submitted reviews %v, want one approval", got)
+	}
+	if !recorder.has("white_check_mark") {
+		t.Errorf("reactions %v, want the approval reaction", recorder.added)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{base: "https://api.github.com/", want: "https://api.github.com/graphql"},
+		{base: "https://ghe.example.com/api/v3/", want: "https://ghe.example.com/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base, func(t *testing.T) {
+			gc := newTestGitHubClient(t, &Configuration{}, (&fakeGitHub{}).ServeHTTP)
+			base, err := url.Parse(tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gc.client.BaseURL = base
+			if got := gc.graphqlURL(); got != tt.want {
+				t.Errorf("graphqlURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// throttleStateKey is the store key holding the approval throttle's bucket
+const throttleStateKey = "approvals"
+
+// throttleState is a throttle's bucket as saved in the store
+type throttleState struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+}
+
+// approvalThrottle is a token bucket capping approvals per minute across all channels,
+// which protects the GitHub token's overall budget. A nil throttle is unlimited.
+type approvalThrottle struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	interval time.Duration
+	last     time.Time
+	// gauge is the metric reporting the available tokens, empty for none
+	gauge string
+	// store keeps the bucket across restarts, nil for a throttle that starts full
+	store Store
+}
+
+// newApprovalThrottle creates a throttle allowing perMinute approvals, restoring the
+// bucket saved in the store; zero disables it
+func newApprovalThrottle(perMinute int, store Store) *approvalThrottle {
+	at := newThrottle(perMinute, metricThrottleTokens)
+	if at == nil {
+		return nil
+	}
+	at.store = store
+	at.load()
+	return at
+}
+
+// newThrottle creates a token bucket allowing perMinute takes, reporting its tokens
+// in gauge when one is given; zero disables it
+func newThrottle(perMinute int, gauge string) *approvalThrottle {
+	if perMinute <= 0 {
+		return nil
+	}
+	at := &approvalThrottle{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		interval: time.Minute / time.Duration(perMinute),
+		last:     time.Now(),
+		gauge:    gauge,
+	}
+	at.report()
+	return at
+}
+
+// load restores the bucket saved by an earlier run, or by a state import
+func (at *approvalThrottle) load() {
+	value, ok, err := at.store.Get(context.Background(), storeNamespaceThrottle, throttleStateKey)
+	if err != nil {
+		logWarn("Failed to load the approval throttle: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	var state throttleState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		logWarn("Ignoring the saved approval throttle: %v", err)
+		return
+	}
+	now := time.Now()
+	at.tokens, at.last = min(state.Tokens, at.capacity), state.Last
+	if at.last.After(now) {
+		at.last = now
+	}
+	at.refill(now)
+}
+
+// save stores the bucket; callers hold the lock. It expires once the bucket would
+// be full again, when it says no more than a new bucket does.
+func (at *approvalThrottle) save() {
+	if at.store == nil {
+		return
+	}
+	data, err := json.Marshal(throttleState{Tokens: at.tokens, Last: at.last})
+	if err != nil {
+		return
+	}
+	refilled := time.Duration((at.capacity-at.tokens)*float64(at.interval)) + time.Second
+	if err := at.store.Put(context.Background(), storeNamespaceThrottle, throttleStateKey, string(data), refilled); err != nil {
+		logWarn("Failed to save the approval throttle: %v", err)
+	}
+}
+
+// report updates the token gauge; callers hold the lock
+func (at *approvalThrottle) report() {
+	if at.gauge != "" {
+		metrics.SetGauge(at.gauge, at.tokens)
+	}
+}
+
+// refill adds the tokens earned since the last call; callers hold the lock
+func (at *approvalThrottle) refill(now time.Time) {
+	at.tokens += float64(now.Sub(at.last)) / float64(at.interval)
+	if at.tokens > at.capacity {
+		at.tokens = at.capacity
+	}
+	at.last = now
+	at.report()
+}
+
+// take uses a token if one is available, otherwise it reports how long until one is
+func (at *approvalThrottle) take() (bool, time.Duration) {
+	if at == nil {
+		return true, 0
+	}
+
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.refill(time.Now())
+	if at.tokens >= 1 {
+		at.tokens--
+		at.report()
+		at.save()
+		return true, 0
+	}
+	return false, time.Duration((1 - at.tokens) * float64(at.interval))
+}
+
+// wait blocks until a token is available or ctx is canceled
+func (at *approvalThrottle) wait(ctx context.Context) error {
+	for {
+		ok, delay := at.take()
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
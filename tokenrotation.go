@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// tokenRefreshMargin is how long before expiry the bot token is refreshed
+const tokenRefreshMargin = 5 * time.Minute
+
+// tokenRotationEnabled reports whether Slack token rotation credentials are configured
+func (config *Configuration) tokenRotationEnabled() bool {
+	return config.SlackRefreshToken != "" && config.SlackClientID != "" && config.SlackClientSecret != ""
+}
+
+// slackAPI returns the current Slack API client, which may be replaced by token rotation
+func (sc *SlackClient) slackAPI() *slack.Client {
+	sc.apiMu.RLock()
+	defer sc.apiMu.RUnlock()
+	return sc.api
+}
+
+// refreshBotToken exchanges the refresh token for a new bot token and swaps in a new API client.
+// It returns how long the new token is valid for.
+func (sc *SlackClient) refreshBotToken(ctx context.Context) (time.Duration, error) {
+	response, err := slack.RefreshOAuthV2TokenContext(ctx, newHTTPClient(sc.config), sc.config.SlackClientID, sc.config.SlackClientSecret, sc.refreshToken)
+	if err != nil {
+		return 0, &AuthenticationError{Service: "Slack", Message: fmt.Sprintf("token refresh failed: %v", err)}
+	}
+
+	sc.apiMu.Lock()
+	sc.api = newSlackAPI(response.AccessToken, sc.config)
+	if response.RefreshToken != "" {
+		sc.refreshToken = response.RefreshToken
+	}
+	sc.apiMu.Unlock()
+
+	expiresIn := time.Duration(response.ExpiresIn) * time.Second
+	logInfo("Slack bot token rotated, expires in %v", expiresIn)
+	return expiresIn, nil
+}
+
+// rotateTokens refreshes the bot token shortly before each expiry until ctx is canceled.
+// Failed refreshes are retried after a minute while the current token is still valid.
+func (sc *SlackClient) rotateTokens(ctx context.Context, expiresIn time.Duration) {
+	for {
+		wait := expiresIn - tokenRefreshMargin
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+
+		next, err := sc.refreshBotToken(ctx)
+		if err != nil {
+			logError("Slack token rotation failed, retrying: %v", err)
+			expiresIn = tokenRefreshMargin + time.Minute
+			continue
+		}
+		expiresIn = next
+	}
+}
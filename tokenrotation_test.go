@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// rotationArgs configures Slack token rotation
+var rotationArgs = []string{"--slack-refresh-token", "xoxe-1-refresh", "--slack-client-id", "123.456", "--slack-client-secret", "secret"}
+
+func TestTokenRotationMakesBotTokenOptional(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantField string
+	}{
+		{name: "rotation without a bot token", args: append([]string{"--slack-app-token", "xapp-test"}, rotationArgs...)},
+		{name: "rotation with a bot token", args: append([]string{"--slack-bot-token", "xoxe.xoxb-1-test", "--slack-app-token", "xapp-test"}, rotationArgs...)},
+		{name: "no bot token", args: []string{"--slack-app-token", "xapp-test"}, wantField: "SlackBotToken"},
+		{name: "refresh token without client credentials", args: []string{"--slack-app-token", "xapp-test", "--slack-refresh-token", "xoxe-1-refresh"}, wantField: "SlackRefreshToken"},
+		{name: "rotation with an invalid bot token", args: append([]string{"--slack-bot-token", "xoxp-test", "--slack-app-token", "xapp-test"}, rotationArgs...), wantField: "SlackBotToken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfiguration(parseRunFlags(t, append([]string{"--github-token", "ghp_test"}, tt.args...)...))
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("validateConfiguration = %v, want no error", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != tt.wantField {
+				t.Errorf("validateConfiguration = %v, want a %s error", err, tt.wantField)
+			}
+		})
+	}
+}
+
+// parseValidateFlags parses the configuration of `lgtm validate` with args
+func parseValidateFlags(t *testing.T, args ...string) *Configuration {
+	t.Helper()
+	app := newApp()
+	var config *Configuration
+	for _, command := range app.Commands {
+		if command.Name == "validate" {
+			command.Action = func(c *cli.Context) error {
+				var err error
+				config, err = parseConfig(c)
+				return err
+			}
+		}
+	}
+	if err := app.Run(append([]string{"lgtm", "validate"}, args...)); err != nil {
+		t.Fatalf("lgtm validate %v: %v", args, err)
+	}
+	return config
+}
+
+func TestValidateWithTokenRotation(t *testing.T) {
+	config := parseValidateFlags(t, append([]string{"--github-token", "ghp_test", "--slack-app-token", "xapp-test"}, rotationArgs...)...)
+	if !config.tokenRotationEnabled() {
+		t.Fatalf("validate parsed %+v, want token rotation enabled", config)
+	}
+
+	checks := validationChecks(context.Background(), config, true)
+	results := make(map[string]bool)
+	for _, result := range checkResults(checks) {
+		results[result] = true
+	}
+	for _, want := range []string{"Slack bot token is set: skipped", "Configuration values: ok"} {
+		if !results[want] {
+			t.Errorf("checks %v, want %q", checkResults(checks), want)
+		}
+	}
+	if failed := writeValidationChecks(io.Discard, checks); failed != 0 {
+		t.Errorf("%d check(s) failed, want none with token rotation in place of a bot token", failed)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// topicDirectivePattern matches lgtm:key=value directives in a channel topic or purpose
+var topicDirectivePattern = regexp.MustCompile(`(?i)(?:^|\s)lgtm:([a-z]+)=(\S*)`)
+
+// channelDirectives holds the standing instructions read from each channel's topic
+type channelDirectives struct {
+	mu      sync.RWMutex
+	targets map[string]RepoTarget
+}
+
+// newChannelDirectives creates an empty directive set
+func newChannelDirectives() *channelDirectives {
+	return &channelDirectives{targets: make(map[string]RepoTarget)}
+}
+
+// target returns the default owner and repo set by a channel's directives
+func (cd *channelDirectives) target(channel string) RepoTarget {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.targets[channel]
+}
+
+// set replaces a channel's directives; an empty target clears them
+func (cd *channelDirectives) set(channel string, target RepoTarget) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if target == (RepoTarget{}) {
+		delete(cd.targets, channel)
+		return
+	}
+	cd.targets[channel] = target
+}
+
+// parseTopicDirectives reads lgtm:repo=owner/repo and lgtm:owner=org directives.
+// Unknown or malformed directives are ignored with a warning.
+func parseTopicDirectives(channel, text string) RepoTarget {
+	var target RepoTarget
+	for _, match := range topicDirectivePattern.FindAllStringSubmatch(text, -1) {
+		key, value := strings.ToLower(match[1]), strings.Trim(match[2], ".,;")
+
+		switch key {
+		case "repo":
+			repoTarget, err := parseRepoTarget(value)
+			if err != nil {
+				logWarn("Ignoring directive lgtm:repo=%s in channel %s: %v", value, channel, err)
+				continue
+			}
+			target = repoTarget
+		case "owner":
+			if !githubLoginPattern.MatchString(value) {
+				logWarn("Ignoring directive lgtm:owner=%s in channel %s: not a valid GitHub owner", value, channel)
+				continue
+			}
+			if target.Repo == "" {
+				target.Owner = value
+			}
+		default:
+			logWarn("Ignoring unknown directive lgtm:%s in channel %s", key, channel)
+		}
+	}
+	return target
+}
+
+// applyChannelDirectives parses a channel's topic and purpose and records the result
+func (sc *SlackClient) applyChannelDirectives(channel slack.Channel) {
+	target := parseTopicDirectives(channel.ID, channel.Topic.Value+"\n"+channel.Purpose.Value)
+	if target != sc.directives.target(channel.ID) {
+		logInfo("Channel %s directives: owner=%q repo=%q", channel.ID, target.Owner, target.Repo)
+	}
+	sc.directives.set(channel.ID, target)
+}
+
+// loadChannelDirectives re-reads one channel's topic, e.g. after the bot joins or the topic changes
+func (sc *SlackClient) loadChannelDirectives(ctx context.Context, channelID string) {
+	channel, err := sc.slackAPI().GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		logWarn("Failed to read topic of channel %s: %v", channelID, err)
+		return
+	}
+	sc.applyChannelDirectives(*channel)
+}
+
+// scanChannelDirectives reads the directives of every channel the bot is a member of
+func (sc *SlackClient) scanChannelDirectives(ctx context.Context) {
+	params := &slack.GetConversationsForUserParameters{
+		Types:           []string{"public_channel", "private_channel"},
+		ExcludeArchived: true,
+		Limit:           200,
+	}
+
+	for {
+		channels, cursor, err := sc.slackAPI().GetConversationsForUserContext(ctx, params)
+		if err != nil {
+			logWarn("Failed to list channels for topic directives: %v", err)
+			return
+		}
+		for _, channel := range channels {
+			sc.applyChannelDirectives(channel)
+		}
+		if cursor == "" {
+			return
+		}
+		params.Cursor = cursor
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultUserNameCacheTTL is how long resolved names are kept when no TTL is configured
+const defaultUserNameCacheTTL = time.Hour
+
+// userNameEntry is a cached display name
+type userNameEntry struct {
+	name    string
+	expires time.Time
+}
+
+// userNames resolves Slack user IDs to display names for logs, caching each lookup
+type userNames struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]userNameEntry
+	fetch   func(ctx context.Context, userID string) (*slack.User, error)
+}
+
+// newUserNames creates a resolver; it returns nil when resolution is disabled
+func newUserNames(enabled bool, ttl time.Duration, fetch func(ctx context.Context, userID string) (*slack.User, error)) *userNames {
+	if !enabled {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = defaultUserNameCacheTTL
+	}
+	return &userNames{
+		ttl:     ttl,
+		entries: make(map[string]userNameEntry),
+		fetch:   fetch,
+	}
+}
+
+// label returns "Name (U123)" for a user, or just the ID when resolution is disabled or
+// fails. Unknown and deactivated users are cached like any other, so they cost one lookup per TTL.
+func (un *userNames) label(ctx context.Context, userID string) string {
+	if un == nil || userID == "" {
+		return userID
+	}
+
+	un.mu.Lock()
+	entry, ok := un.entries[userID]
+	un.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return fmt.Sprintf("%s (%s)", entry.name, userID)
+	}
+
+	user, err := un.fetch(ctx, userID)
+	var name string
+	switch {
+	case err != nil && err.Error() == "user_not_found":
+		name = "unknown user"
+	case err != nil:
+		logDebug("Failed to resolve Slack user %s: %v", userID, err)
+		return userID
+	default:
+		name = userDisplayName(user)
+	}
+
+	un.mu.Lock()
+	un.entries[userID] = userNameEntry{name: name, expires: time.Now().Add(un.ttl)}
+	un.mu.Unlock()
+	return fmt.Sprintf("%s (%s)", name, userID)
+}
+
+// userDisplayName picks the name people see in Slack, marking deactivated accounts
+func userDisplayName(user *slack.User) string {
+	name := user.Profile.DisplayName
+	if name == "" {
+		name = user.RealName
+	}
+	if name == "" {
+		name = user.Name
+	}
+	if user.Deleted {
+		name += " (deactivated)"
+	}
+	return name
+}
+
+// userLabel names a Slack user for log lines and replies
+func (sc *SlackClient) userLabel(ctx context.Context, userID string) string {
+	return sc.userNames.label(ctx, userID)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// countingFetch answers user lookups from users, failing for the others with err
+type countingFetch struct {
+	users   map[string]*slack.User
+	err     error
+	lookups int
+}
+
+// fetch answers one lookup
+func (cf *countingFetch) fetch(ctx context.Context, userID string) (*slack.User, error) {
+	cf.lookups++
+	if user, ok := cf.users[userID]; ok {
+		return user, nil
+	}
+	return nil, cf.err
+}
+
+func TestUserNameLabel(t *testing.T) {
+	alice := &slack.User{ID: "U1", Name: "alice", RealName: "Alice Smith"}
+	alice.Profile.DisplayName = "alice.s"
+	bob := &slack.User{ID: "U2", Name: "bob", RealName: "Bob Jones", Deleted: true}
+	carol := &slack.User{ID: "U3", Name: "carol"}
+
+	tests := []struct {
+		name   string
+		userID string
+		err    error
+		want   string
+	}{
+		{name: "display name", userID: "U1", want: "alice.s (U1)"},
+		{name: "deactivated, by real name", userID: "U2", want: "Bob Jones (deactivated) (U2)"},
+		{name: "user name", userID: "U3", want: "carol (U3)"},
+		{name: "unknown user", userID: "U4", err: errors.New("user_not_found"), want: "unknown user (U4)"},
+		{name: "lookup failure", userID: "U4", err: errors.New("ratelimited"), want: "U4"},
+		{name: "no user", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch := &countingFetch{users: map[string]*slack.User{"U1": alice, "U2": bob, "U3": carol}, err: tt.err}
+			names := newUserNames(true, time.Hour, fetch.fetch)
+			if got := names.label(context.Background(), tt.userID); got != tt.want {
+				t.Errorf("label(%q) = %q, want %q", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserNamesAreCached(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		ttl         time.Duration
+		wantLookups int
+	}{
+		{name: "resolved", wantLookups: 1, ttl: time.Hour},
+		{name: "unknown user", err: errors.New("user_not_found"), ttl: time.Hour, wantLookups: 1},
+		{name: "failed lookups are retried", err: errors.New("ratelimited"), ttl: time.Hour, wantLookups: 3},
+		{name: "expired", ttl: time.Nanosecond, wantLookups: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch := &countingFetch{err: tt.err}
+			if tt.err == nil {
+				fetch.users = map[string]*slack.User{"U1": {ID: "U1", Name: "alice"}}
+			}
+			names := newUserNames(true, tt.ttl, fetch.fetch)
+			for i := 0; i < 3; i++ {
+				names.label(context.Background(), "U1")
+				time.Sleep(time.Microsecond)
+			}
+			if fetch.lookups != tt.wantLookups {
+				t.Errorf("looked up the user %d times, want %d", fetch.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestUserNamesDisabled(t *testing.T) {
+	names := newUserNames(false, 0, func(ctx context.Context, userID string) (*slack.User, error) {
+		t.Fatal("looked up a user with resolution disabled")
+		return nil, nil
+	})
+	if got := names.label(context.Background(), "U1"); got != "U1" {
+		t.Errorf("label = %q, want the bare ID", got)
+	}
+}
+
+func TestUserLabelLooksUpUsersInfo(t *testing.T) {
+	sc, _ := newTestSlackClient(t, &Configuration{ResolveUserNames: true}, &fakeGitHub{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users.info" || r.FormValue("user") != "U1" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": "user_not_found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "user": map[string]interface{}{"id": "U1", "name": "alice", "profile": map[string]string{"display_name": "alice.s"}}})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	if got := sc.userLabel(context.Background(), "U1"); got != "alice.s (U1)" {
+		t.Errorf("userLabel(U1) = %q, want alice.s (U1)", got)
+	}
+	if got := sc.userLabel(context.Background(), "U9"); got != "unknown user (U9)" {
+		t.Errorf("userLabel(U9) = %q, want unknown user (U9)", got)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Actions when RequireMappedUser is on and the triggering Slack user has no GitHub mapping
+const (
+	UnmappedUserSkip  = "skip"
+	UnmappedUserReact = "react"
+	UnmappedUserReply = "reply"
+)
+
+// githubLoginPattern matches a valid GitHub login
+var githubLoginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+
+// mapMeCommandPattern matches the self-service "map me as <github-login>" DM command
+var mapMeCommandPattern = regexp.MustCompile(`(?i)^\s*map\s+me\s+as\s+@?(\S+)\s*$`)
+
+// mappingReviewPattern matches an admin's "lgtm approve mapping <@U123>" and
+// "lgtm deny mapping <@U123>" DM commands
+var mappingReviewPattern = regexp.MustCompile(`(?i)^\s*lgtm\s+(approve|deny)\s+mapping\s+<@([A-Z0-9]+)(?:\|[^>]*)?>\s*$`)
+
+// userDirectory maps Slack user IDs to GitHub logins
+type userDirectory struct {
+	mu       sync.RWMutex
+	mappings map[string]string
+	// configured holds the mappings an admin configured, as opposed to self-service ones
+	configured map[string]string
+	// requested holds the self-service mappings waiting for an admin, in memory only
+	requested map[string]string
+	store     Store
+}
+
+// newUserDirectory creates a directory seeded with the self-service mappings saved in
+// the store, then the configured mappings, which take precedence
+func newUserDirectory(mappings map[string]string, store Store) *userDirectory {
+	ud := &userDirectory{mappings: make(map[string]string), configured: mappings, requested: make(map[string]string), store: store}
+
+	saved, err := store.List(context.Background(), storeNamespaceUserMappings)
+	if err != nil {
+		logWarn("Failed to load saved user mappings: %v", err)
+	}
+	for slackUser, login := range saved {
+		ud.mappings[slackUser] = login
+	}
+
+	for slackUser, login := range mappings {
+		ud.mappings[slackUser] = login
+	}
+	return ud
+}
+
+// lookup returns the GitHub login mapped to a Slack user
+func (ud *userDirectory) lookup(slackUser string) (string, bool) {
+	ud.mu.RLock()
+	defer ud.mu.RUnlock()
+	login, ok := ud.mappings[slackUser]
+	return login, ok
+}
+
+// lookupConfigured returns the GitHub login an admin configured for a Slack user,
+// ignoring self-service mappings, which anyone can point at any login
+func (ud *userDirectory) lookupConfigured(slackUser string) (string, bool) {
+	ud.mu.RLock()
+	defer ud.mu.RUnlock()
+	login, ok := ud.configured[slackUser]
+	return login, ok
+}
+
+// set maps a Slack user to a GitHub login and saves the mapping in the store. A
+// configured mapping is never overwritten; set reports false for those users.
+func (ud *userDirectory) set(slackUser, login string) bool {
+	ud.mu.Lock()
+	defer ud.mu.Unlock()
+	if _, configured := ud.configured[slackUser]; configured {
+		return false
+	}
+	ud.mappings[slackUser] = login
+
+	if err := ud.store.Put(context.Background(), storeNamespaceUserMappings, slackUser, login, 0); err != nil {
+		logWarn("Failed to save mapping for Slack user %s: %v", slackUser, err)
+	}
+	return true
+}
+
+// request records a self-service mapping for an admin to approve, replacing the user's
+// earlier request
+func (ud *userDirectory) request(slackUser, login string) {
+	ud.mu.Lock()
+	defer ud.mu.Unlock()
+	ud.requested[slackUser] = login
+}
+
+// takeRequest returns and forgets a user's pending self-service mapping
+func (ud *userDirectory) takeRequest(slackUser string) (string, bool) {
+	ud.mu.Lock()
+	defer ud.mu.Unlock()
+	login, ok := ud.requested[slackUser]
+	delete(ud.requested, slackUser)
+	return login, ok
+}
+
+// parseUserMappings parses slackUserID=githubLogin pairs
+func parseUserMappings(values []string) (map[string]string, error) {
+	pairs, err := parseKeyValues(values, "UserMappings", "mapping", "slackUserID=githubLogin")
+	if err != nil {
+		return nil, err
+	}
+	mappings := make(map[string]string)
+	for _, pair := range pairs {
+		mappings[pair.key] = strings.TrimPrefix(strings.TrimSpace(pair.value), "@")
+	}
+	return mappings, nil
+}
+
+// handleUnmappedUser applies the configured fallback for a trigger from a user with no GitHub mapping
+func (sc *SlackClient) handleUnmappedUser(msg *SlackMessage) {
+	logInfo("Skipping approval in channel %s: Slack user %s has no GitHub mapping", msg.Channel, msg.User)
+
+	switch sc.config.UnmappedUserAction {
+	case UnmappedUserSkip:
+	case UnmappedUserReply:
+		text := fmt.Sprintf("<@%s> I don't know your GitHub login, so I can't approve on your behalf. Ask an admin to add a mapping for you.", msg.User)
+		if sc.config.EnableSelfServiceMapping {
+			text = fmt.Sprintf("<@%s> I don't know your GitHub login, so I can't approve on your behalf. Send me a DM saying `map me as <github-login>` and try again.", msg.User)
+		}
+
+		if err := sc.replyInThread(msg.Channel, msg.Timestamp, msg.ThreadTS, text); err != nil {
+			logWarn("Failed to reply to unmapped user %s: %v", msg.User, err)
+		}
+	default:
+		sc.react(msg.Channel, msg.Timestamp, outcomeDenied)
+	}
+}
+
+// handleMappingCommand handles the "map me as <github-login>" DM command. The mapping
+// names the user in review bodies, so it only takes effect once an admin approves it,
+// and never replaces a configured mapping.
+func (sc *SlackClient) handleMappingCommand(event *slackevents.MessageEvent) {
+	if event.SubType != "" || event.BotID != "" || sc.isSelf(event.User, event.BotID) {
+		return
+	}
+
+	match := mapMeCommandPattern.FindStringSubmatch(event.Text)
+	if match == nil {
+		sc.replyInChannel(event.Channel, "Send `map me as <github-login>` to link your Slack account to your GitHub login.")
+		return
+	}
+
+	login := match[1]
+	if !githubLoginPattern.MatchString(login) {
+		sc.replyInChannel(event.Channel, fmt.Sprintf("%q isn't a valid GitHub login.", login))
+		return
+	}
+
+	if configured, ok := sc.users.lookupConfigured(event.User); ok {
+		logWarn("Slack user %s asked to be mapped to GitHub login %s, but is configured as %s", event.User, login, configured)
+		sc.replyInChannel(event.Channel, fmt.Sprintf("You're mapped to @%s by the bot's configuration. Ask an admin to change it.", configured))
+		return
+	}
+
+	sc.users.request(event.User, login)
+	logInfo("Slack user %s asked to be mapped to GitHub login %s", event.User, login)
+	for _, admin := range sc.config.AdminSlackUsers {
+		sc.replyInChannel(admin, fmt.Sprintf("<@%s> asks to be mapped to GitHub login @%s. Reply `lgtm approve mapping <@%s>` or `lgtm deny mapping <@%s>`.", event.User, login, event.User, event.User))
+	}
+	sc.replyInChannel(event.Channel, fmt.Sprintf("Thanks. An admin has to approve mapping you to @%s on GitHub; I'll let you know.", login))
+}
+
+// handleMappingReview approves or denies a self-service mapping for "lgtm approve mapping"
+// and "lgtm deny mapping" from an admin. It reports whether the message was one of them.
+func (sc *SlackClient) handleMappingReview(ctx context.Context, msg *SlackMessage) bool {
+	match := mappingReviewPattern.FindStringSubmatch(msg.Text)
+	if match == nil {
+		return false
+	}
+
+	if !sc.config.isAdmin(msg.User) {
+		logWarn("Ignoring %q from Slack user %s: not an admin", strings.TrimSpace(msg.Text), sc.userLabel(ctx, msg.User))
+		sc.react(msg.Channel, msg.Timestamp, outcomeDenied)
+		return true
+	}
+
+	slackUser := match[2]
+	login, ok := sc.users.takeRequest(slackUser)
+	if !ok {
+		sc.replyInChannel(msg.Channel, fmt.Sprintf("<@%s> has no mapping request waiting.", slackUser))
+		return true
+	}
+
+	if strings.EqualFold(match[1], "deny") {
+		logInfo("Slack user %s denied mapping Slack user %s to GitHub login %s", sc.userLabel(ctx, msg.User), slackUser, login)
+		sc.replyInChannel(msg.Channel, fmt.Sprintf("Denied mapping <@%s> to @%s.", slackUser, login))
+		sc.replyInChannel(slackUser, fmt.Sprintf("An admin denied mapping you to @%s on GitHub.", login))
+		return true
+	}
+
+	if !sc.users.set(slackUser, login) {
+		sc.replyInChannel(msg.Channel, fmt.Sprintf("<@%s> has a configured mapping, which a self-service mapping can't replace.", slackUser))
+		return true
+	}
+	logInfo("Slack user %s approved mapping Slack user %s to GitHub login %s", sc.userLabel(ctx, msg.User), slackUser, login)
+	sc.replyInChannel(msg.Channel, fmt.Sprintf("Mapped <@%s> to @%s.", slackUser, login))
+	sc.replyInChannel(slackUser, fmt.Sprintf("Got it, you're @%s on GitHub.", login))
+	return true
+}
+
+// replyInChannel posts a plain message to a channel or DM
+func (sc *SlackClient) replyInChannel(channel, text string) {
+	if _, _, err := sc.slackAPI().PostMessage(channel, slack.MsgOptionText(text, false)); err != nil {
+		logWarn("Failed to post message to %s: %v", channel, err)
+	}
+}
+
+// replyInThread posts a plain message in the thread of the message at ts, which is
+// threadTS when that message is itself a reply
+func (sc *SlackClient) replyInThread(channel, ts, threadTS, text string) error {
+	if threadTS == "" {
+		threadTS = ts
+	}
+	_, _, err := sc.slackAPI().PostMessage(channel, slack.MsgOptionText(text, false), slack.MsgOptionTS(threadTS))
+	return err
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// sendDM delivers a direct message from user to the bot
+func sendDM(sc *SlackClient, user, text string) {
+	sc.handleMessageEvent(context.Background(), &slackevents.MessageEvent{
+		ChannelType: "im",
+		Channel:     "D" + user,
+		User:        user,
+		Text:        text,
+		TimeStamp:   "1700000000.000100",
+	}, "", "")
+}
+
+func newMappingTestClient(t *testing.T) *SlackClient {
+	t.Helper()
+	sc, _ := newTestSlackClient(t, &Configuration{
+		EnableSelfServiceMapping: true,
+		AdminSlackUsers:          []string{"UADMIN"},
+		UserMappings:             map[string]string{"UCONF": "configured"},
+	}, &fakeGitHub{})
+	return sc
+}
+
+func TestSelfServiceMappingNeedsAdminApproval(t *testing.T) {
+	t.Run("approved", func(t *testing.T) {
+		sc := newMappingTestClient(t)
+		sendDM(sc, "U1", "map me as octocat")
+		if login, ok := sc.users.lookup("U1"); ok {
+			t.Fatalf("mapped to %s before an admin approved it", login)
+		}
+
+		sendDM(sc, "U2", "lgtm approve mapping <@U1>")
+		if login, ok := sc.users.lookup("U1"); ok {
+			t.Fatalf("mapped to %s on a non-admin's approval", login)
+		}
+
+		sendDM(sc, "UADMIN", "lgtm approve mapping <@U1>")
+		if login, ok := sc.users.lookup("U1"); !ok || login != "octocat" {
+			t.Fatalf("lookup = %q, %v, want octocat once approved", login, ok)
+		}
+		saved, _ := sc.store.List(context.Background(), storeNamespaceUserMappings)
+		if saved["U1"] != "octocat" {
+			t.Errorf("saved mappings %v, want U1=octocat", saved)
+		}
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		sc := newMappingTestClient(t)
+		sendDM(sc, "U1", "map me as octocat")
+		sendDM(sc, "UADMIN", "lgtm deny mapping <@U1>")
+		sendDM(sc, "UADMIN", "lgtm approve mapping <@U1>")
+		if login, ok := sc.users.lookup("U1"); ok {
+			t.Errorf("mapped to %s after the request was denied", login)
+		}
+	})
+}
+
+func TestSelfServiceMappingKeepsConfiguredMapping(t *testing.T) {
+	sc := newMappingTestClient(t)
+	sendDM(sc, "UCONF", "map me as someone-else")
+	sendDM(sc, "UADMIN", "lgtm approve mapping <@UCONF>")
+
+	if login, _ := sc.users.lookup("UCONF"); login != "configured" {
+		t.Errorf("lookup = %q, want the configured mapping", login)
+	}
+	if sc.users.set("UCONF", "someone-else") {
+		t.Error("set replaced a configured mapping")
+	}
+	if saved, _ := sc.store.List(context.Background(), storeNamespaceUserMappings); len(saved) != 0 {
+		t.Errorf("saved mappings %v, want none", saved)
+	}
+}
+
+func TestReplyInThread(t *testing.T) {
+	tests := []struct {
+		name     string
+		threadTS string
+		want     string
+	}{
+		{name: "top-level message", want: "1700000000.000100"},
+		{name: "thread reply", threadTS: "1699999999.000100", want: "1699999999.000100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newTestSlackClient(t, &Configuration{}, &fakeGitHub{})
+			var posted string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted = r.FormValue("thread_ts")
+				writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channel": "C1", "ts": "1.1"})
+			}))
+			t.Cleanup(server.Close)
+			sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+			if err := sc.replyInThread("C1", "1700000000.000100", tt.threadTS, "hello"); err != nil {
+				t.Fatalf("replyInThread: %v", err)
+			}
+			if posted != tt.want {
+				t.Errorf("posted in thread %q, want %q", posted, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyInThreadFailure(t *testing.T) {
+	sc, _ := newTestSlackClient(t, &Configuration{}, &fakeGitHub{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": false, "error": "channel_not_found"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	if err := sc.replyInThread("C1", "1700000000.000100", "", "hello"); err == nil {
+		t.Error("replyInThread succeeded against a failing Slack API")
+	}
+}
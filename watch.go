@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxMergeWatchers bounds how many PRs are polled for a merge at once
+const maxMergeWatchers = 100
+
+// Defaults used when the watch timeout or poll interval is left at zero
+const (
+	defaultMergeWatchTimeout  = 24 * time.Hour
+	defaultMergeWatchInterval = time.Minute
+)
+
+// mergeState is where an approved PR stands on its way to the base branch
+type mergeState int
+
+const (
+	mergeStateOpen mergeState = iota
+	mergeStateMerged
+	mergeStateClosed
+)
+
+// mergeWatcher polls approved PRs until they merge, close or time out,
+// running one goroutine per PR up to maxMergeWatchers
+type mergeWatcher struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	interval time.Duration
+	watching map[string]bool
+	state    func(ctx context.Context, owner, repo string, prNumber int) (mergeState, error)
+	notify   func(ctx context.Context, req *ApprovalRequest, state mergeState)
+}
+
+// newMergeWatcher creates a watcher; it returns nil when watching is disabled
+func newMergeWatcher(enabled bool, timeout, interval time.Duration, state func(ctx context.Context, owner, repo string, prNumber int) (mergeState, error), notify func(ctx context.Context, req *ApprovalRequest, state mergeState)) *mergeWatcher {
+	if !enabled {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = defaultMergeWatchTimeout
+	}
+	if interval <= 0 {
+		interval = defaultMergeWatchInterval
+	}
+	return &mergeWatcher{
+		timeout:  timeout,
+		interval: interval,
+		watching: make(map[string]bool),
+		state:    state,
+		notify:   notify,
+	}
+}
+
+// watch starts polling a PR in the background; it reports false when the PR is
+// already watched or the watcher is full. A nil watcher never watches.
+func (mw *mergeWatcher) watch(ctx context.Context, req *ApprovalRequest) bool {
+	if mw == nil {
+		return false
+	}
+
+	key := fmt.Sprintf("%s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+
+	mw.mu.Lock()
+	if mw.watching[key] || len(mw.watching) >= maxMergeWatchers {
+		mw.mu.Unlock()
+		return false
+	}
+	mw.watching[key] = true
+	mw.mu.Unlock()
+
+	go func() {
+		defer func() {
+			mw.mu.Lock()
+			delete(mw.watching, key)
+			mw.mu.Unlock()
+		}()
+		mw.poll(ctx, req)
+	}()
+	return true
+}
+
+// poll checks the PR every interval until it leaves the open state, the timeout
+// passes or ctx is cancelled. Lookup errors are logged and retried on the next tick.
+func (mw *mergeWatcher) poll(ctx context.Context, req *ApprovalRequest) {
+	ctx, cancel := context.WithTimeout(ctx, mw.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(mw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logDebug("Stopped watching PR %s/%s#%d for a merge: %v", req.Owner, req.Repository, req.PRNumber, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		state, err := mw.state(ctx, req.Owner, req.Repository, req.PRNumber)
+		if err != nil {
+			if ctx.Err() == nil {
+				logDebug("Failed to check merge state of PR %s/%s#%d: %v", req.Owner, req.Repository, req.PRNumber, err)
+			}
+			continue
+		}
+		if state != mergeStateOpen {
+			mw.notify(ctx, req, state)
+			return
+		}
+	}
+}
+
+// MergeState reports whether a PR is still open, merged, or closed without merging
+func (gc *GitHubClient) MergeState(ctx context.Context, owner, repo string, prNumber int) (mergeState, error) {
+	pr, err := gc.getPR(ctx, owner, repo, prNumber)
+	if err != nil {
+		return mergeStateOpen, err
+	}
+
+	switch {
+	case pr.GetMerged():
+		return mergeStateMerged, nil
+	case pr.GetState() == "closed":
+		return mergeStateClosed, nil
+	default:
+		return mergeStateOpen, nil
+	}
+}
+
+// DeleteMergedBranch deletes the head branch of a merged PR. Branches in forks and the
+// repository's default branch are left alone, and a branch that is already gone is not
+// an error. It reports whether a branch was deleted.
+func (gc *GitHubClient) DeleteMergedBranch(ctx context.Context, owner, repo string, prNumber int) (bool, error) {
+	pr, err := gc.getPR(ctx, owner, repo, prNumber)
+	if err != nil {
+		return false, err
+	}
+	if !pr.GetMerged() {
+		return false, nil
+	}
+
+	head := pr.GetHead()
+	branch := head.GetRef()
+	if head.GetRepo() == nil || head.GetRepo().GetFullName() != pr.GetBase().GetRepo().GetFullName() {
+		logDebug("Not deleting branch %s of PR %s/%s#%d: it is in a fork or the fork is gone", branch, owner, repo, prNumber)
+		return false, nil
+	}
+	if branch == head.GetRepo().GetDefaultBranch() {
+		logDebug("Not deleting branch %s of PR %s/%s#%d: it is the default branch", branch, owner, repo, prNumber)
+		return false, nil
+	}
+
+	response, err := gc.client.Git.DeleteRef(ctx, owner, repo, "heads/"+branch)
+	if err == nil {
+		return true, nil
+	}
+	if response != nil {
+		switch {
+		case response.StatusCode == http.StatusNotFound,
+			response.StatusCode == http.StatusUnprocessableEntity && strings.Contains(strings.ToLower(errorResponseText(err)), "reference does not exist"):
+			// Deleted already, e.g. by the repository's own auto-delete setting
+			return false, nil
+		case response.StatusCode == http.StatusForbidden, response.StatusCode == http.StatusUnprocessableEntity:
+			return false, fmt.Errorf("branch %s is protected or the token may not delete it: %v", branch, err)
+		}
+	}
+	return false, fmt.Errorf("failed to delete branch %s: %v", branch, err)
+}
+
+// reportMergeState replies in the approval's thread once a watched PR merges or closes,
+// deleting the merged branch first when DeleteBranchOnMerge is set
+func (sc *SlackClient) reportMergeState(ctx context.Context, req *ApprovalRequest, state mergeState) {
+	pr := fmt.Sprintf("%s/%s#%d", req.Owner, req.Repository, req.PRNumber)
+
+	var text string
+	switch state {
+	case mergeStateMerged:
+		logInfo("Watched PR %s merged", pr)
+		text = fmt.Sprintf("%s merged 🎉", pr)
+
+		if sc.config.DeleteBranchOnMerge {
+			deleted, err := sc.githubClient.DeleteMergedBranch(ctx, req.Owner, req.Repository, req.PRNumber)
+			if err != nil {
+				logWarn("Failed to delete the branch of merged PR %s: %v", pr, err)
+			} else if deleted {
+				logInfo("Deleted the branch of merged PR %s", pr)
+				text += " Its branch was deleted."
+			}
+		}
+	case mergeStateClosed:
+		logInfo("Watched PR %s closed without merging", pr)
+		text = fmt.Sprintf("%s was closed without merging", pr)
+	default:
+		return
+	}
+
+	if err := sc.replyInThread(req.SourceChannel, req.SourceMessage.Timestamp, req.SourceMessage.ThreadTS, text); err != nil {
+		logWarn("Failed to post merge notice for %s: %v", pr, err)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// flippingState is a merge state lookup that reports open until its polls run out
+type flippingState struct {
+	mu    sync.Mutex
+	polls int
+	// openFor is how many polls report the PR still open before final
+	openFor int
+	// failFor is how many polls fail before the PR is looked up at all
+	failFor int
+	final   mergeState
+}
+
+// state answers one poll
+func (fs *flippingState) state(ctx context.Context, owner, repo string, prNumber int) (mergeState, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.polls++
+	if fs.polls <= fs.failFor {
+		return mergeStateOpen, errors.New("GitHub is unreachable")
+	}
+	if fs.polls <= fs.failFor+fs.openFor {
+		return mergeStateOpen, nil
+	}
+	return fs.final, nil
+}
+
+// notifications collects the merge states notified
+type notifications struct {
+	states chan mergeState
+}
+
+// notify records a notification
+func (n *notifications) notify(ctx context.Context, req *ApprovalRequest, state mergeState) {
+	n.states <- state
+}
+
+func TestMergeWatcher(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *flippingState
+		want  mergeState
+	}{
+		{name: "merged", state: &flippingState{openFor: 2, final: mergeStateMerged}, want: mergeStateMerged},
+		{name: "closed without merging", state: &flippingState{openFor: 1, final: mergeStateClosed}, want: mergeStateClosed},
+		{name: "lookup errors are retried", state: &flippingState{failFor: 2, final: mergeStateMerged}, want: mergeStateMerged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notified := &notifications{states: make(chan mergeState, 1)}
+			mw := newMergeWatcher(true, time.Minute, time.Millisecond, tt.state.state, notified.notify)
+
+			if !mw.watch(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1}) {
+				t.Fatal("watch = false, want the PR watched")
+			}
+			select {
+			case state := <-notified.states:
+				if state != tt.want {
+					t.Errorf("notified %v, want %v", state, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no merge notification")
+			}
+		})
+	}
+}
+
+func TestMergeWatcherTimeout(t *testing.T) {
+	notified := &notifications{states: make(chan mergeState, 1)}
+	state := &flippingState{openFor: 1 << 30}
+	mw := newMergeWatcher(true, 20*time.Millisecond, time.Millisecond, state.state, notified.notify)
+
+	mw.watch(context.Background(), &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mw.mu.Lock()
+		watching := len(mw.watching)
+		mw.mu.Unlock()
+		if watching == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("still watching after the timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case state := <-notified.states:
+		t.Errorf("notified %v, want no notification for a PR that stayed open", state)
+	default:
+	}
+}
+
+func TestMergeWatcherDeduplicates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	state := &flippingState{openFor: 1 << 30}
+	mw := newMergeWatcher(true, time.Minute, time.Hour, state.state, (&notifications{}).notify)
+
+	req := &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1}
+	if !mw.watch(ctx, req) {
+		t.Fatal("first watch = false, want the PR watched")
+	}
+	if mw.watch(ctx, req) {
+		t.Error("second watch = true, want the PR watched once")
+	}
+	var disabled *mergeWatcher
+	if disabled.watch(ctx, req) {
+		t.Error("a disabled watcher watched a PR")
+	}
+	if newMergeWatcher(false, 0, 0, state.state, nil) != nil {
+		t.Error("newMergeWatcher returned a watcher while disabled")
+	}
+}
+
+func TestMergeState(t *testing.T) {
+	tests := []struct {
+		name   string
+		pr     map[string]interface{}
+		status int
+		want   mergeState
+	}{
+		{name: "open", pr: map[string]interface{}{"number": 1, "state": "open"}, want: mergeStateOpen},
+		{name: "merged", pr: map[string]interface{}{"number": 1, "state": "closed", "merged": true}, want: mergeStateMerged},
+		{name: "closed", pr: map[string]interface{}{"number": 1, "state": "closed"}, want: mergeStateClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1": tt.pr}}
+			gc := newTestGitHubClient(t, &Configuration{}, gh.ServeHTTP)
+
+			got, err := gc.MergeState(context.Background(), "o", "r", 1)
+			if err != nil {
+				t.Fatalf("MergeState: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MergeState = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeStateLookupFailure(t *testing.T) {
+	gh := &fakeGitHub{failures: map[string]int{"GET /repos/o/r/pulls/1": http.StatusNotFound}}
+	gc := newTestGitHubClient(t, &Configuration{}, gh.ServeHTTP)
+
+	if _, err := gc.MergeState(context.Background(), "o", "r", 1); err == nil {
+		t.Error("MergeState succeeded for a PR that can't be found")
+	}
+}
+
+// postedMessages answers sc's Slack API calls, collecting the text of posted messages
+func postedMessages(t *testing.T, sc *SlackClient) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		texts = append(texts, r.FormValue("text"))
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "channel": "C1", "ts": "1.1"})
+	}))
+	t.Cleanup(server.Close)
+	sc.api = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), texts...)
+	}
+}
+
+func TestReportMergeState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state mergeState
+		want  string
+	}{
+		{name: "merged", state: mergeStateMerged, want: "o/r#1 merged 🎉"},
+		{name: "closed", state: mergeStateClosed, want: "o/r#1 was closed without merging"},
+		{name: "still open", state: mergeStateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newTestSlackClient(t, &Configuration{}, &fakeGitHub{})
+			posted := postedMessages(t, sc)
+
+			req := &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, SourceChannel: "C1", SourceMessage: &SlackMessage{Channel: "C1", Timestamp: "1700000000.000100"}}
+			sc.reportMergeState(context.Background(), req, tt.state)
+
+			got := posted()
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("posted %q, want nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("posted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mergedPR is a merged PR of o/r whose head branch is in headRepo
+func mergedPR(merged bool, branch, headRepo string) map[string]interface{} {
+	return map[string]interface{}{
+		"number": 1,
+		"state":  "closed",
+		"merged": merged,
+		"head":   map[string]interface{}{"ref": branch, "sha": "abc123", "repo": map[string]string{"full_name": headRepo, "default_branch": "main"}},
+		"base":   map[string]interface{}{"ref": "main", "repo": map[string]string{"full_name": "o/r", "default_branch": "main"}},
+	}
+}
+
+func TestDeleteMergedBranch(t *testing.T) {
+	const deleteRef = "DELETE /repos/o/r/git/refs/heads/feature"
+	tests := []struct {
+		name        string
+		pr          map[string]interface{}
+		status      int
+		message     string
+		wantDeleted bool
+		wantDelete  bool
+		wantErr     bool
+	}{
+		{name: "merged", pr: mergedPR(true, "feature", "o/r"), wantDeleted: true, wantDelete: true},
+		{name: "not merged", pr: mergedPR(false, "feature", "o/r")},
+		{name: "fork", pr: mergedPR(true, "feature", "someone/r")},
+		{name: "default branch", pr: mergedPR(true, "main", "o/r")},
+		{name: "already deleted", pr: mergedPR(true, "feature", "o/r"), status: http.StatusNotFound, message: "Not Found", wantDelete: true},
+		{name: "reference gone", pr: mergedPR(true, "feature", "o/r"), status: http.StatusUnprocessableEntity, message: "Reference does not exist", wantDelete: true},
+		{name: "protected", pr: mergedPR(true, "feature", "o/r"), status: http.StatusUnprocessableEntity, message: "Cannot delete this protected branch", wantDelete: true, wantErr: true},
+		{name: "forbidden", pr: mergedPR(true, "feature", "o/r"), status: http.StatusForbidden, message: "Resource not accessible by integration", wantDelete: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{"GET /repos/o/r/pulls/1": tt.pr}}
+			deleted := false
+			gc := newTestGitHubClient(t, &Configuration{}, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method+" "+r.URL.Path != deleteRef {
+					gh.ServeHTTP(w, r)
+					return
+				}
+				deleted = true
+				if tt.status != 0 {
+					writeJSON(w, tt.status, map[string]string{"message": tt.message})
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			got, err := gc.DeleteMergedBranch(context.Background(), "o", "r", 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeleteMergedBranch error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.wantDeleted {
+				t.Errorf("DeleteMergedBranch = %v, want %v", got, tt.wantDeleted)
+			}
+			if deleted != tt.wantDelete {
+				t.Errorf("deleted the ref = %v, want %v", deleted, tt.wantDelete)
+			}
+		})
+	}
+}
+
+func TestMergeNoticeReportsDeletedBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing bool
+		want    string
+	}{
+		{name: "deleted", want: "o/r#1 merged 🎉 Its branch was deleted."},
+		{name: "deletion failed", failing: true, want: "o/r#1 merged 🎉"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakeGitHub{routes: map[string]interface{}{
+				"GET /repos/o/r/pulls/1":                   mergedPR(true, "feature", "o/r"),
+				"DELETE /repos/o/r/git/refs/heads/feature": map[string]interface{}{},
+			}}
+			if tt.failing {
+				gh.failures = map[string]int{"DELETE /repos/o/r/git/refs/heads/feature": http.StatusForbidden}
+			}
+			sc, _ := newTestSlackClient(t, &Configuration{WatchUntilMerged: true, DeleteBranchOnMerge: true}, gh)
+			posted := postedMessages(t, sc)
+
+			req := &ApprovalRequest{Owner: "o", Repository: "r", PRNumber: 1, SourceChannel: "C1", SourceMessage: &SlackMessage{Channel: "C1", Timestamp: "1700000000.000100"}}
+			sc.reportMergeState(context.Background(), req, mergeStateMerged)
+
+			if got := posted(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("posted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteBranchOnMergeValidation(t *testing.T) {
+	err := validateConfiguration(validConfig(t, "--delete-branch-on-merge"))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "DeleteBranchOnMerge" {
+		t.Errorf("validateConfiguration = %v, want a DeleteBranchOnMerge error", err)
+	}
+}